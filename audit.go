@@ -0,0 +1,24 @@
+package docshelf
+
+import "time"
+
+// An AuditAction is something security relevant that happened in docshelf.
+type AuditAction string
+
+// AuditAction enum values
+const (
+	AuditLoginFailed     = AuditAction("loginFailed")
+	AuditLoginThrottled  = AuditAction("loginThrottled")
+	AuditAccountLocked   = AuditAction("accountLocked")
+	AuditAccountUnlocked = AuditAction("accountUnlocked")
+)
+
+// An AuditEvent records a single AuditAction, who it concerned and where it came from.
+type AuditEvent struct {
+	ID        string      `json:"id"`
+	Action    AuditAction `json:"action"`
+	Email     string      `json:"email,omitempty"`
+	IP        string      `json:"ip,omitempty"`
+	ActorID   string      `json:"actorId,omitempty"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
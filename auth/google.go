@@ -2,11 +2,8 @@ package auth
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
 	"net/http"
 
 	"github.com/dgrijalva/jwt-go"
@@ -29,7 +26,7 @@ type Google struct {
 	clientID     string
 	clientSecret string
 	userStore    docshelf.UserStore
-	client       *http.Client
+	keys         *KeyCache
 }
 
 // NewGoogle returns a new Google oauth config.
@@ -38,53 +35,18 @@ func NewGoogle(userStore docshelf.UserStore, clientID, clientSecret string) Goog
 		clientID:     clientID,
 		clientSecret: clientSecret,
 		userStore:    userStore,
-		client:       http.DefaultClient,
+		keys:         NewKeyCache(http.DefaultClient, googleKeyEndpoint, ParsePEMKeys),
 	}
 }
 
-func (g Google) getGooglePublicKeys() (map[string]string, error) {
-	res, err := g.client.Get(googleKeyEndpoint)
-	if err != nil {
-		return nil, err
-	}
-
-	if res.StatusCode != 200 {
-		return nil, errors.New("failed to fetch keys")
-	}
-
-	log.Printf("%v", res.Header.Get("Cache-Control"))
-	data, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	keys := make(map[string]string)
-
-	if err := json.Unmarshal(data, &keys); err != nil {
-		return nil, err
-	}
-
-	return keys, nil
-}
-
 func (g Google) validate(ctx context.Context, token string) (*Claims, error) {
 	tok, err := jwt.ParseWithClaims(token, &Claims{}, func(tok *jwt.Token) (interface{}, error) {
 		if _, ok := tok.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", tok.Header["alg"])
 		}
 
-		keys, err := g.getGooglePublicKeys()
-		if err != nil {
-			return nil, err
-		}
-
-		kid := tok.Header["kid"].(string)
-		publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(keys[kid]))
-		if err != nil {
-			return nil, err
-		}
-
-		return publicKey, nil
+		kid, _ := tok.Header["kid"].(string)
+		return g.keys.Key(kid)
 	})
 
 	if err != nil {
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	// used when a provider doesn't say how long its keys can be cached
+	defaultKeyTTL = 5 * time.Minute
+
+	// keys are refreshed in the background once they're this close to expiring
+	keyRefreshWindow = time.Minute
+
+	// unknown kids trigger at most one refetch in this period, so bogus tokens can't hammer the provider
+	unknownKeyBackoff = 30 * time.Second
+)
+
+// A KeyParser turns the body of a key endpoint into public keys indexed by kid.
+type KeyParser func(data []byte) (map[string]*rsa.PublicKey, error)
+
+// A KeyCache keeps a provider's public signing keys in memory for as long as the provider's Cache-Control
+// header allows. Keys are refreshed in the background shortly before they expire, and a token signed with
+// an unknown kid triggers a single refetch so rotated keys are picked up right away.
+type KeyCache struct {
+	url    string
+	parse  KeyParser
+	client *http.Client
+	now    func() time.Time
+
+	mu         sync.RWMutex
+	keys       map[string]*rsa.PublicKey
+	expires    time.Time
+	lastFetch  time.Time
+	refreshing bool
+	fetchLock  sync.Mutex
+}
+
+// NewKeyCache returns a KeyCache for the keys served at the given URL.
+func NewKeyCache(client *http.Client, url string, parse KeyParser) *KeyCache {
+	return &KeyCache{
+		url:    url,
+		parse:  parse,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+// Key returns the public key with the given kid.
+func (c *KeyCache) Key(kid string) (*rsa.PublicKey, error) {
+	c.mu.RLock()
+	key, known := c.keys[kid]
+	cached := c.keys != nil
+	expires := c.expires
+	lastFetch := c.lastFetch
+	c.mu.RUnlock()
+
+	now := c.now()
+	switch {
+	case !cached || !now.Before(expires):
+		// nothing fresh is cached, so callers have to wait for new keys. Stale keys are still better than
+		// locking everyone out while the provider is unreachable though
+		if err := c.refresh(lastFetch); err != nil {
+			if known {
+				return key, nil
+			}
+
+			return nil, err
+		}
+	case !known:
+		if now.Sub(lastFetch) < unknownKeyBackoff {
+			return nil, fmt.Errorf("unknown signing key: %s", kid)
+		}
+
+		if err := c.refresh(lastFetch); err != nil {
+			return nil, err
+		}
+	default:
+		if expires.Sub(now) < keyRefreshWindow {
+			c.refreshInBackground()
+		}
+
+		return key, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key: %s", kid)
+}
+
+func (c *KeyCache) refreshInBackground() {
+	c.mu.Lock()
+	if c.refreshing {
+		c.mu.Unlock()
+		return
+	}
+
+	c.refreshing = true
+	lastFetch := c.lastFetch
+	c.mu.Unlock()
+
+	go func() {
+		_ = c.refresh(lastFetch) // failures are retried by the next caller once the keys expire
+		c.mu.Lock()
+		c.refreshing = false
+		c.mu.Unlock()
+	}()
+}
+
+// refresh fetches the keys unless someone else already fetched them since the given time. That way
+// concurrent callers waiting on the same refresh only cause a single request.
+func (c *KeyCache) refresh(since time.Time) error {
+	c.fetchLock.Lock()
+	defer c.fetchLock.Unlock()
+
+	c.mu.RLock()
+	fetched := c.lastFetch.After(since)
+	c.mu.RUnlock()
+	if fetched {
+		return nil
+	}
+
+	res, err := c.client.Get(c.url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d fetching signing keys", res.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read signing keys: %w", err)
+	}
+
+	keys, err := c.parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse signing keys: %w", err)
+	}
+
+	now := c.now()
+	c.mu.Lock()
+	c.keys = keys
+	c.expires = now.Add(keyTTL(res.Header))
+	c.lastFetch = now
+	c.mu.Unlock()
+
+	return nil
+}
+
+// keyTTL works out how long keys can be cached from the max-age directive of a response's Cache-Control
+// header, minus however long the response already spent in other caches.
+func keyTTL(header http.Header) time.Duration {
+	var maxAge time.Duration
+	found := false
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(strings.ToLower(directive))
+		if directive == "no-cache" || directive == "no-store" {
+			return 0
+		}
+
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+		if err != nil || seconds < 0 {
+			continue
+		}
+
+		maxAge = time.Duration(seconds) * time.Second
+		found = true
+	}
+
+	if !found {
+		return defaultKeyTTL
+	}
+
+	if age, err := strconv.Atoi(header.Get("Age")); err == nil && age > 0 {
+		maxAge -= time.Duration(age) * time.Second
+	}
+
+	if maxAge < 0 {
+		return 0
+	}
+
+	return maxAge
+}
+
+// ParsePEMKeys parses keys served as a JSON object of PEM encoded certificates indexed by kid, the way
+// Google serves them.
+func ParsePEMKeys(data []byte) (map[string]*rsa.PublicKey, error) {
+	certs := make(map[string]string)
+	if err := json.Unmarshal(data, &certs); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for kid, cert := range certs {
+		key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(cert))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", kid, err)
+		}
+
+		keys[kid] = key
+	}
+
+	return keys, nil
+}
+
+// ParseJWKS parses keys served as a JSON Web Key Set, the way OpenID Connect providers serve them.
+func ParseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	return parseJWKS(set)
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a key endpoint that counts how often it's hit
+type keyServer struct {
+	*httptest.Server
+	sync.Mutex
+	kids         []string
+	cacheControl string
+	fetches      int
+}
+
+func newKeyServer(t *testing.T, cacheControl string, kids ...string) *keyServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks := &keyServer{kids: kids, cacheControl: cacheControl}
+	ks.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ks.Lock()
+		defer ks.Unlock()
+		ks.fetches++
+
+		var set jwks
+		for _, kid := range ks.kids {
+			set.Keys = append(set.Keys, jwk{
+				Kid: kid,
+				Kty: "RSA",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+
+		w.Header().Set("Cache-Control", ks.cacheControl)
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+
+	return ks
+}
+
+func (ks *keyServer) count() int {
+	ks.Lock()
+	defer ks.Unlock()
+	return ks.fetches
+}
+
+func (ks *keyServer) rotate(kids ...string) {
+	ks.Lock()
+	defer ks.Unlock()
+	ks.kids = kids
+}
+
+// a clock that only moves when told to
+type fakeClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func Test_KeyCacheMaxAge(t *testing.T) {
+	// SETUP
+	ks := newKeyServer(t, "public, max-age=600", "one")
+	defer ks.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	cache := NewKeyCache(http.DefaultClient, ks.URL, ParseJWKS)
+	cache.now = clock.Now
+
+	// RUN
+	if _, err := cache.Key("one"); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(5 * time.Minute)
+	if _, err := cache.Key("one"); err != nil {
+		t.Fatal(err)
+	}
+	cachedFetches := ks.count()
+
+	clock.Advance(6 * time.Minute)
+	if _, err := cache.Key("one"); err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if cachedFetches != 1 {
+		t.Fatalf("expected keys to be cached within max-age, got %d fetches", cachedFetches)
+	}
+
+	if ks.count() != 2 {
+		t.Fatalf("expected keys to be refetched after max-age, got %d fetches", ks.count())
+	}
+}
+
+func Test_KeyCacheUnknownKid(t *testing.T) {
+	// SETUP
+	ks := newKeyServer(t, "max-age=600", "one")
+	defer ks.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	cache := NewKeyCache(http.DefaultClient, ks.URL, ParseJWKS)
+	cache.now = clock.Now
+
+	if _, err := cache.Key("one"); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	_, earlyErr := cache.Key("two")
+	earlyFetches := ks.count()
+
+	clock.Advance(unknownKeyBackoff)
+	ks.rotate("one", "two")
+	_, rotatedErr := cache.Key("two")
+
+	clock.Advance(unknownKeyBackoff)
+	_, bogusErr := cache.Key("bogus")
+	_, repeatErr := cache.Key("bogus")
+
+	// ASSERT
+	if earlyErr == nil || earlyFetches != 1 {
+		t.Fatalf("expected unknown kid right after a fetch to be rejected without refetching, got %d fetches", earlyFetches)
+	}
+
+	if rotatedErr != nil {
+		t.Fatalf("expected rotated key to be fetched, got: %v", rotatedErr)
+	}
+
+	if bogusErr == nil || repeatErr == nil {
+		t.Fatal("expected bogus kid to be rejected")
+	}
+
+	if ks.count() != 3 {
+		t.Fatalf("expected a single refetch per unknown kid, got %d fetches", ks.count())
+	}
+}
+
+func Test_KeyCacheBackgroundRefresh(t *testing.T) {
+	// SETUP
+	ks := newKeyServer(t, "max-age=600", "one")
+	defer ks.Close()
+
+	clock := &fakeClock{now: time.Now()}
+	cache := NewKeyCache(http.DefaultClient, ks.URL, ParseJWKS)
+	cache.now = clock.Now
+
+	if _, err := cache.Key("one"); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	clock.Advance(10*time.Minute - keyRefreshWindow/2)
+	_, err := cache.Key("one")
+
+	// ASSERT
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ks.count() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected keys to be refreshed in the background before expiring")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_KeyTTL(t *testing.T) {
+	cases := []struct {
+		cacheControl string
+		age          string
+		ttl          time.Duration
+	}{
+		{"public, max-age=19523, must-revalidate, no-transform", "", 19523 * time.Second},
+		{"max-age=600", "100", 500 * time.Second},
+		{"no-cache", "", 0},
+		{"", "", defaultKeyTTL},
+	}
+
+	for _, c := range cases {
+		// SETUP
+		header := http.Header{}
+		header.Set("Cache-Control", c.cacheControl)
+		header.Set("Age", c.age)
+
+		// RUN
+		ttl := keyTTL(header)
+
+		// ASSERT
+		if ttl != c.ttl {
+			t.Fatalf("%q: expected ttl %s, got %s", c.cacheControl, c.ttl, ttl)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/docshelf/docshelf"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConfig holds everything needed to authenticate against an LDAP directory.
+type LDAPConfig struct {
+	// URL of the directory, e.g. ldaps://ldap.example.com:636
+	URL string
+
+	// BindDN and BindPassword are used to look users and their groups up before binding as them. Leaving
+	// them empty searches anonymously.
+	BindDN       string
+	BindPassword string
+
+	// BaseDN is where users are searched for using UserFilter, which gets the login email in place of %s.
+	BaseDN     string
+	UserFilter string
+
+	// GroupBaseDN is where groups are searched for using GroupFilter, which gets the user's DN in place of
+	// %s.
+	GroupBaseDN string
+	GroupFilter string
+
+	EmailAttr     string
+	NameAttr      string
+	GroupNameAttr string
+
+	// GroupMapping maps LDAP group names to the names of the docshelf Groups their members belong to. Only
+	// mapped Groups are managed, members are added and removed to match the directory on every login.
+	GroupMapping map[string]string
+}
+
+// LDAP config for authenticating against an LDAP directory.
+type LDAP struct {
+	cfg        LDAPConfig
+	userStore  docshelf.UserStore
+	groupStore docshelf.GroupStore
+}
+
+// NewLDAP returns a new LDAP authenticator. Any attributes and filters left empty in the config fall back
+// to common defaults.
+func NewLDAP(userStore docshelf.UserStore, groupStore docshelf.GroupStore, cfg LDAPConfig) LDAP {
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(mail=%s)"
+	}
+
+	if cfg.GroupFilter == "" {
+		cfg.GroupFilter = "(member=%s)"
+	}
+
+	if cfg.GroupBaseDN == "" {
+		cfg.GroupBaseDN = cfg.BaseDN
+	}
+
+	if cfg.EmailAttr == "" {
+		cfg.EmailAttr = "mail"
+	}
+
+	if cfg.NameAttr == "" {
+		cfg.NameAttr = "displayName"
+	}
+
+	if cfg.GroupNameAttr == "" {
+		cfg.GroupNameAttr = "cn"
+	}
+
+	return LDAP{
+		cfg:        cfg,
+		userStore:  userStore,
+		groupStore: groupStore,
+	}
+}
+
+// Authenticate implements the docshelf.Authenticator interface. The user is looked up in the directory by
+// email, and the login succeeds if binding as them with the given password does. Their name and email are
+// taken from the directory and their mapped docshelf Groups are synced with their LDAP groups.
+func (l LDAP) Authenticate(ctx context.Context, email, token string) (docshelf.User, error) {
+	// most directories treat a bind without a password as anonymous and let it succeed
+	if email == "" || token == "" {
+		return docshelf.User{}, errors.New("email and password are required")
+	}
+
+	conn, err := ldap.DialURL(l.cfg.URL)
+	if err != nil {
+		return docshelf.User{}, fmt.Errorf("failed to connect to ldap: %w", err)
+	}
+	defer conn.Close()
+
+	if l.cfg.BindDN != "" {
+		if err := conn.Bind(l.cfg.BindDN, l.cfg.BindPassword); err != nil {
+			return docshelf.User{}, fmt.Errorf("failed to bind ldap service account: %w", err)
+		}
+	}
+
+	entry, err := l.findUser(conn, email)
+	if err != nil {
+		return docshelf.User{}, err
+	}
+
+	groups, err := l.findGroups(conn, entry.DN)
+	if err != nil {
+		return docshelf.User{}, err
+	}
+
+	if err := conn.Bind(entry.DN, token); err != nil {
+		return docshelf.User{}, errors.New("authentication failed")
+	}
+
+	if dirEmail := entry.GetAttributeValue(l.cfg.EmailAttr); dirEmail != "" {
+		email = dirEmail
+	}
+
+	user, err := getOrPutUser(ctx, l.userStore, email, entry.GetAttributeValue(l.cfg.NameAttr))
+	if err != nil {
+		return docshelf.User{}, err
+	}
+
+	if err := l.syncGroups(ctx, user.ID, groups); err != nil {
+		return docshelf.User{}, err
+	}
+
+	// membership changes are recorded on the user as well, so they need to be fetched again
+	return l.userStore.GetUser(ctx, user.ID)
+}
+
+func (l LDAP) findUser(conn *ldap.Conn, email string) (*ldap.Entry, error) {
+	req := ldap.NewSearchRequest(
+		l.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, // anything more than one match is ambiguous
+		0,
+		false,
+		fmt.Sprintf(l.cfg.UserFilter, ldap.EscapeFilter(email)),
+		[]string{l.cfg.EmailAttr, l.cfg.NameAttr},
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("failed to search for ldap user: %w", err)
+	}
+
+	if res == nil || len(res.Entries) != 1 {
+		return nil, errors.New("could not find a unique ldap user to authenticate")
+	}
+
+	return res.Entries[0], nil
+}
+
+func (l LDAP) findGroups(conn *ldap.Conn, userDN string) ([]string, error) {
+	if len(l.cfg.GroupMapping) == 0 {
+		return nil, nil
+	}
+
+	req := ldap.NewSearchRequest(
+		l.cfg.GroupBaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		0,
+		false,
+		fmt.Sprintf(l.cfg.GroupFilter, ldap.EscapeFilter(userDN)),
+		[]string{l.cfg.GroupNameAttr},
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search for ldap groups: %w", err)
+	}
+
+	groups := make([]string, 0, len(res.Entries))
+	for _, entry := range res.Entries {
+		groups = append(groups, entry.GetAttributeValue(l.cfg.GroupNameAttr))
+	}
+
+	return groups, nil
+}
+
+// syncGroups adds the user to the docshelf Groups mapped from their LDAP groups, and removes them from
+// mapped Groups they no longer belong to. Mapped Groups that don't exist yet are created.
+func (l LDAP) syncGroups(ctx context.Context, userID string, ldapGroups []string) error {
+	if len(l.cfg.GroupMapping) == 0 {
+		return nil
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range l.cfg.GroupMapping {
+		wanted[name] = false
+	}
+
+	for _, group := range ldapGroups {
+		if name, ok := l.cfg.GroupMapping[group]; ok {
+			wanted[name] = true
+		}
+	}
+
+	existing, err := l.groupStore.ListGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list groups: %w", err)
+	}
+
+	byName := make(map[string]docshelf.Group)
+	for _, group := range existing {
+		byName[group.Name] = group
+	}
+
+	for name, member := range wanted {
+		group, ok := byName[name]
+		switch {
+		case member && !ok:
+			if _, err := l.groupStore.PutGroup(ctx, docshelf.Group{Name: name, Users: []string{userID}}); err != nil {
+				return fmt.Errorf("failed to create group %s: %w", name, err)
+			}
+		case member && !contains(group.Users, userID):
+			if err := l.groupStore.AddGroupUsers(ctx, group.ID, userID); err != nil {
+				return fmt.Errorf("failed to add user to group %s: %w", name, err)
+			}
+		case !member && ok && contains(group.Users, userID):
+			if err := l.groupStore.RemoveGroupUsers(ctx, group.ID, userID); err != nil {
+				return fmt.Errorf("failed to remove user from group %s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/docshelf/docshelf/bolt"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+const ldapDBName = "ldap_test.db"
+
+type ldapEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// a bare bones LDAP server that only understands simple binds and equality filters, which is all the
+// LDAP authenticator needs
+type stubDirectory struct {
+	sync.Mutex
+	listener net.Listener
+	entries  []ldapEntry
+}
+
+func newStubDirectory(t *testing.T, entries ...ldapEntry) *stubDirectory {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := &stubDirectory{listener: listener, entries: entries}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go dir.serve(conn)
+		}
+	}()
+
+	return dir
+}
+
+func (d *stubDirectory) URL() string {
+	return "ldap://" + d.listener.Addr().String()
+}
+
+func (d *stubDirectory) Close() {
+	d.listener.Close()
+}
+
+func (d *stubDirectory) setGroupMembers(cn string, members ...string) {
+	d.Lock()
+	defer d.Unlock()
+
+	for _, entry := range d.entries {
+		if entry.attrs["cn"] != nil && entry.attrs["cn"][0] == cn {
+			entry.attrs["member"] = members
+		}
+	}
+}
+
+func (d *stubDirectory) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			code := int64(ldap.LDAPResultInvalidCredentials)
+			if d.bind(op.Children[1].Value.(string), op.Children[2].Data.String()) {
+				code = ldap.LDAPResultSuccess
+			}
+
+			write(conn, id, ldapResult(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			filter, err := ldap.DecompileFilter(op.Children[6])
+			if err != nil {
+				return
+			}
+
+			for _, entry := range d.search(op.Children[0].Value.(string), filter) {
+				write(conn, id, entry)
+			}
+
+			write(conn, id, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		default:
+			return
+		}
+	}
+}
+
+func (d *stubDirectory) bind(dn, password string) bool {
+	d.Lock()
+	defer d.Unlock()
+
+	for _, entry := range d.entries {
+		if entry.dn == dn && entry.password != "" && entry.password == password {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *stubDirectory) search(base, filter string) []*ber.Packet {
+	d.Lock()
+	defer d.Unlock()
+
+	pair := strings.SplitN(strings.Trim(filter, "()"), "=", 2)
+	results := make([]*ber.Packet, 0)
+	for _, entry := range d.entries {
+		if !strings.HasSuffix(entry.dn, base) || !contains(entry.attrs[pair[0]], pair[1]) {
+			continue
+		}
+
+		res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Entry")
+		res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "DN"))
+		attrs := ber.NewSequence("Attributes")
+		for name, vals := range entry.attrs {
+			attr := ber.NewSequence("Attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, val := range vals {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, val, "Value"))
+			}
+
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+
+		res.AppendChild(attrs)
+		results = append(results, res)
+	}
+
+	return results
+}
+
+func ldapResult(app ber.Tag, code int64) *ber.Packet {
+	res := ber.Encode(ber.ClassApplication, ber.TypeConstructed, app, nil, "Result")
+	res.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Code"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	res.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Message"))
+	return res
+}
+
+func write(conn net.Conn, id int64, op *ber.Packet) {
+	packet := ber.NewSequence("Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "ID"))
+	packet.AppendChild(op)
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func Test_LDAPAuthenticate(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(ldapDBName) // cleanup database after test
+
+	store, err := bolt.New(ldapDBName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	aliceDN := "uid=alice,ou=people,dc=test"
+	dir := newStubDirectory(t,
+		ldapEntry{dn: "cn=admin,dc=test", password: "service"},
+		ldapEntry{dn: aliceDN, password: "hunter2", attrs: map[string][]string{
+			"mail":        {"alice@test.com"},
+			"displayName": {"Alice"},
+		}},
+		ldapEntry{dn: "cn=engineers,ou=groups,dc=test", attrs: map[string][]string{
+			"cn":     {"engineers"},
+			"member": {aliceDN},
+		}},
+		ldapEntry{dn: "cn=admins,ou=groups,dc=test", attrs: map[string][]string{
+			"cn":     {"admins"},
+			"member": {aliceDN},
+		}},
+	)
+	defer dir.Close()
+
+	auth := NewLDAP(store, store, LDAPConfig{
+		URL:          dir.URL(),
+		BindDN:       "cn=admin,dc=test",
+		BindPassword: "service",
+		BaseDN:       "ou=people,dc=test",
+		GroupBaseDN:  "ou=groups,dc=test",
+		GroupMapping: map[string]string{"engineers": "Engineering", "admins": "Admins"},
+	})
+
+	// RUN
+	first, err := auth.Authenticate(ctx, "alice@test.com", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir.setGroupMembers("admins")
+	second, err := auth.Authenticate(ctx, "alice@test.com", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, wrongErr := auth.Authenticate(ctx, "alice@test.com", "wrong")
+	_, unknownErr := auth.Authenticate(ctx, "bob@test.com", "hunter2")
+	_, emptyErr := auth.Authenticate(ctx, "alice@test.com", "")
+
+	groups, err := store.ListGroups(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if first.Email != "alice@test.com" || first.Name != "Alice" {
+		t.Fatalf("expected user to be created from directory attributes, got: %+v", first)
+	}
+
+	if first.ID != second.ID {
+		t.Fatal("expected the same user on every login")
+	}
+
+	if len(first.Groups) != 2 {
+		t.Fatalf("expected user to join both mapped groups, got: %v", first.Groups)
+	}
+
+	if len(second.Groups) != 1 {
+		t.Fatalf("expected user to leave the group they were removed from, got: %v", second.Groups)
+	}
+
+	for _, group := range groups {
+		member := contains(group.Users, first.ID)
+		if (group.Name == "Engineering") != member {
+			t.Fatalf("unexpected membership of %s: %v", group.Name, group.Users)
+		}
+	}
+
+	for name, err := range map[string]error{"wrong password": wrongErr, "unknown user": unknownErr, "empty password": emptyErr} {
+		if err == nil {
+			t.Fatalf("%s: expected login to fail", name)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/docshelf/docshelf"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+// OIDC config for authenticating against any OpenID Connect provider, e.g. Keycloak, Okta or Dex. The
+// provider's endpoints and signing keys are discovered from its issuer URL.
+type OIDC struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	userStore    docshelf.UserStore
+	client       *http.Client
+
+	// shared between copies so discovery and keys are only fetched once
+	state *oidcState
+}
+
+type oidcState struct {
+	sync.Mutex
+	provider *oidcProvider
+	keys     *KeyCache
+}
+
+type oidcProvider struct {
+	Issuer        string `json:"issuer"`
+	TokenEndpoint string `json:"token_endpoint"`
+	JWKSURI       string `json:"jwks_uri"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type tokenResponse struct {
+	IDToken string `json:"id_token"`
+	Error   string `json:"error"`
+}
+
+// audience handles the aud claim being either a single string or a list of them.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+
+	*a = list
+	return nil
+}
+
+type oidcClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	ExpiresAt     int64    `json:"exp"`
+	NotBefore     int64    `json:"nbf"`
+	Email         string   `json:"email"`
+	EmailVerified *bool    `json:"email_verified"`
+	Name          string   `json:"name"`
+}
+
+// Valid implements the jwt.Claims interface by checking the time based claims.
+func (c oidcClaims) Valid() error {
+	now := time.Now().Unix()
+	if c.ExpiresAt == 0 || now > c.ExpiresAt {
+		return errors.New("token is expired")
+	}
+
+	if c.NotBefore != 0 && now < c.NotBefore {
+		return errors.New("token is not valid yet")
+	}
+
+	return nil
+}
+
+// NewOIDC returns a new OIDC authenticator for the provider at the given issuer URL. The provider's
+// configuration is discovered the first time someone logs in.
+func NewOIDC(userStore docshelf.UserStore, issuer, clientID, clientSecret, redirectURL string) OIDC {
+	return OIDC{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		userStore:    userStore,
+		client:       http.DefaultClient,
+		state:        &oidcState{},
+	}
+}
+
+// Authenticate implements the docshelf.Authenticator interface. The email is ignored and the token is
+// expected to be an authorization code, which is exchanged with the provider for an ID token. The ID
+// token is validated against the provider's signing keys, issuer and our client ID before the user is
+// logged in using its email claim.
+func (o OIDC) Authenticate(ctx context.Context, email, token string) (docshelf.User, error) {
+	provider, err := o.discover()
+	if err != nil {
+		return docshelf.User{}, err
+	}
+
+	idToken, err := o.exchange(provider, token)
+	if err != nil {
+		return docshelf.User{}, err
+	}
+
+	claims, err := o.validate(provider, idToken)
+	if err != nil {
+		return docshelf.User{}, err
+	}
+
+	return getOrPutUser(ctx, o.userStore, claims.Email, claims.Name)
+}
+
+func (o OIDC) discover() (*oidcProvider, error) {
+	o.state.Lock()
+	defer o.state.Unlock()
+
+	if o.state.provider != nil {
+		return o.state.provider, nil
+	}
+
+	var provider oidcProvider
+	if err := o.getJSON(o.issuer+discoveryPath, &provider); err != nil {
+		return nil, fmt.Errorf("failed to discover oidc provider: %w", err)
+	}
+
+	// the spec requires the discovered issuer to match exactly, otherwise tokens could be minted elsewhere
+	if provider.Issuer != o.issuer {
+		return nil, fmt.Errorf("discovered issuer %q does not match %q", provider.Issuer, o.issuer)
+	}
+
+	o.state.provider = &provider
+	o.state.keys = NewKeyCache(o.client, provider.JWKSURI, ParseJWKS)
+	return &provider, nil
+}
+
+func (o OIDC) exchange(provider *oidcProvider, code string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", o.redirectURL)
+	form.Set("client_id", o.clientID)
+	form.Set("client_secret", o.clientSecret)
+
+	res, err := o.client.PostForm(provider.TokenEndpoint, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tokenRes tokenResponse
+	if err := json.Unmarshal(body, &tokenRes); err != nil {
+		return "", fmt.Errorf("failed to unmarshal token response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK || tokenRes.IDToken == "" {
+		return "", fmt.Errorf("token exchange failed with status %d: %s", res.StatusCode, tokenRes.Error)
+	}
+
+	return tokenRes.IDToken, nil
+}
+
+func (o OIDC) validate(provider *oidcProvider, token string) (*oidcClaims, error) {
+	tok, err := jwt.ParseWithClaims(token, &oidcClaims{}, func(tok *jwt.Token) (interface{}, error) {
+		if _, ok := tok.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", tok.Header["alg"])
+		}
+
+		kid, _ := tok.Header["kid"].(string)
+		return o.state.keys.Key(kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jwt: %w", err)
+	}
+
+	claims := tok.Claims.(*oidcClaims)
+	if claims.Issuer != provider.Issuer {
+		return nil, errors.New("invalid issuer")
+	}
+
+	if !contains(claims.Audience, o.clientID) {
+		return nil, errors.New("clientID in token did not match server")
+	}
+
+	if claims.Email == "" {
+		return nil, errors.New("token does not contain an email")
+	}
+
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		return nil, errors.New("email in token has not been verified")
+	}
+
+	return claims, nil
+}
+
+func (o OIDC) getJSON(url string, out interface{}) error {
+	res, err := o.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", res.StatusCode, url)
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+// parseJWKS turns the RSA signing keys in a JSON Web Key Set into public keys indexed by kid.
+func parseJWKS(set jwks) (map[string]*rsa.PublicKey, error) {
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus for key %s: %w", k.Kid, err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent for key %s: %w", k.Kid, err)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+func contains(slice []string, el string) bool {
+	for _, s := range slice {
+		if s == el {
+			return true
+		}
+	}
+
+	return false
+}
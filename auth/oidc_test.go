@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/docshelf/docshelf"
+	"github.com/rs/xid"
+)
+
+// a userStore that keeps users in memory, keyed by both ID and email
+type memUserStore map[string]docshelf.User
+
+func (m memUserStore) GetUser(ctx context.Context, id string) (docshelf.User, error) {
+	if user, ok := m[id]; ok {
+		return user, nil
+	}
+
+	return docshelf.User{}, docshelf.NewErrNotFound("user does not exist")
+}
+
+func (m memUserStore) ListUsers(ctx context.Context) ([]docshelf.User, error) {
+	return nil, nil
+}
+
+func (m memUserStore) PutUser(ctx context.Context, user docshelf.User) (string, error) {
+	if user.ID == "" {
+		user.ID = xid.New().String()
+	}
+
+	m[user.ID] = user
+	m[user.Email] = user
+	return user.ID, nil
+}
+
+func (m memUserStore) RemoveUser(ctx context.Context, id string) error {
+	delete(m, id)
+	return nil
+}
+
+// a stub identity provider that hands out ID tokens for any authorization code
+type stubIdP struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+	claims jwt.MapClaims
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &stubIdP{key: key, kid: "test-key"}
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(oidcProvider{
+			Issuer:        idp.URL,
+			TokenEndpoint: idp.URL + "/token",
+			JWKSURI:       idp.URL + "/keys",
+		})
+	})
+
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwks{Keys: []jwk{{
+			Kid: idp.kid,
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "valid-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(tokenResponse{Error: "invalid_grant"})
+			return
+		}
+
+		tok := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims)
+		tok.Header["kid"] = idp.kid
+		signed, err := tok.SignedString(idp.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_ = json.NewEncoder(w).Encode(tokenResponse{IDToken: signed})
+	})
+
+	idp.Server = httptest.NewServer(mux)
+	return idp
+}
+
+func (idp *stubIdP) setClaims(overrides jwt.MapClaims) {
+	idp.claims = jwt.MapClaims{
+		"iss":   idp.URL,
+		"sub":   "subject",
+		"aud":   "docshelf",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"email": "oidc@test.com",
+		"name":  "OIDC User",
+	}
+
+	for k, v := range overrides {
+		idp.claims[k] = v
+	}
+}
+
+func Test_OIDCAuthenticate(t *testing.T) {
+	// SETUP
+	idp := newStubIdP(t)
+	defer idp.Close()
+
+	cases := []struct {
+		name      string
+		code      string
+		overrides jwt.MapClaims
+		valid     bool
+	}{
+		{"valid token", "valid-code", nil, true},
+		{"audience list", "valid-code", jwt.MapClaims{"aud": []string{"other", "docshelf"}}, true},
+		{"bad code", "invalid-code", nil, false},
+		{"wrong audience", "valid-code", jwt.MapClaims{"aud": "other"}, false},
+		{"wrong issuer", "valid-code", jwt.MapClaims{"iss": "https://evil.example.com"}, false},
+		{"expired", "valid-code", jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, false},
+		{"unverified email", "valid-code", jwt.MapClaims{"email_verified": false}, false},
+	}
+
+	for _, c := range cases {
+		store := make(memUserStore)
+		oidc := NewOIDC(store, idp.URL+"/", "docshelf", "secret", "http://localhost/oauth/oidc")
+		idp.setClaims(c.overrides)
+
+		// RUN
+		user, err := oidc.Authenticate(context.Background(), "", c.code)
+
+		// ASSERT
+		if c.valid && err != nil {
+			t.Fatalf("%s: expected login to succeed, got: %v", c.name, err)
+		}
+
+		if !c.valid && err == nil {
+			t.Fatalf("%s: expected login to fail", c.name)
+		}
+
+		if c.valid && (user.Email != "oidc@test.com" || user.ID == "") {
+			t.Fatalf("%s: expected user to be created from claims, got: %+v", c.name, user)
+		}
+	}
+}
+
+func Test_OIDCKeyRotation(t *testing.T) {
+	// SETUP
+	idp := newStubIdP(t)
+	defer idp.Close()
+
+	oidc := NewOIDC(make(memUserStore), idp.URL, "docshelf", "secret", "")
+	idp.setClaims(nil)
+
+	if _, err := oidc.Authenticate(context.Background(), "", "valid-code"); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp.key = key
+	idp.kid = "rotated-key"
+
+	// unknown kids right after a fetch are ignored to protect the provider, so move past that
+	oidc.state.keys.now = func() time.Time { return time.Now().Add(unknownKeyBackoff) }
+
+	// RUN
+	_, err = oidc.Authenticate(context.Background(), "", "valid-code")
+
+	// ASSERT
+	if err != nil {
+		t.Fatalf("expected rotated key to be fetched, got: %v", err)
+	}
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/docshelf/docshelf"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TODO (erik): Adjust the cost parameter once we can benchmark the time spent hashing the password.
+const passwordCost = 12
+
+// Errors returned by Passwords that are the caller's fault rather than something going wrong.
+var (
+	ErrWrongPassword = errors.New("current password is incorrect")
+	ErrEmptyPassword = errors.New("password can't be empty")
+	ErrInvalidReset  = errors.New("password reset token is invalid or expired")
+)
+
+// HashPassword hashes a password with bcrypt so it can be stored as a User's token.
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	return string(hashed), nil
+}
+
+// Passwords lets Users logging in with basic auth change their password, or reset it through a single use
+// token delivered by a Notifier if they've forgotten it. Setting a password revokes the User's sessions and
+// API tokens, so anyone who got hold of them loses access along with the old password.
+type Passwords struct {
+	userStore    docshelf.UserStore
+	resetStore   docshelf.PasswordResetStore
+	sessionStore docshelf.SessionStore
+	tokenStore   docshelf.TokenStore
+	notifier     docshelf.Notifier
+	ttl          time.Duration
+	resetURL     string
+}
+
+// NewPasswords returns a new Passwords manager. Reset tokens are valid for the given duration and are sent
+// to Users as a link to resetURL.
+func NewPasswords(userStore docshelf.UserStore, resetStore docshelf.PasswordResetStore, sessionStore docshelf.SessionStore, tokenStore docshelf.TokenStore, notifier docshelf.Notifier, ttl time.Duration, resetURL string) Passwords {
+	return Passwords{
+		userStore:    userStore,
+		resetStore:   resetStore,
+		sessionStore: sessionStore,
+		tokenStore:   tokenStore,
+		notifier:     notifier,
+		ttl:          ttl,
+		resetURL:     resetURL,
+	}
+}
+
+// ChangePassword implements the docshelf.PasswordManager interface. The User's current password has to be
+// given for the change to go through.
+func (p Passwords) ChangePassword(ctx context.Context, user docshelf.User, current, password string) error {
+	// the user might have come from an API token or a session, so the stored hash is fetched fresh
+	existing, err := p.userStore.GetUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(existing.Token), []byte(current)); err != nil {
+		return ErrWrongPassword
+	}
+
+	return p.setPassword(ctx, existing, password)
+}
+
+// RequestReset implements the docshelf.PasswordManager interface. A reset token is sent to the User with the
+// given email. Unknown emails are silently ignored so they can't be used to find out who has an account.
+func (p Passwords) RequestReset(ctx context.Context, email string) error {
+	user, err := p.userStore.GetUser(ctx, email)
+	if err != nil {
+		if docshelf.CheckNotFound(err) || docshelf.CheckRemoved(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	reset, token, err := docshelf.NewPasswordReset(user.ID, p.ttl)
+	if err != nil {
+		return fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	if err := p.resetStore.PutPasswordReset(ctx, reset); err != nil {
+		return fmt.Errorf("failed to save password reset: %w", err)
+	}
+
+	body := fmt.Sprintf(
+		"A password reset was requested for your docshelf account. Follow the link below to choose a new password:\n\n%s?token=%s\n\nThe link expires in %s. If you didn't ask for this, you can ignore this message.",
+		p.resetURL,
+		token,
+		p.ttl,
+	)
+
+	if err := p.notifier.Notify(ctx, user, "Reset your docshelf password", body); err != nil {
+		return fmt.Errorf("failed to deliver password reset: %w", err)
+	}
+
+	return nil
+}
+
+// ResetPassword implements the docshelf.PasswordManager interface. The token has to belong to an active
+// PasswordReset, which is used up in the process.
+func (p Passwords) ResetPassword(ctx context.Context, token, password string) error {
+	if password == "" {
+		return ErrEmptyPassword
+	}
+
+	id, secret, err := docshelf.ParsePasswordReset(token)
+	if err != nil {
+		return ErrInvalidReset
+	}
+
+	reset, err := p.resetStore.GetPasswordReset(ctx, id)
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			return ErrInvalidReset
+		}
+
+		return fmt.Errorf("failed to fetch password reset: %w", err)
+	}
+
+	if !reset.Matches(secret) || !reset.Active() {
+		return ErrInvalidReset
+	}
+
+	// marking the reset as used first makes sure racing requests can't both set a password
+	if err := p.resetStore.UsePasswordReset(ctx, id); err != nil {
+		if docshelf.CheckConflict(err) {
+			return ErrInvalidReset
+		}
+
+		return fmt.Errorf("failed to use password reset: %w", err)
+	}
+
+	user, err := p.userStore.GetUser(ctx, reset.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	return p.setPassword(ctx, user, password)
+}
+
+func (p Passwords) setPassword(ctx context.Context, user docshelf.User, password string) error {
+	if password == "" {
+		return ErrEmptyPassword
+	}
+
+	hashed, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user.Token = hashed
+	if _, err := p.userStore.PutUser(ctx, user); err != nil {
+		return fmt.Errorf("failed to save password: %w", err)
+	}
+
+	return p.revokeAccess(ctx, user.ID)
+}
+
+// revokeAccess ends every session and removes every API token belonging to a User.
+func (p Passwords) revokeAccess(ctx context.Context, userID string) error {
+	if _, err := p.sessionStore.RevokeUserSessions(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	tokens, err := p.tokenStore.ListTokens(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list api tokens: %w", err)
+	}
+
+	for _, token := range tokens {
+		if err := p.tokenStore.RemoveToken(ctx, token.ID); err != nil {
+			return fmt.Errorf("failed to remove api token: %w", err)
+		}
+	}
+
+	return nil
+}
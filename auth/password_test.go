@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docshelf/docshelf"
+	"github.com/docshelf/docshelf/bolt"
+)
+
+const passwordDBName = "password_test.db"
+
+// a Notifier that keeps messages around instead of delivering them
+type memNotifier map[string]string
+
+func (m memNotifier) Notify(ctx context.Context, user docshelf.User, subject, body string) error {
+	m[user.Email] = body
+	return nil
+}
+
+// resetToken pulls the token out of the link in a reset message.
+func resetToken(t *testing.T, body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if link, err := url.Parse(line); err == nil && link.Query().Get("token") != "" {
+			return link.Query().Get("token")
+		}
+	}
+
+	t.Fatalf("no reset token in message: %s", body)
+	return ""
+}
+
+func Test_Passwords(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(passwordDBName) // cleanup database after test
+
+	store, err := bolt.New(passwordDBName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	hashed, err := HashPassword("original")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := store.PutUser(ctx, docshelf.User{Email: "user@docshelf.io", Token: hashed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := make(memNotifier)
+	passwords := NewPasswords(store, store, store, store, notifier, time.Hour, "http://localhost/reset")
+	basic := NewBasic(store)
+	user := docshelf.User{ID: id}
+
+	// RUN
+	wrongErr := passwords.ChangePassword(ctx, user, "wrong", "changed")
+	emptyErr := passwords.ChangePassword(ctx, user, "original", "")
+	if err := passwords.ChangePassword(ctx, user, "original", "changed"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, changedErr := basic.Authenticate(ctx, "user@docshelf.io", "changed")
+
+	if err := passwords.RequestReset(ctx, "user@docshelf.io"); err != nil {
+		t.Fatal(err)
+	}
+
+	unknownErr := passwords.RequestReset(ctx, "nobody@docshelf.io")
+	token := resetToken(t, notifier["user@docshelf.io"])
+	tamperedErr := passwords.ResetPassword(ctx, token+"x", "reset")
+	if err := passwords.ResetPassword(ctx, token, "reset"); err != nil {
+		t.Fatal(err)
+	}
+
+	reuseErr := passwords.ResetPassword(ctx, token, "again")
+	_, resetErr := basic.Authenticate(ctx, "user@docshelf.io", "reset")
+	_, staleErr := basic.Authenticate(ctx, "user@docshelf.io", "changed")
+
+	// ASSERT
+	if !errors.Is(wrongErr, ErrWrongPassword) {
+		t.Fatalf("expected wrong current password to be rejected, got: %v", wrongErr)
+	}
+
+	if !errors.Is(emptyErr, ErrEmptyPassword) {
+		t.Fatalf("expected empty password to be rejected, got: %v", emptyErr)
+	}
+
+	if changedErr != nil {
+		t.Fatalf("expected changed password to work, got: %v", changedErr)
+	}
+
+	if unknownErr != nil || len(notifier) != 1 {
+		t.Fatal("expected unknown email to be silently ignored")
+	}
+
+	if !errors.Is(tamperedErr, ErrInvalidReset) {
+		t.Fatalf("expected tampered token to be rejected, got: %v", tamperedErr)
+	}
+
+	if !errors.Is(reuseErr, ErrInvalidReset) {
+		t.Fatalf("expected reset token to be single use, got: %v", reuseErr)
+	}
+
+	if resetErr != nil {
+		t.Fatalf("expected reset password to work, got: %v", resetErr)
+	}
+
+	if staleErr == nil {
+		t.Fatal("expected old password to stop working after reset")
+	}
+}
+
+func Test_PasswordsRevokeAccess(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(passwordDBName) // cleanup database after test
+
+	store, err := bolt.New(passwordDBName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	hashed, err := HashPassword("original")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := store.PutUser(ctx, docshelf.User{Email: "user@docshelf.io", Token: hashed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherID, err := store.PutUser(ctx, docshelf.User{Email: "other@docshelf.io", Token: hashed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	notifier := make(memNotifier)
+	passwords := NewPasswords(store, store, store, store, notifier, time.Hour, "http://localhost/reset")
+	sessions := NewSessions(store, []byte("secret"), time.Hour)
+	user := docshelf.User{ID: id, Email: "user@docshelf.io"}
+
+	changedSession, _, err := sessions.StartSession(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherSession, _, err := sessions.StartSession(ctx, docshelf.User{ID: otherID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, _, err := docshelf.NewAPIToken(id, "ci", []docshelf.Scope{docshelf.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PutToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	if err := passwords.ChangePassword(ctx, user, "original", "changed"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, changedErr := sessions.VerifySession(ctx, changedSession)
+	_, otherErr := sessions.VerifySession(ctx, otherSession)
+	changedTokens, err := store.ListTokens(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resetSession, _, err := sessions.StartSession(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := passwords.RequestReset(ctx, "user@docshelf.io"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := passwords.ResetPassword(ctx, resetToken(t, notifier["user@docshelf.io"]), "reset"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, resetErr := sessions.VerifySession(ctx, resetSession)
+
+	// ASSERT
+	if changedErr == nil {
+		t.Fatal("expected sessions to be revoked when the password changes")
+	}
+
+	if otherErr != nil {
+		t.Fatalf("expected other users' sessions to be left alone, got: %v", otherErr)
+	}
+
+	if len(changedTokens) != 0 {
+		t.Fatalf("expected api tokens to be removed when the password changes, got %d", len(changedTokens))
+	}
+
+	if resetErr == nil {
+		t.Fatal("expected sessions to be revoked when the password is reset")
+	}
+}
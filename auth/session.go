@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/docshelf/docshelf"
+)
+
+const sessionIssuer = "docshelf"
+
+// Sessions hands out HMAC signed session tokens that expire on their own. Every token is backed by a
+// Session in a SessionStore, so it can also be revoked before it expires.
+type Sessions struct {
+	store  docshelf.SessionStore
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSessions returns a new Sessions manager that signs tokens with the given secret and keeps them valid
+// for the given duration.
+func NewSessions(store docshelf.SessionStore, secret []byte, ttl time.Duration) Sessions {
+	return Sessions{
+		store:  store,
+		secret: secret,
+		ttl:    ttl,
+	}
+}
+
+// StartSession implements the docshelf.SessionManager interface. It records a new Session for the User and
+// returns a signed token referencing it.
+func (s Sessions) StartSession(ctx context.Context, user docshelf.User) (string, docshelf.Session, error) {
+	now := time.Now()
+	session := docshelf.Session{
+		UserID:    user.ID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+
+	id, err := s.store.PutSession(ctx, session)
+	if err != nil {
+		return "", session, fmt.Errorf("failed to save session: %w", err)
+	}
+	session.ID = id
+
+	claims := jwt.StandardClaims{
+		Id:        session.ID,
+		Subject:   user.ID,
+		Issuer:    sessionIssuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: session.ExpiresAt.Unix(),
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", session, fmt.Errorf("failed to sign session token: %w", err)
+	}
+
+	return token, session, nil
+}
+
+// VerifySession implements the docshelf.SessionManager interface. It checks the token's signature and
+// expiry, and makes sure the Session behind it hasn't been revoked.
+func (s Sessions) VerifySession(ctx context.Context, token string) (docshelf.Session, error) {
+	claims, err := s.parse(token)
+	if err != nil {
+		return docshelf.Session{}, err
+	}
+
+	session, err := s.store.GetSession(ctx, claims.Id)
+	if err != nil {
+		return docshelf.Session{}, fmt.Errorf("failed to fetch session: %w", err)
+	}
+
+	if session.UserID != claims.Subject {
+		return docshelf.Session{}, errors.New("session does not belong to token subject")
+	}
+
+	if !session.Active() {
+		return docshelf.Session{}, errors.New("session is no longer active")
+	}
+
+	return session, nil
+}
+
+// EndSession implements the docshelf.SessionManager interface. It revokes the Session behind the token so
+// it can't be used again.
+func (s Sessions) EndSession(ctx context.Context, token string) error {
+	claims, err := s.parse(token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.store.RevokeSession(ctx, claims.Id); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+func (s Sessions) parse(token string) (*jwt.StandardClaims, error) {
+	tok, err := jwt.ParseWithClaims(token, &jwt.StandardClaims{}, func(tok *jwt.Token) (interface{}, error) {
+		if _, ok := tok.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", tok.Header["alg"])
+		}
+
+		return s.secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse session token: %w", err)
+	}
+
+	claims := tok.Claims.(*jwt.StandardClaims)
+	if !claims.VerifyIssuer(sessionIssuer, true) {
+		return nil, errors.New("invalid session issuer")
+	}
+
+	return claims, nil
+}
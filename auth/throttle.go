@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docshelf/docshelf"
+)
+
+// DefaultAccountPolicy throttles logins to a single account. A handful of typos are free, after that each
+// failure doubles the wait and enough of them lock the account until it's unlocked or the lockout ends.
+var DefaultAccountPolicy = docshelf.ThrottlePolicy{
+	FreeAttempts: 5,
+	BaseDelay:    time.Second,
+	MaxDelay:     5 * time.Minute,
+	LockoutAfter: 15,
+	Lockout:      time.Hour,
+	Window:       24 * time.Hour,
+}
+
+// DefaultIPPolicy throttles logins from a single address. Addresses can be shared by a lot of people, so it
+// allows a lot more failures than DefaultAccountPolicy.
+var DefaultIPPolicy = docshelf.ThrottlePolicy{
+	FreeAttempts: 25,
+	BaseDelay:    time.Second,
+	MaxDelay:     5 * time.Minute,
+	LockoutAfter: 100,
+	Lockout:      time.Hour,
+	Window:       time.Hour,
+}
+
+// Throttle slows down repeated failed logins by account and by client address. Every attempt is counted as
+// a failure up front and forgiven once it succeeds, so a burst of concurrent guesses can't all get in before
+// the first one fails. Failures, lockouts and unlocks are recorded in an AuditStore.
+type Throttle struct {
+	store   docshelf.LoginAttemptStore
+	audit   docshelf.AuditStore
+	account docshelf.ThrottlePolicy
+	ip      docshelf.ThrottlePolicy
+	scope   string
+	now     func() time.Time
+}
+
+// NewThrottle returns a new Throttle that tracks attempts with the given policies.
+func NewThrottle(store docshelf.LoginAttemptStore, audit docshelf.AuditStore, account, ip docshelf.ThrottlePolicy) Throttle {
+	return Throttle{
+		store:   store,
+		audit:   audit,
+		account: account,
+		ip:      ip,
+		now:     time.Now,
+	}
+}
+
+// Scoped returns a copy of the Throttle that counts attempts apart from the original, so that something
+// like password reset requests can be throttled without using up anyone's login attempts.
+func (t Throttle) Scoped(scope string) Throttle {
+	t.scope = scope + ":"
+	return t
+}
+
+// Attempt implements the docshelf.LoginThrottle interface.
+func (t Throttle) Attempt(ctx context.Context, email, ip string) (time.Duration, error) {
+	wait, err := t.attempt(ctx, t.ipKey(ip), t.ip)
+	if err != nil || wait > 0 {
+		t.record(ctx, docshelf.AuditLoginThrottled, email, ip, "")
+		return wait, err
+	}
+
+	if email == "" {
+		return 0, nil
+	}
+
+	wait, err = t.attempt(ctx, t.accountKey(email), t.account)
+	if err != nil || wait > 0 {
+		// the attempt never happened, so it shouldn't count against the address either
+		if releaseErr := t.release(ctx, t.ipKey(ip)); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+
+		t.record(ctx, docshelf.AuditLoginThrottled, email, ip, "")
+		return wait, err
+	}
+
+	return 0, nil
+}
+
+// Finish implements the docshelf.LoginThrottle interface. Successful logins clear the account's failures,
+// but only forgive the single attempt for the address so one valid account can't be used to reset it.
+func (t Throttle) Finish(ctx context.Context, email, ip string, success bool) error {
+	if success {
+		if email != "" {
+			if err := t.store.RemoveLoginAttempts(ctx, t.accountKey(email)); err != nil {
+				return fmt.Errorf("failed to clear login attempts: %w", err)
+			}
+		}
+
+		return t.release(ctx, t.ipKey(ip))
+	}
+
+	t.record(ctx, docshelf.AuditLoginFailed, email, ip, "")
+	if email == "" {
+		return nil
+	}
+
+	attempts, err := t.store.GetLoginAttempts(ctx, t.accountKey(email))
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			return nil
+		}
+
+		return fmt.Errorf("failed to fetch login attempts: %w", err)
+	}
+
+	// only the failure that crosses the threshold gets recorded, not every one after it
+	if attempts.Failures == t.account.LockoutAfter && t.account.Locked(attempts.Failures) {
+		t.record(ctx, docshelf.AuditAccountLocked, email, ip, "")
+	}
+
+	return nil
+}
+
+// Unlock implements the docshelf.LoginThrottle interface. It clears the failures of an account, an address,
+// or both.
+func (t Throttle) Unlock(ctx context.Context, email, ip, actorID string) error {
+	if email != "" {
+		if err := t.store.RemoveLoginAttempts(ctx, t.accountKey(email)); err != nil {
+			return fmt.Errorf("failed to unlock account: %w", err)
+		}
+	}
+
+	if ip != "" {
+		if err := t.store.RemoveLoginAttempts(ctx, t.ipKey(ip)); err != nil {
+			return fmt.Errorf("failed to unlock address: %w", err)
+		}
+	}
+
+	t.record(ctx, docshelf.AuditAccountUnlocked, email, ip, actorID)
+	return nil
+}
+
+// attempt counts a new attempt for the key unless it has to wait, in which case the wait is returned.
+func (t Throttle) attempt(ctx context.Context, key string, policy docshelf.ThrottlePolicy) (time.Duration, error) {
+	now := t.now()
+	var wait time.Duration
+	if _, err := t.store.UpdateLoginAttempts(ctx, key, func(attempts docshelf.LoginAttempts) docshelf.LoginAttempts {
+		if wait = attempts.Wait(policy, now); wait > 0 {
+			return attempts
+		}
+
+		attempts = attempts.Current(policy, now)
+		attempts.Failures++
+		attempts.LastFailure = now
+		return attempts
+	}); err != nil {
+		return 0, fmt.Errorf("failed to count login attempt: %w", err)
+	}
+
+	return wait, nil
+}
+
+// release takes back a single attempt that was counted for the key.
+func (t Throttle) release(ctx context.Context, key string) error {
+	if _, err := t.store.UpdateLoginAttempts(ctx, key, func(attempts docshelf.LoginAttempts) docshelf.LoginAttempts {
+		if attempts.Failures > 0 {
+			attempts.Failures--
+		}
+
+		return attempts
+	}); err != nil {
+		return fmt.Errorf("failed to release login attempt: %w", err)
+	}
+
+	return nil
+}
+
+// the audit log is best effort, a failed write shouldn't decide whether someone gets to log in
+func (t Throttle) record(ctx context.Context, action docshelf.AuditAction, email, ip, actorID string) {
+	_ = t.audit.PutAuditEvent(ctx, docshelf.AuditEvent{
+		Action:    action,
+		Email:     email,
+		IP:        ip,
+		ActorID:   actorID,
+		CreatedAt: t.now(),
+	})
+}
+
+func (t Throttle) accountKey(email string) string {
+	return t.scope + "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func (t Throttle) ipKey(ip string) string {
+	return t.scope + "ip:" + ip
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/docshelf/docshelf"
+	"github.com/docshelf/docshelf/bolt"
+)
+
+const throttleDBName = "throttle_test.db"
+
+func Test_Throttle(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(throttleDBName) // cleanup database after test
+
+	store, err := bolt.New(throttleDBName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	start := time.Now()
+	clock := &fakeClock{now: start}
+	policy := docshelf.ThrottlePolicy{
+		FreeAttempts: 2,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		LockoutAfter: 4,
+		Lockout:      24 * time.Hour,
+		Window:       48 * time.Hour,
+	}
+
+	throttle := NewThrottle(store, store, policy, DefaultIPPolicy)
+	throttle.now = clock.Now
+
+	fail := func(email string) time.Duration {
+		wait, err := throttle.Attempt(ctx, email, "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if wait == 0 {
+			if err := throttle.Finish(ctx, email, "10.0.0.1", false); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return wait
+	}
+
+	// RUN
+	free := []time.Duration{fail("user@docshelf.io"), fail("user@docshelf.io")}
+	throttled := fail("USER@docshelf.io")
+	otherAccount := fail("other@docshelf.io")
+
+	clock.now = clock.now.Add(time.Minute)
+	afterBackoff := fail("user@docshelf.io")
+	clock.now = clock.now.Add(2 * time.Minute)
+	lockingFailure := fail("user@docshelf.io")
+	clock.now = clock.now.Add(time.Hour)
+	locked := fail("user@docshelf.io")
+
+	if err := throttle.Unlock(ctx, "user@docshelf.io", "", "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	unlocked, err := throttle.Attempt(ctx, "user@docshelf.io", "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := throttle.Finish(ctx, "user@docshelf.io", "10.0.0.1", true); err != nil {
+		t.Fatal(err)
+	}
+
+	_, clearedErr := store.GetLoginAttempts(ctx, throttle.accountKey("user@docshelf.io"))
+	ipAttempts, err := store.GetLoginAttempts(ctx, throttle.ipKey("10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := store.ListAuditEvents(ctx, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if free[0] != 0 || free[1] != 0 {
+		t.Fatalf("expected the first failures to be free, got %v", free)
+	}
+
+	if throttled != time.Minute {
+		t.Fatalf("expected to wait for the base delay regardless of email case, got %s", throttled)
+	}
+
+	if otherAccount != 0 {
+		t.Fatalf("expected other accounts not to be throttled, got %s", otherAccount)
+	}
+
+	if afterBackoff != 0 || lockingFailure != 0 {
+		t.Fatal("expected attempts to be allowed once the delay passed")
+	}
+
+	if locked != 23*time.Hour {
+		t.Fatalf("expected the account to be locked out, got %s", locked)
+	}
+
+	if unlocked != 0 {
+		t.Fatalf("expected unlocked account to be allowed, got %s", unlocked)
+	}
+
+	if !docshelf.CheckNotFound(clearedErr) {
+		t.Fatalf("expected successful login to clear account failures, got: %v", clearedErr)
+	}
+
+	// the successful login only forgives its own attempt for the address
+	if ipAttempts.Failures != 5 {
+		t.Fatalf("expected address to keep its failures, got %d", ipAttempts.Failures)
+	}
+
+	counts := make(map[docshelf.AuditAction]int)
+	for _, event := range events {
+		counts[event.Action]++
+	}
+
+	expected := map[docshelf.AuditAction]int{
+		docshelf.AuditLoginFailed:     5,
+		docshelf.AuditLoginThrottled:  2,
+		docshelf.AuditAccountLocked:   1,
+		docshelf.AuditAccountUnlocked: 1,
+	}
+
+	for action, count := range expected {
+		if counts[action] != count {
+			t.Fatalf("expected %d %s events, got %d", count, action, counts[action])
+		}
+	}
+}
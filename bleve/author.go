@@ -0,0 +1,52 @@
+package bleve
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docshelf/docshelf"
+)
+
+// authors resolves the names and emails used in author searches to user IDs. Without a UserStore, authors
+// can only be searched by ID.
+type authors struct {
+	users docshelf.UserStore
+}
+
+// resolve returns the IDs of every user a search term could refer to. A term matches a user's ID, email, full
+// name or any single word of their name, ignoring case. The term itself is always included so docs can
+// still be found by the ID of a user that has since been removed.
+func (a *authors) resolve(ctx context.Context, term string) ([]string, error) {
+	ids := []string{term}
+	if a == nil || a.users == nil {
+		return ids, nil
+	}
+
+	users, err := a.users.ListUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users for author search: %w", err)
+	}
+
+	for _, user := range users {
+		if user.ID != term && matchesUser(user, term) {
+			ids = append(ids, user.ID)
+		}
+	}
+
+	return ids, nil
+}
+
+func matchesUser(user docshelf.User, term string) bool {
+	if strings.EqualFold(user.Email, term) || strings.EqualFold(user.Name, term) {
+		return true
+	}
+
+	for _, word := range strings.Fields(user.Name) {
+		if strings.EqualFold(word, term) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/lang/en"
 	"github.com/docshelf/docshelf"
 	"github.com/docshelf/docshelf/env"
 )
@@ -14,21 +17,23 @@ const defIndexPath = "docshelf.bleve"
 
 // An Index implements the docshelf.TextIndex interface.
 type Index struct {
-	idx bleve.Index
+	idx     bleve.Index
+	authors *authors
 }
 
-// New returns a new bleve Index.
+// New returns a new bleve Index. Indexes that already exist keep the mapping they were created with, so
+// an index created before docs had an explicit mapping has to be removed and rebuilt to support field
+// scoped searches.
 func New() (Index, error) {
 	path := env.GetEnvString("DS_INDEX_PATH", defIndexPath)
 	stat, err := os.Stat(path)
 	if err != nil {
-		mapping := bleve.NewIndexMapping()
-		idx, err := bleve.New(env.GetEnvString("DS_INDEX_PATH", defIndexPath), mapping)
+		idx, err := bleve.New(env.GetEnvString("DS_INDEX_PATH", defIndexPath), docMapping())
 		if err != nil {
 			return Index{}, err
 		}
 
-		return Index{idx}, nil
+		return Index{idx, &authors{}}, nil
 	}
 
 	if !stat.IsDir() {
@@ -40,7 +45,13 @@ func New() (Index, error) {
 		return Index{}, err
 	}
 
-	return Index{idx}, nil
+	return Index{idx, &authors{}}, nil
+}
+
+// UseUserStore lets author: and editor: searches match users by name or email instead of only by ID. The
+// UserStore usually belongs to a backend that needs the Index to be created first, so it's set afterwards.
+func (i Index) UseUserStore(users docshelf.UserStore) {
+	i.authors.users = users
 }
 
 // Index takes a docshelf Doc and indexes it in bleve.
@@ -48,23 +59,62 @@ func (i Index) Index(ctx context.Context, doc docshelf.Doc) error {
 	return i.idx.Index(doc.Path, doc)
 }
 
-// Search takes a search term and returns all doc paths that match.
-func (i Index) Search(ctx context.Context, query string) ([]string, error) {
+// Delete removes a docshelf Doc from the bleve index by its path. Deleting a path that was never indexed is
+// not an error.
+func (i Index) Delete(ctx context.Context, path string) error {
+	return i.idx.Delete(path)
+}
+
+// Search takes a search query and returns the docs that match, best matches first. Only the paths of the
+// returned docs are filled in. See queryParser for the supported syntax.
+func (i Index) Search(ctx context.Context, query string) ([]docshelf.SearchHit, error) {
 	// don't waste time searching for blanks.
-	if query == "" {
+	if strings.TrimSpace(query) == "" {
+		return nil, nil
+	}
+
+	q, err := parseQuery(ctx, query, i.idx.Mapping().AnalyzerNamed(en.AnalyzerName), i.authors)
+	if err != nil {
+		return nil, err
+	}
+
+	if q == nil {
 		return nil, nil
 	}
 
-	req := bleve.NewSearchRequest(bleve.NewFuzzyQuery(query))
+	// every match is returned, so stores can filter and order them however they're listed
+	count, err := i.idx.DocCount()
+	if err != nil {
+		return nil, err
+	}
+
+	req := bleve.NewSearchRequestOptions(q, int(count), 0, false)
+	req.IncludeLocations = true
+	req.Highlight = bleve.NewHighlightWithStyle(highlighterName)
+	for _, field := range highlightFields {
+		req.Highlight.AddField(field)
+	}
+
 	res, err := i.idx.Search(req)
 	if err != nil {
 		return nil, err
 	}
 
-	ids := make([]string, len(res.Hits))
-	for i, hit := range res.Hits {
-		ids[i] = hit.ID
+	hits := make([]docshelf.SearchHit, len(res.Hits))
+	for i, match := range res.Hits {
+		fields := make([]string, 0, len(match.Locations))
+		for field := range match.Locations {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		hits[i] = docshelf.SearchHit{
+			Doc:       docshelf.Doc{Path: match.ID},
+			Score:     match.Score,
+			Fields:    fields,
+			Fragments: match.Fragments,
+		}
 	}
 
-	return ids, nil
+	return hits, nil
 }
@@ -3,6 +3,9 @@ package bleve
 import (
 	"context"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/docshelf/docshelf"
 
@@ -61,11 +64,11 @@ func Test_IndexSearch(t *testing.T) {
 	}
 
 	// ASSERT
-	if len(unicornResults) == 0 || unicornResults[0] != doc1.Path {
+	if len(unicornResults) == 0 || unicornResults[0].Doc.Path != doc1.Path {
 		t.Fatal("search returned incorrent document")
 	}
 
-	if len(gopherResults) == 0 || gopherResults[0] != doc2.Path {
+	if len(gopherResults) == 0 || gopherResults[0].Doc.Path != doc2.Path {
 		t.Fatal("search returned incorrent document")
 	}
 
@@ -73,3 +76,288 @@ func Test_IndexSearch(t *testing.T) {
 		t.Fatal("search returned incorrect documents")
 	}
 }
+
+func Test_Delete(t *testing.T) {
+	defer os.RemoveAll(testBlevePath)
+	// SETUP
+	ctx := context.Background()
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := docshelf.Doc{
+		Path:    "testPath",
+		Content: "This is a test document about unicorns",
+	}
+
+	if err := idx.Index(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	if err := idx.Delete(ctx, doc.Path); err != nil {
+		t.Fatal(err)
+	}
+
+	missingErr := idx.Delete(ctx, "missingPath")
+
+	results, err := idx.Search(ctx, "unicorn")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if len(results) != 0 {
+		t.Fatal("deleted document should no longer be found")
+	}
+
+	if missingErr != nil {
+		t.Fatalf("deleting an unindexed path shouldn't fail, got: %v", missingErr)
+	}
+}
+
+func Test_QuerySyntax(t *testing.T) {
+	defer os.RemoveAll(testBlevePath)
+	// SETUP
+	ctx := context.Background()
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs := []docshelf.Doc{
+		{
+			Path:      "guides/deploy.md",
+			Title:     "Deploying the service",
+			Content:   "Run the release pipeline and watch the dashboards",
+			Tags:      []string{"ops", "how to"},
+			CreatedBy: "alice",
+			CreatedAt: time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			Path:      "guides/onboarding.md",
+			Title:     "Onboarding",
+			Content:   "Welcome aboard, start by reading the deploy guide",
+			Tags:      []string{"people"},
+			CreatedBy: "bob",
+			CreatedAt: time.Date(2019, 6, 15, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			Path:      "notes/pipeline.md",
+			Title:     "Release notes",
+			Content:   "The pipeline watches the release branch",
+			CreatedBy: "alice",
+			CreatedAt: time.Date(2020, 1, 10, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			Path:      "guides-old/legacy.md",
+			Title:     "Legacy",
+			Content:   "Archived material",
+			CreatedBy: "carol",
+			CreatedAt: time.Date(2018, 1, 10, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, doc := range docs {
+		if err := idx.Index(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		query string
+		paths []string
+	}{
+		{`release pipeline`, []string{"guides/deploy.md", "notes/pipeline.md"}},
+		{`"release pipeline"`, []string{"guides/deploy.md"}},
+		{`release AND welcome`, nil},
+		{`welcome OR dashboards`, []string{"guides/deploy.md", "guides/onboarding.md"}},
+		{`release NOT dashboards`, []string{"notes/pipeline.md"}},
+		{`release -dashboards`, []string{"notes/pipeline.md"}},
+		{`-release`, []string{"guides-old/legacy.md", "guides/onboarding.md"}},
+		{`(welcome OR dashboards) -title:onboarding`, []string{"guides/deploy.md"}},
+		{`title:deploy`, []string{"guides/deploy.md"}},
+		{`title:"release notes"`, []string{"notes/pipeline.md"}},
+		{`tag:ops`, []string{"guides/deploy.md"}},
+		{`tag:"how to"`, []string{"guides/deploy.md"}},
+		{`author:alice`, []string{"guides/deploy.md", "notes/pipeline.md"}},
+		{`path:guides/`, []string{"guides/deploy.md", "guides/onboarding.md"}},
+		{`path:guides`, []string{"guides/deploy.md", "guides/onboarding.md"}},
+		{`path:guides/deploy.md`, []string{"guides/deploy.md"}},
+		{`created:2019-06-15`, []string{"guides/onboarding.md"}},
+		{`created:>2019-03-01`, []string{"guides/onboarding.md", "notes/pipeline.md"}},
+		{`created:>=2019-03-01 created:<2020-01-01`, []string{"guides/deploy.md", "guides/onboarding.md"}},
+		{`author:alice created:<=2019-03-01`, []string{"guides/deploy.md"}},
+		{`the welcome`, []string{"guides/onboarding.md"}},
+		{`the`, nil},
+	}
+
+	for _, c := range cases {
+		// RUN
+		hits, err := idx.Search(ctx, c.query)
+		if err != nil {
+			t.Fatalf("%s: %v", c.query, err)
+		}
+
+		// ASSERT
+		var paths []string
+		for _, hit := range hits {
+			paths = append(paths, hit.Doc.Path)
+		}
+
+		sort.Strings(paths)
+		if strings.Join(paths, ",") != strings.Join(c.paths, ",") {
+			t.Fatalf("%s: expected %v, got %v", c.query, c.paths, paths)
+		}
+	}
+}
+
+// a UserStore that only knows how to list a fixed set of users
+type userList []docshelf.User
+
+func (u userList) GetUser(ctx context.Context, id string) (docshelf.User, error) {
+	return docshelf.User{}, docshelf.NewErrNotFound("user does not exist")
+}
+
+func (u userList) ListUsers(ctx context.Context) ([]docshelf.User, error) {
+	return u, nil
+}
+
+func (u userList) PutUser(ctx context.Context, user docshelf.User) (string, error) {
+	return "", nil
+}
+
+func (u userList) RemoveUser(ctx context.Context, id string) error {
+	return nil
+}
+
+func Test_AuthorSearch(t *testing.T) {
+	defer os.RemoveAll(testBlevePath)
+	// SETUP
+	ctx := context.Background()
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := docshelf.User{ID: "b1f5a3c0", Name: "Alice Liddell", Email: "alice@docshelf.io"}
+	bob := docshelf.User{ID: "c2e6b4d1", Name: "Bob", Email: "bob@docshelf.io"}
+	idx.UseUserStore(userList{alice, bob})
+
+	docs := []docshelf.Doc{
+		{Path: "guides/deploy.md", Title: "Deploying", Content: "deploy", CreatedBy: alice.ID, UpdatedBy: bob.ID},
+		{Path: "notes/todo.md", Title: "Todo", Content: "todo", CreatedBy: bob.ID, UpdatedBy: bob.ID},
+	}
+
+	for _, doc := range docs {
+		if err := idx.Index(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		query string
+		paths []string
+	}{
+		{`author:alice`, []string{"guides/deploy.md"}},
+		{`author:"alice liddell"`, []string{"guides/deploy.md"}},
+		{`author:bob@docshelf.io`, []string{"notes/todo.md"}},
+		{`author:` + alice.ID, []string{"guides/deploy.md"}},
+		{`editor:bob`, []string{"guides/deploy.md", "notes/todo.md"}},
+		{`editor:alice`, nil},
+		{`author:carol`, nil},
+	}
+
+	for _, c := range cases {
+		// RUN
+		hits, err := idx.Search(ctx, c.query)
+		if err != nil {
+			t.Fatalf("%s: %v", c.query, err)
+		}
+
+		// ASSERT
+		var paths []string
+		for _, hit := range hits {
+			paths = append(paths, hit.Doc.Path)
+		}
+
+		sort.Strings(paths)
+		if strings.Join(paths, ",") != strings.Join(c.paths, ",") {
+			t.Fatalf("%s: expected %v, got %v", c.query, c.paths, paths)
+		}
+	}
+}
+
+func Test_BadQuery(t *testing.T) {
+	defer os.RemoveAll(testBlevePath)
+	// SETUP
+	ctx := context.Background()
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := []string{
+		"created:yesterday",
+		"title:>gophers",
+		"tag:",
+		"(unicorns",
+		"unicorns)",
+		"unicorns NOT",
+	}
+
+	for _, query := range queries {
+		// RUN
+		_, err := idx.Search(ctx, query)
+
+		// ASSERT
+		if !docshelf.CheckBadQuery(err) {
+			t.Fatalf("%s: expected a bad query error, got: %v", query, err)
+		}
+	}
+}
+
+func Test_SearchHighlights(t *testing.T) {
+	defer os.RemoveAll(testBlevePath)
+	// SETUP
+	ctx := context.Background()
+	idx, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := docshelf.Doc{
+		Path:    "testPath",
+		Title:   "Unicorn care",
+		Content: "Keep <b>unicorns</b> away from gophers",
+	}
+
+	if err := idx.Index(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	hits, err := idx.Search(ctx, "unicorns")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if len(hits) != 1 || hits[0].Doc.Path != doc.Path || hits[0].Score <= 0 {
+		t.Fatalf("expected a single scored hit, got: %v", hits)
+	}
+
+	if strings.Join(hits[0].Fields, ",") != "content,title" {
+		t.Fatalf("expected title and content to match, got: %v", hits[0].Fields)
+	}
+
+	if title := hits[0].Fragments["title"]; len(title) != 1 || title[0] != "<mark>Unicorn</mark> care" {
+		t.Fatalf("unexpected title fragments: %v", title)
+	}
+
+	content := hits[0].Fragments["content"]
+	if len(content) != 1 || content[0] != "Keep &lt;b&gt;<mark>unicorns</mark>&lt;/b&gt; away from gophers" {
+		t.Fatalf("unexpected content fragments: %v", content)
+	}
+}
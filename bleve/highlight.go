@@ -0,0 +1,54 @@
+package bleve
+
+import (
+	"fmt"
+	"html"
+
+	"github.com/blevesearch/bleve/registry"
+	"github.com/blevesearch/bleve/search/highlight"
+	simpleFragmenter "github.com/blevesearch/bleve/search/highlight/fragmenter/simple"
+	simpleHighlighter "github.com/blevesearch/bleve/search/highlight/highlighter/simple"
+)
+
+// highlighterName is the bleve highlighter used for search snippets. It works like bleve's html highlighter
+// but escapes the text around matches, since docs can contain markup of their own.
+const highlighterName = "docshelf"
+
+// highlightFields are the fields snippets are taken from.
+var highlightFields = []string{"title", "content"}
+
+func init() {
+	registry.RegisterHighlighter(highlighterName, newHighlighter)
+}
+
+func newHighlighter(config map[string]interface{}, cache *registry.Cache) (highlight.Highlighter, error) {
+	fragmenter, err := cache.FragmenterNamed(simpleFragmenter.Name)
+	if err != nil {
+		return nil, fmt.Errorf("error building fragmenter: %v", err)
+	}
+
+	return simpleHighlighter.NewHighlighter(fragmenter, escapingFormatter{}, simpleHighlighter.DefaultSeparator), nil
+}
+
+// escapingFormatter wraps matched terms in <mark> tags and html escapes everything else.
+type escapingFormatter struct{}
+
+func (escapingFormatter) Format(f *highlight.Fragment, locations highlight.TermLocations) string {
+	formatted := ""
+	curr := f.Start
+	for _, loc := range locations {
+		if loc == nil || !loc.ArrayPositions.Equals(f.ArrayPositions) || loc.Start < curr {
+			continue
+		}
+
+		if loc.End > f.End {
+			break
+		}
+
+		formatted += html.EscapeString(string(f.Orig[curr:loc.Start]))
+		formatted += "<mark>" + html.EscapeString(string(f.Orig[loc.Start:loc.End])) + "</mark>"
+		curr = loc.End
+	}
+
+	return formatted + html.EscapeString(string(f.Orig[curr:f.End]))
+}
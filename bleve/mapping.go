@@ -0,0 +1,38 @@
+package bleve
+
+import (
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/analysis/lang/en"
+	"github.com/blevesearch/bleve/mapping"
+)
+
+// docMapping describes how docshelf Docs are indexed. Titles and content are analyzed as english prose so
+// different forms of a word match each other, while tags, paths and authors are kept as exact keywords.
+// Everything else about a Doc isn't searchable.
+func docMapping() mapping.IndexMapping {
+	prose := bleve.NewTextFieldMapping()
+	prose.Analyzer = en.AnalyzerName
+
+	exact := bleve.NewTextFieldMapping()
+	exact.Analyzer = keyword.Name
+	exact.IncludeInAll = false
+
+	date := bleve.NewDateTimeFieldMapping()
+	date.IncludeInAll = false
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt("title", prose)
+	doc.AddFieldMappingsAt("content", prose)
+	doc.AddFieldMappingsAt("tags", exact)
+	doc.AddFieldMappingsAt("path", exact)
+	doc.AddFieldMappingsAt("createdBy", exact)
+	doc.AddFieldMappingsAt("updatedBy", exact)
+	doc.AddFieldMappingsAt("createdAt", date)
+	doc.AddFieldMappingsAt("updatedAt", date)
+
+	idx := bleve.NewIndexMapping()
+	idx.DefaultMapping = doc
+	idx.DefaultAnalyzer = en.AnalyzerName
+	return idx
+}
@@ -0,0 +1,421 @@
+package bleve
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis"
+	"github.com/blevesearch/bleve/search/query"
+	"github.com/docshelf/docshelf"
+)
+
+// searchFields maps the field names users can scope terms to onto the indexed fields they search.
+var searchFields = map[string]string{
+	"title":   "title",
+	"content": "content",
+	"tag":     "tags",
+	"tags":    "tags",
+	"author":  "createdBy",
+	"editor":  "updatedBy",
+	"path":    "path",
+	"created": "createdAt",
+	"updated": "updatedAt",
+}
+
+// dateFields are the indexed fields that are searched by date range rather than by text.
+var dateFields = map[string]bool{
+	"createdAt": true,
+	"updatedAt": true,
+}
+
+type tokenKind int
+
+const (
+	tokTerm = tokenKind(iota)
+	tokAnd
+	tokOr
+	tokNot
+	tokOpen
+	tokClose
+)
+
+type token struct {
+	kind   tokenKind
+	field  string // the indexed field a term is scoped to, empty for any text field
+	op     string // one of >, >=, < or <= for date ranges
+	value  string
+	phrase bool
+}
+
+// A queryParser turns a search query into a bleve query. Terms next to each other all have to match, and
+// can be combined with OR, negated with NOT or a leading -, and grouped with parentheses. Quoted terms are
+// matched as phrases, and terms can be scoped to a single field like title:gophers or tag:"how to".
+// Dates can be filtered with created: and updated: followed by a date and an optional comparison, like
+// created:>=2019-01-01. Docs can be found by who created or last updated them with author: and editor:.
+type queryParser struct {
+	ctx      context.Context
+	tokens   []token
+	pos      int
+	analyzer *analysis.Analyzer
+	authors  *authors
+}
+
+// parseQuery parses a search query. Terms that would be dropped by the analyzer, like stop words, are left
+// out of the query so they don't prevent anything from matching. A nil query means nothing can match.
+func parseQuery(ctx context.Context, raw string, analyzer *analysis.Analyzer, authors *authors) (query.Query, error) {
+	tokens, err := lex(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := queryParser{ctx: ctx, tokens: tokens, analyzer: analyzer, authors: authors}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.tokens) {
+		return nil, docshelf.NewErrBadQuery("unexpected closing parenthesis in query")
+	}
+
+	return q, nil
+}
+
+func (p *queryParser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) parseOr() (query.Query, error) {
+	var disjuncts []query.Query
+	for {
+		q, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		if q != nil {
+			disjuncts = append(disjuncts, q)
+		}
+
+		if tok, ok := p.peek(); !ok || tok.kind != tokOr {
+			break
+		}
+
+		p.pos++
+	}
+
+	switch len(disjuncts) {
+	case 0:
+		return nil, nil
+	case 1:
+		return disjuncts[0], nil
+	default:
+		return bleve.NewDisjunctionQuery(disjuncts...), nil
+	}
+}
+
+func (p *queryParser) parseAnd() (query.Query, error) {
+	var must, mustNot []query.Query
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokOr || tok.kind == tokClose {
+			break
+		}
+
+		if tok.kind == tokAnd {
+			p.pos++
+			continue
+		}
+
+		q, negated, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case q == nil:
+		case negated:
+			mustNot = append(mustNot, q)
+		default:
+			must = append(must, q)
+		}
+	}
+
+	if len(mustNot) == 0 {
+		switch len(must) {
+		case 0:
+			return nil, nil
+		case 1:
+			return must[0], nil
+		default:
+			return bleve.NewConjunctionQuery(must...), nil
+		}
+	}
+
+	// excluding terms only makes sense when there's something to exclude them from
+	if len(must) == 0 {
+		must = append(must, bleve.NewMatchAllQuery())
+	}
+
+	q := bleve.NewBooleanQuery()
+	q.AddMust(must...)
+	q.AddMustNot(mustNot...)
+	return q, nil
+}
+
+func (p *queryParser) parseUnary() (query.Query, bool, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return nil, false, docshelf.NewErrBadQuery("query ends unexpectedly")
+	}
+
+	p.pos++
+	switch tok.kind {
+	case tokNot:
+		q, negated, err := p.parseUnary()
+		return q, !negated, err
+	case tokOpen:
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, false, err
+		}
+
+		if next, ok := p.peek(); !ok || next.kind != tokClose {
+			return nil, false, docshelf.NewErrBadQuery("missing closing parenthesis in query")
+		}
+
+		p.pos++
+		return q, false, nil
+	case tokTerm:
+		q, err := p.term(tok)
+		return q, false, err
+	default:
+		return nil, false, docshelf.NewErrBadQuery("unexpected operator in query")
+	}
+}
+
+// term builds the query for a single term. Terms that aren't scoped to a field search titles, content and
+// tags, with matching titles counting for more.
+func (p *queryParser) term(tok token) (query.Query, error) {
+	if dateFields[tok.field] {
+		return dateRange(tok)
+	}
+
+	if tok.op != "" {
+		return nil, docshelf.NewErrBadQuery(fmt.Sprintf("comparisons are only supported for dates, not %s", tok.value))
+	}
+
+	switch tok.field {
+	case "tags":
+		q := bleve.NewTermQuery(tok.value)
+		q.SetField(tok.field)
+		return q, nil
+	case "createdBy", "updatedBy":
+		return p.author(tok)
+	case "path":
+		// paths match exactly, or as a folder without also matching folders that just start the same way
+		exact := bleve.NewTermQuery(strings.Trim(tok.value, "/"))
+		exact.SetField(tok.field)
+		nested := bleve.NewPrefixQuery(docshelf.DirPrefix(tok.value))
+		nested.SetField(tok.field)
+		return bleve.NewDisjunctionQuery(exact, nested), nil
+	case "title", "content":
+		return p.text(tok, tok.field, 1), nil
+	}
+
+	title := p.text(tok, "title", 2)
+	if title == nil {
+		return nil, nil
+	}
+
+	tag := bleve.NewTermQuery(tok.value)
+	tag.SetField("tags")
+	return bleve.NewDisjunctionQuery(title, p.text(tok, "content", 1), tag), nil
+}
+
+// author builds the query for docs created or last updated by a user. Docs are indexed with user IDs, so
+// names and emails are resolved to the IDs of every user they could refer to.
+func (p *queryParser) author(tok token) (query.Query, error) {
+	ids, err := p.authors.resolve(p.ctx, tok.value)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]query.Query, 0, len(ids))
+	for _, id := range ids {
+		q := bleve.NewTermQuery(id)
+		q.SetField(tok.field)
+		matches = append(matches, q)
+	}
+
+	if len(matches) == 1 {
+		return matches[0], nil
+	}
+
+	return bleve.NewDisjunctionQuery(matches...), nil
+}
+
+// text builds a query for analyzed text, or nil if nothing would be left of the term after analysis.
+func (p *queryParser) text(tok token, field string, boost float64) query.Query {
+	if len(p.analyzer.Analyze([]byte(tok.value))) == 0 {
+		return nil
+	}
+
+	if tok.phrase {
+		q := bleve.NewMatchPhraseQuery(tok.value)
+		q.SetField(field)
+		q.SetBoost(boost)
+		return q
+	}
+
+	q := bleve.NewMatchQuery(tok.value)
+	q.SetField(field)
+	q.SetFuzziness(1)
+	q.SetBoost(boost)
+	return q
+}
+
+// dateRange builds a query for the dates matching a comparison. Plain dates stand for the whole day, so
+// created:2019-01-01 matches anything created that day and created:>2019-01-01 anything after it.
+func dateRange(tok token) (query.Query, error) {
+	date, span, err := parseDate(tok.value)
+	if err != nil {
+		return nil, err
+	}
+
+	var start, end time.Time
+	switch tok.op {
+	case "":
+		start, end = date, date.Add(span)
+	case ">":
+		start = date.Add(span)
+	case ">=":
+		start = date
+	case "<":
+		end = date
+	case "<=":
+		end = date.Add(span)
+	}
+
+	q := bleve.NewDateRangeQuery(start, end)
+	q.SetField(tok.field)
+	return q, nil
+}
+
+func parseDate(value string) (time.Time, time.Duration, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, 24 * time.Hour, nil
+	}
+
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, time.Nanosecond, nil
+	}
+
+	return time.Time{}, 0, docshelf.NewErrBadQuery(fmt.Sprintf("invalid date %q, expected YYYY-MM-DD or RFC3339", value))
+}
+
+// lex splits a search query into tokens.
+func lex(raw string) ([]token, error) {
+	var tokens []token
+	runes := []rune(raw)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokOpen})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokClose})
+			i++
+		case (r == '-' || r == '+') && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			// terms are required by default, so a leading + doesn't change anything
+			if r == '-' {
+				tokens = append(tokens, token{kind: tokNot})
+			}
+			i++
+		case r == '"':
+			var value string
+			value, i = readPhrase(runes, i)
+			tokens = append(tokens, token{kind: tokTerm, value: value, phrase: true})
+		default:
+			var word string
+			word, i = readWord(runes, i)
+			switch word {
+			case "AND":
+				tokens = append(tokens, token{kind: tokAnd})
+				continue
+			case "OR":
+				tokens = append(tokens, token{kind: tokOr})
+				continue
+			case "NOT":
+				tokens = append(tokens, token{kind: tokNot})
+				continue
+			}
+
+			tok := token{kind: tokTerm, value: word}
+			if idx := strings.Index(word, ":"); idx > 0 {
+				if field, ok := searchFields[strings.ToLower(word[:idx])]; ok {
+					tok.field = field
+					tok.value = word[idx+1:]
+					for _, op := range []string{">=", "<=", ">", "<"} {
+						if strings.HasPrefix(tok.value, op) {
+							tok.op = op
+							tok.value = strings.TrimPrefix(tok.value, op)
+							break
+						}
+					}
+
+					// scoped phrases like title:"some title" are split across the word and the phrase after it
+					if tok.value == "" && i < len(runes) && runes[i] == '"' {
+						tok.value, i = readPhrase(runes, i)
+						tok.phrase = true
+					}
+
+					if tok.value == "" {
+						return nil, docshelf.NewErrBadQuery(fmt.Sprintf("missing value for %s", word))
+					}
+				}
+			}
+
+			tokens = append(tokens, tok)
+		}
+	}
+
+	return tokens, nil
+}
+
+// readPhrase reads a quoted phrase starting at the opening quote. A missing closing quote ends the phrase at
+// the end of the query.
+func readPhrase(runes []rune, start int) (string, int) {
+	end := start + 1
+	for end < len(runes) && runes[end] != '"' {
+		end++
+	}
+
+	phrase := string(runes[start+1 : end])
+	if end < len(runes) {
+		end++ // skip the closing quote
+	}
+
+	return phrase, end
+}
+
+// readWord reads a word up to the next space, parenthesis or quote.
+func readWord(runes []rune, start int) (string, int) {
+	end := start
+	for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()"`, runes[end]) {
+		end++
+	}
+
+	return string(runes[start:end]), end
+}
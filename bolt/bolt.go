@@ -10,13 +10,25 @@ import (
 )
 
 var (
-	userBucket      = []byte("user")
-	userEmailBucket = []byte("userEmail")
-	groupBucket     = []byte("group")
-	docBucket       = []byte("doc")
-	docIDBucket     = []byte("docID")
-	policyBucket    = []byte("policy")
-	tagBucket       = []byte("tag")
+	userBucket        = []byte("user")
+	userEmailBucket   = []byte("userEmail")
+	groupBucket       = []byte("group")
+	docBucket         = []byte("doc")
+	docIDBucket       = []byte("docID")
+	docOrderBucket    = []byte("docOrder")
+	policyBucket      = []byte("policy")
+	tagBucket         = []byte("tag")
+	revisionBucket    = []byte("revision")
+	snapshotBucket    = []byte("snapshot")
+	snapshotDocBucket = []byte("snapshotDoc")
+	trashBucket       = []byte("trash")
+	lockBucket        = []byte("lock")
+	pathPolicyBucket  = []byte("pathPolicy")
+	sessionBucket     = []byte("session")
+	tokenBucket       = []byte("apiToken")
+	resetBucket       = []byte("passwordReset")
+	attemptBucket     = []byte("loginAttempt")
+	auditBucket       = []byte("audit")
 )
 
 // A Store implements several docshelf interfaces using boltdb as the backend.
@@ -122,6 +134,10 @@ func initBuckets(tx *bolt.Tx) error {
 		return err
 	}
 
+	if err := initDocOrder(tx); err != nil {
+		return err
+	}
+
 	if _, err := tx.CreateBucketIfNotExists(policyBucket); err != nil {
 		return err
 	}
@@ -130,6 +146,50 @@ func initBuckets(tx *bolt.Tx) error {
 		return err
 	}
 
+	if _, err := tx.CreateBucketIfNotExists(revisionBucket); err != nil {
+		return err
+	}
+
+	if _, err := tx.CreateBucketIfNotExists(snapshotBucket); err != nil {
+		return err
+	}
+
+	if _, err := tx.CreateBucketIfNotExists(snapshotDocBucket); err != nil {
+		return err
+	}
+
+	if _, err := tx.CreateBucketIfNotExists(trashBucket); err != nil {
+		return err
+	}
+
+	if _, err := tx.CreateBucketIfNotExists(lockBucket); err != nil {
+		return err
+	}
+
+	if _, err := tx.CreateBucketIfNotExists(pathPolicyBucket); err != nil {
+		return err
+	}
+
+	if _, err := tx.CreateBucketIfNotExists(sessionBucket); err != nil {
+		return err
+	}
+
+	if _, err := tx.CreateBucketIfNotExists(tokenBucket); err != nil {
+		return err
+	}
+
+	if _, err := tx.CreateBucketIfNotExists(resetBucket); err != nil {
+		return err
+	}
+
+	if _, err := tx.CreateBucketIfNotExists(attemptBucket); err != nil {
+		return err
+	}
+
+	if _, err := tx.CreateBucketIfNotExists(auditBucket); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -153,3 +213,39 @@ func contains(slice []string, el string) bool {
 
 	return false
 }
+
+func union(left, right []string) []string {
+	combined := make([]string, 0, len(left)+len(right))
+	for _, slice := range [][]string{left, right} {
+		for _, el := range slice {
+			if !contains(combined, el) {
+				combined = append(combined, el)
+			}
+		}
+	}
+
+	return combined
+}
+
+// without returns the elements of left that aren't in right.
+func without(left, right []string) []string {
+	remaining := make([]string, 0, len(left))
+	for _, el := range left {
+		if !contains(right, el) {
+			remaining = append(remaining, el)
+		}
+	}
+
+	return remaining
+}
+
+// hasSearchableTag reports whether any of the tags are regular tags rather than pins.
+func hasSearchableTag(tags []string) bool {
+	for _, tag := range tags {
+		if !docshelf.IsPin(tag) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,9 +2,14 @@ package bolt
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/docshelf/docshelf"
 	"github.com/docshelf/docshelf/mock"
 	"github.com/rs/xid"
@@ -148,9 +153,19 @@ func Test_PutGetRemoveGroup(t *testing.T) {
 	}
 	defer store.Close()
 
+	var userIDs []string
+	for _, email := range []string{"one@test.com", "two@test.com", "three@test.com"} {
+		userID, err := store.PutUser(ctx, docshelf.User{Email: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		userIDs = append(userIDs, userID)
+	}
+
 	group := docshelf.Group{
 		Name:  "test",
-		Users: []string{xid.New().String(), xid.New().String(), xid.New().String()},
+		Users: userIDs,
 	}
 
 	// RUN
@@ -168,9 +183,7 @@ func Test_PutGetRemoveGroup(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if _, err := store.GetGroup(ctx, id); err != nil {
-		t.Fatal(err)
-	}
+	_, removedErr := store.GetGroup(ctx, id)
 
 	// ASSERT
 	if getGroup.Name != group.Name {
@@ -188,6 +201,89 @@ func Test_PutGetRemoveGroup(t *testing.T) {
 	if getGroup.UpdatedAt.IsZero() {
 		t.Fatal("group UpdatedAt not set properly")
 	}
+
+	if !docshelf.CheckNotFound(removedErr) {
+		t.Fatalf("expected removed group to be not found, got: %v", removedErr)
+	}
+}
+
+func Test_GroupMembership(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := New(dbName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	alice, err := store.PutUser(ctx, docshelf.User{Email: "alice@test.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bob, err := store.PutUser(ctx, docshelf.User{Email: "bob@test.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	id, err := store.PutGroup(ctx, docshelf.Group{Name: "test", Users: []string{alice}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.AddGroupUsers(ctx, id, bob); err != nil {
+		t.Fatal(err)
+	}
+
+	// updating a user shouldn't drop their memberships
+	if _, err := store.PutUser(ctx, docshelf.User{ID: bob, Email: "bob@test.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	bobJoined, err := store.GetUser(ctx, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveGroupUsers(ctx, id, alice); err != nil {
+		t.Fatal(err)
+	}
+
+	aliceLeft, err := store.GetUser(ctx, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, unknownErr := store.PutGroup(ctx, docshelf.Group{Name: "unknown", Users: []string{xid.New().String()}})
+
+	if err := store.RemoveGroup(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	bobRemoved, err := store.GetUser(ctx, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if len(bobJoined.Groups) != 1 || bobJoined.Groups[0] != id {
+		t.Fatalf("expected bob to be in group %s, got: %v", id, bobJoined.Groups)
+	}
+
+	if len(aliceLeft.Groups) != 0 {
+		t.Fatalf("expected alice to have left the group, got: %v", aliceLeft.Groups)
+	}
+
+	if !docshelf.CheckNotFound(unknownErr) {
+		t.Fatalf("expected unknown user to be rejected, got: %v", unknownErr)
+	}
+
+	if len(bobRemoved.Groups) != 0 {
+		t.Fatalf("expected removed group to be dropped from bob, got: %v", bobRemoved.Groups)
+	}
 }
 
 func Test_PutGetRemovePolicy(t *testing.T) {
@@ -221,9 +317,7 @@ func Test_PutGetRemovePolicy(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if _, err := store.GetPolicy(ctx, id); err != nil {
-		t.Fatal(err)
-	}
+	_, removedErr := store.GetPolicy(ctx, id)
 
 	// ASSERT
 
@@ -242,6 +336,10 @@ func Test_PutGetRemovePolicy(t *testing.T) {
 	if getPolicy.UpdatedAt.IsZero() {
 		t.Fatal("policy UpdatedAt not set properly")
 	}
+
+	if !docshelf.CheckNotFound(removedErr) {
+		t.Fatalf("expected removed policy to be not found, got: %v", removedErr)
+	}
 }
 
 func Test_PutGetRemoveDoc(t *testing.T) {
@@ -273,7 +371,7 @@ func Test_PutGetRemoveDoc(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := store.RemoveDoc(ctx, doc.Path); err != nil {
+	if err := store.RemoveDoc(ctx, doc.Path, doc.UpdatedBy); err != nil {
 		t.Fatal(err)
 	}
 
@@ -324,17 +422,225 @@ func Test_ListDocs(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	list, err := store.ListDocs(ctx, "")
+	list, err := store.ListDocs(ctx, docshelf.ListOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// ASSERT
-	if len(list) != 2 {
+	if len(list.Docs) != 2 {
 		t.Fatal("listing didn't return enough results")
 	}
 }
 
+func Test_ListDocsPages(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	ti := mock.NewTextIndex(nil)
+	ti.SearchFn = func(ctx context.Context, term string) ([]docshelf.SearchHit, error) {
+		return []docshelf.SearchHit{
+			{Doc: docshelf.Doc{Path: "c.md"}},
+			{Doc: docshelf.Doc{Path: "a.md"}},
+			{Doc: docshelf.Doc{Path: "b.md"}},
+		}, nil
+	}
+
+	store, err := New(dbName, mock.NewFileStore(), ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	for _, doc := range []docshelf.Doc{
+		{Path: "b.md", Title: "Bravo", Content: "b"},
+		{Path: "c.md", Title: "Charlie", Content: "c"},
+		{Path: "a.md", Title: "Alpha", Content: "a"},
+	} {
+		if _, err := store.PutDoc(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.TagDoc(ctx, "a.md", "paged"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.TagDoc(ctx, "c.md", "paged"); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	first, err := store.ListDocs(ctx, docshelf.ListOptions{Sort: docshelf.SortTitle, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := store.ListDocs(ctx, docshelf.ListOptions{Sort: docshelf.SortTitle, Limit: 2, Cursor: first.Next})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firstPath, err := store.ListDocs(ctx, docshelf.ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondPath, err := store.ListDocs(ctx, docshelf.ListOptions{Limit: 2, Cursor: firstPath.Next})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firstTagged, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"paged"}, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secondTagged, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"paged"}, Limit: 1, Cursor: firstTagged.Next})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ranked, err := store.ListDocs(ctx, docshelf.ListOptions{Query: "doc", Tags: []string{"paged"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	missingTag, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"paged", "missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if len(first.Docs) != 2 || first.Docs[0].Path != "a.md" || first.Docs[1].Path != "b.md" || first.Next == "" {
+		t.Fatalf("unexpected first page: %v", first)
+	}
+
+	if len(second.Docs) != 1 || second.Docs[0].Path != "c.md" || second.Next != "" {
+		t.Fatalf("unexpected last page: %v", second)
+	}
+
+	if len(firstPath.Docs) != 2 || firstPath.Docs[0].Path != "a.md" || firstPath.Docs[1].Path != "b.md" || firstPath.Next == "" {
+		t.Fatalf("unexpected first page by path: %v", firstPath)
+	}
+
+	if len(secondPath.Docs) != 1 || secondPath.Docs[0].Path != "c.md" || secondPath.Next != "" {
+		t.Fatalf("unexpected last page by path: %v", secondPath)
+	}
+
+	if len(firstTagged.Docs) != 1 || firstTagged.Docs[0].Path != "a.md" || firstTagged.Next == "" {
+		t.Fatalf("unexpected first tagged page: %v", firstTagged)
+	}
+
+	if len(secondTagged.Docs) != 1 || secondTagged.Docs[0].Path != "c.md" || secondTagged.Next != "" {
+		t.Fatalf("unexpected last tagged page: %v", secondTagged)
+	}
+
+	if len(ranked.Docs) != 2 || ranked.Docs[0].Path != "c.md" || ranked.Docs[1].Path != "a.md" {
+		t.Fatalf("expected tagged search hits by relevance, got: %v", ranked.Docs)
+	}
+
+	if len(missingTag.Docs) != 0 {
+		t.Fatalf("docs missing one of the tags shouldn't be listed, got: %v", missingTag.Docs)
+	}
+}
+
+// listAll pages through a listing one doc at a time and returns the listed paths.
+func listAll(t *testing.T, store Store, sort docshelf.DocSort) string {
+	var paths []string
+	opts := docshelf.ListOptions{Sort: sort, Limit: 1}
+	for {
+		page, err := store.ListDocs(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, doc := range page.Docs {
+			paths = append(paths, doc.Path)
+		}
+
+		if page.Next == "" {
+			return strings.Join(paths, ",")
+		}
+
+		opts.Cursor = page.Next
+	}
+}
+
+func Test_ListDocsOrderIndex(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+
+	store, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dbName) // cleanup database after test
+
+	userID := xid.New().String()
+	for _, doc := range []docshelf.Doc{
+		{Path: "a.md", Title: "Charlie", Content: "a"},
+		{Path: "b.md", Title: "alpha", Content: "b"},
+		{Path: "c.md", Title: "Bravo", Content: "c"},
+		{Path: "d.md", Title: "Delta", Content: "d"},
+	} {
+		if _, err := store.PutDoc(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// RUN
+	updated := docshelf.Doc{Path: "b.md", Title: "Echo", Content: "b again"}
+	if _, err := store.PutDoc(ctx, updated); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.MoveDoc(ctx, "c.md", "e.md", userID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveDoc(ctx, "d.md", userID); err != nil {
+		t.Fatal(err)
+	}
+
+	byUpdated := listAll(t, store, docshelf.SortUpdated)
+	byCreated := listAll(t, store, docshelf.SortCreated)
+	byTitle := listAll(t, store, docshelf.SortTitle)
+
+	// stores opened before the order bucket existed have it built from their docs
+	if err := store.db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket(docOrderBucket)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	reopened, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+
+	rebuilt := listAll(t, reopened, docshelf.SortTitle)
+
+	// ASSERT
+	if byUpdated != "b.md,e.md,a.md" {
+		t.Fatalf("unexpected listing by updatedAt: %s", byUpdated)
+	}
+
+	if byCreated != "e.md,b.md,a.md" {
+		t.Fatalf("unexpected listing by createdAt: %s", byCreated)
+	}
+
+	if byTitle != "e.md,a.md,b.md" {
+		t.Fatalf("unexpected listing by title: %s", byTitle)
+	}
+
+	if rebuilt != byTitle {
+		t.Fatalf("rebuilt order doesn't match: %s", rebuilt)
+	}
+}
+
 func Test_TagLifecycle(t *testing.T) {
 	// SETUP
 	ctx := context.Background()
@@ -379,31 +685,1522 @@ func Test_TagLifecycle(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	testTag, err := store.ListDocs(ctx, "", "test")
+	testTag, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"test"}})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	oneTag, err := store.ListDocs(ctx, "", "one")
+	oneTag, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"one"}})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	twoTag, err := store.ListDocs(ctx, "", "two")
+	twoTag, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"two"}})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// ASSERT
-	if len(testTag) != 2 {
+	if len(testTag.Docs) != 2 {
 		t.Fatal("listing didn't return enough results")
 	}
 
-	if len(oneTag) != 1 && oneTag[0].Path == doc1.Path {
+	if len(oneTag.Docs) != 1 && oneTag.Docs[0].Path == doc1.Path {
 		t.Fatal("listing returned wrong results for tag 'one'")
 	}
 
-	if len(twoTag) != 1 && twoTag[0].Path == doc2.Path {
+	if len(twoTag.Docs) != 1 && twoTag.Docs[0].Path == doc2.Path {
 		t.Fatal("listing returned wrong results for tag 'two'")
 	}
 }
+
+func Test_Revisions(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+
+	store, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	doc := docshelf.Doc{
+		Path:      "test.md",
+		Title:     "Test Document",
+		Content:   "first draft",
+		Message:   "initial version",
+		CreatedBy: xid.New().String(),
+		UpdatedBy: xid.New().String(),
+	}
+
+	// RUN
+	id, err := store.PutDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc.Content = "second draft"
+	doc.Message = "fix typos"
+	doc.UpdatedBy = xid.New().String()
+	if _, err := store.PutDoc(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	revs, err := store.ListRevisions(ctx, doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idRevs, err := store.ListRevisions(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := store.GetRevision(ctx, doc.Path, revs[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, missingErr := store.GetRevision(ctx, doc.Path, xid.New().String())
+
+	// ASSERT
+	if len(revs) != 2 || len(idRevs) != 2 {
+		t.Fatal("listing didn't return every revision")
+	}
+
+	if revs[0].Content != "" {
+		t.Fatal("revision listing shouldn't include content")
+	}
+
+	if revs[1].Message != "fix typos" || revs[1].CreatedBy != doc.UpdatedBy {
+		t.Fatal("revisions are out of order or missing metadata")
+	}
+
+	if first.Content != "first draft" || first.Message != "initial version" {
+		t.Fatal("revision content doesn't match")
+	}
+
+	if first.DocID != id {
+		t.Fatal("revision isn't linked to the doc")
+	}
+
+	if !docshelf.CheckNotFound(missingErr) {
+		t.Fatal("expected not found error for missing revision")
+	}
+}
+
+func Test_SnapshotLifecycle(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+
+	store, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	userID := xid.New().String()
+	doc1 := docshelf.Doc{
+		Path:      "guides/test1.md",
+		Title:     "Test Document 1",
+		Content:   "original guide",
+		CreatedBy: userID,
+		UpdatedBy: userID,
+	}
+
+	doc2 := docshelf.Doc{
+		Path:      "notes/test2.md",
+		Title:     "Test Document 2",
+		Content:   "original note",
+		CreatedBy: userID,
+		UpdatedBy: userID,
+	}
+
+	if _, err := store.PutDoc(ctx, doc1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PutDoc(ctx, doc2); err != nil {
+		t.Fatal(err)
+	}
+
+	sibling := docshelf.Doc{Path: "guidesx/test3.md", Title: "Test Document 3", Content: "original sibling", CreatedBy: userID, UpdatedBy: userID}
+	if _, err := store.PutDoc(ctx, sibling); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.TagDoc(ctx, doc1.Path, "ops"); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	id, err := store.TakeSnapshot(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited := doc1
+	edited.Content = "broken guide"
+	if _, err := store.PutDoc(ctx, edited); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveDoc(ctx, doc2.Path, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	editedSibling := sibling
+	editedSibling.Content = "edited sibling"
+	if _, err := store.PutDoc(ctx, editedSibling); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RestoreSnapshot(ctx, id, "guides", userID); err != nil {
+		t.Fatal(err)
+	}
+
+	untouchedSibling, err := store.GetDoc(ctx, sibling.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restoredGuide, err := store.GetDoc(ctx, doc1.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, missingErr := store.GetDoc(ctx, doc2.Path)
+
+	if err := store.RestoreSnapshot(ctx, id, "", userID); err != nil {
+		t.Fatal(err)
+	}
+
+	restoredNote, err := store.GetDoc(ctx, doc2.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := store.GetSnapshot(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	snaps, err := store.ListSnapshots(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if restoredGuide.Content != doc1.Content {
+		t.Fatal("prefix restore didn't restore doc content")
+	}
+
+	if !docshelf.CheckNotFound(missingErr) {
+		t.Fatal("prefix restore shouldn't restore docs outside of the prefix")
+	}
+
+	if untouchedSibling.Content != editedSibling.Content {
+		t.Fatal("prefix restore shouldn't restore siblings that only share the prefix")
+	}
+
+	if restoredNote.Content != doc2.Content {
+		t.Fatal("full restore didn't restore removed doc")
+	}
+
+	if snap.DocCount != 3 || len(snap.Docs) != 3 {
+		t.Fatal("snapshot didn't capture every doc")
+	}
+
+	if !contains(snap.Docs[0].Tags, "ops") || snap.Docs[0].Content != "" {
+		t.Fatal("snapshot docs should carry tags without content")
+	}
+
+	if len(snaps) != 1 || snaps[0].ID != id {
+		t.Fatal("snapshot listing returned wrong results")
+	}
+}
+
+func Test_RestoreSnapshotLocked(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+
+	store, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	userID := xid.New().String()
+	other := xid.New().String()
+	docs := []docshelf.Doc{
+		{Path: "guides/a.md", Title: "A", Content: "original a", CreatedBy: userID, UpdatedBy: userID},
+		{Path: "guides/b.md", Title: "B", Content: "original b", CreatedBy: userID, UpdatedBy: userID},
+	}
+
+	for _, doc := range docs {
+		if _, err := store.PutDoc(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	id, err := store.TakeSnapshot(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, doc := range docs {
+		doc.Content = "edited"
+		if _, err := store.PutDoc(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := store.LockDoc(ctx, docs[1].Path, other, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	restoreErr := store.RestoreSnapshot(ctx, id, "guides", userID)
+
+	first, err := store.GetDoc(ctx, docs[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.UnlockDoc(ctx, docs[1].Path, other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RestoreSnapshot(ctx, id, "guides", userID); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := store.GetDoc(ctx, docs[1].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if !docshelf.CheckLocked(restoreErr) {
+		t.Fatalf("restoring over a doc locked by another user should fail, got: %v", restoreErr)
+	}
+
+	if first.Content != "edited" {
+		t.Fatal("nothing should be restored while a captured doc is locked")
+	}
+
+	if second.Content != docs[1].Content {
+		t.Fatal("restore should succeed once the lock is released")
+	}
+}
+
+func Test_MoveDoc(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	fs := mock.NewFileStore()
+	ti := mock.NewTextIndex(nil)
+
+	store, err := New(dbName, fs, ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	userID := xid.New().String()
+	doc := docshelf.Doc{
+		Path:      "old/test.md",
+		Title:     "Test Document",
+		Content:   "This is a test document, for testing purposes only",
+		CreatedBy: userID,
+		UpdatedBy: userID,
+	}
+
+	other := doc
+	other.Path = "other.md"
+
+	id, err := store.PutDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PutDoc(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.TagDoc(ctx, doc.Path, "test", "user/"+userID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.LockDoc(ctx, other.Path, "someone else", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	indexed := ti.IndexCalled
+
+	// RUN
+	if err := store.MoveDoc(ctx, id, "/new/test.md/", userID); err != nil {
+		t.Fatal(err)
+	}
+
+	conflictErr := store.MoveDoc(ctx, "new/test.md", other.Path, userID)
+	lockedErr := store.MoveDoc(ctx, other.Path, "elsewhere.md", userID)
+
+	moved, err := store.GetDoc(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, oldErr := store.GetDoc(ctx, doc.Path)
+
+	tagged, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinned, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"user/" + userID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revs, err := store.ListRevisions(ctx, moved.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldContent, err := fs.ReadFile(doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherContent, err := fs.ReadFile(other.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if moved.ID != id || moved.Path != "new/test.md" {
+		t.Fatal("moved doc should keep its ID under the new path")
+	}
+
+	if moved.Content != doc.Content {
+		t.Fatal("moved doc content doesn't match")
+	}
+
+	if !docshelf.CheckNotFound(oldErr) {
+		t.Fatal("doc should no longer exist at the old path")
+	}
+
+	if conflictErr == nil {
+		t.Fatal("moving onto an existing doc should fail")
+	}
+
+	if string(otherContent) != other.Content {
+		t.Fatal("failed move shouldn't touch the existing doc's content")
+	}
+
+	if !docshelf.CheckLocked(lockedErr) {
+		t.Fatalf("moving a doc locked by another user should fail, got: %v", lockedErr)
+	}
+
+	if len(tagged.Docs) != 1 || tagged.Docs[0].Path != moved.Path {
+		t.Fatal("tags didn't follow the moved doc")
+	}
+
+	if len(pinned.Docs) != 1 || pinned.Docs[0].Path != moved.Path {
+		t.Fatal("pins didn't follow the moved doc")
+	}
+
+	if ti.DeleteCalled != 1 {
+		t.Fatal("old path should be removed from the text index")
+	}
+
+	if len(revs) != 1 {
+		t.Fatal("revision history didn't follow the moved doc")
+	}
+
+	if oldContent != nil {
+		t.Fatal("content should be removed from the old path")
+	}
+
+	if ti.IndexCalled != indexed+1 {
+		t.Fatal("moved doc wasn't re-indexed")
+	}
+}
+
+func Test_TreeLifecycle(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+
+	store, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	userID := xid.New().String()
+	docs := []docshelf.Doc{
+		{Path: "root.md", Title: "Root", Content: "root"},
+		{Path: "guides/setup.md", Title: "Setup", Content: "setup"},
+		{Path: "guides/deep/nested.md", Title: "Nested", Content: "nested"},
+		{Path: "guides/empty", Title: "empty", IsDir: true},
+		{Path: "notes/todo.md", Title: "Todo", Content: "todo"},
+	}
+
+	for _, doc := range docs {
+		doc.CreatedBy = userID
+		doc.UpdatedBy = userID
+		if _, err := store.PutDoc(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// RUN
+	root, err := store.ListTree(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	guides, err := store.ListTree(ctx, "guides/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emptyDir, err := store.GetDoc(ctx, "guides/empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveDir(ctx, "guides", userID); err != nil {
+		t.Fatal(err)
+	}
+
+	afterRemove, err := store.ListTree(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, nestedErr := store.GetDoc(ctx, "guides/deep/nested.md")
+
+	// ASSERT
+	if len(root) != 3 || root[0].Path != "guides" || root[1].Path != "notes" || root[2].Path != "root.md" {
+		t.Fatalf("unexpected root listing: %+v", root)
+	}
+
+	if !root[0].IsDir || !root[1].IsDir || root[2].IsDir {
+		t.Fatal("folders weren't identified correctly")
+	}
+
+	if len(guides) != 3 || guides[0].Path != "guides/deep" || guides[1].Path != "guides/empty" || guides[2].Path != "guides/setup.md" {
+		t.Fatalf("unexpected guides listing: %+v", guides)
+	}
+
+	if guides[1].ID == "" || !emptyDir.IsDir {
+		t.Fatal("explicitly created folder wasn't returned")
+	}
+
+	if len(afterRemove) != 2 {
+		t.Fatalf("folder wasn't removed: %+v", afterRemove)
+	}
+
+	if !docshelf.CheckNotFound(nestedErr) {
+		t.Fatal("nested docs weren't removed with their folder")
+	}
+}
+
+func Test_RemoveDirRejectsDocs(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+
+	store, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	userID := xid.New().String()
+	doc := docshelf.Doc{Path: "guides/setup.md", Title: "Setup", Content: "setup", CreatedBy: userID, UpdatedBy: userID}
+	if _, err := store.PutDoc(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	removeErr := store.RemoveDir(ctx, doc.Path, userID)
+
+	getDoc, err := store.GetDoc(ctx, doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trash, err := store.ListTrash(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if !docshelf.CheckBadQuery(removeErr) {
+		t.Fatalf("expected a bad query error, got: %v", removeErr)
+	}
+
+	if getDoc.ID == "" {
+		t.Fatal("doc was removed as a folder")
+	}
+
+	if len(trash) != 0 {
+		t.Fatal("doc shouldn't have been moved to the trash")
+	}
+}
+
+func Test_TrashLifecycle(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	fs := mock.NewFileStore()
+	ti := mock.NewTextIndex(nil)
+
+	store, err := New(dbName, fs, ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	userID := xid.New().String()
+	doc := docshelf.Doc{
+		Path:      "test.md",
+		Title:     "Test Document",
+		Content:   "This is a test document, for testing purposes only",
+		CreatedBy: userID,
+		UpdatedBy: userID,
+	}
+
+	expired := doc
+	expired.Path = "expired.md"
+
+	id, err := store.PutDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiredID, err := store.PutDoc(ctx, expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.TagDoc(ctx, doc.Path, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	if err := store.RemoveDoc(ctx, doc.Path, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveDoc(ctx, expired.Path, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	_, removedErr := store.GetDoc(ctx, doc.Path)
+
+	trash, err := store.ListTrash(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RestoreDoc(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := store.GetDoc(ctx, doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagged, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keptCount, err := store.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	purgedCount, err := store.PurgeTrash(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := store.ListTrash(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	purgeErr := store.PurgeDoc(ctx, expiredID)
+
+	// ASSERT
+	if !docshelf.CheckNotFound(removedErr) {
+		t.Fatal("removed doc should not be found")
+	}
+
+	if len(trash) != 2 {
+		t.Fatalf("trash should contain 2 docs, found %d", len(trash))
+	}
+
+	for _, trashed := range trash {
+		if trashed.DeletedAt == nil {
+			t.Fatal("trashed doc should have a deletion time")
+		}
+
+		if trashed.Content != "" {
+			t.Fatal("trash listing should not include content")
+		}
+	}
+
+	if restored.ID != id || restored.Content != doc.Content || restored.DeletedAt != nil {
+		t.Fatal("restored doc should match the original")
+	}
+
+	if len(tagged.Docs) != 1 || tagged.Docs[0].ID != id {
+		t.Fatal("restored doc should keep its tags")
+	}
+
+	if keptCount != 0 {
+		t.Fatal("docs removed within the retention period should not be purged")
+	}
+
+	if purgedCount != 1 || len(remaining) != 0 {
+		t.Fatal("expired doc should be purged from the trash")
+	}
+
+	if !docshelf.CheckNotFound(purgeErr) {
+		t.Fatal("purging a doc that isn't in the trash should not be found")
+	}
+}
+
+func Test_PutDocConflict(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	store, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	doc := docshelf.Doc{
+		Path:    "test.md",
+		Title:   "Test Document",
+		Content: "This is a test document, for testing purposes only",
+	}
+
+	if _, err := store.PutDoc(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := store.GetDoc(ctx, doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := read
+	first.Content = "first edit"
+
+	second := read
+	second.Content = "second edit"
+
+	// RUN
+	if _, err := store.PutDoc(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+
+	_, staleErr := store.PutDoc(ctx, second)
+
+	second.Version = 0
+	if _, err := store.PutDoc(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+
+	fetched, err := store.GetDoc(ctx, doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	missing := doc
+	missing.Path = "missing.md"
+	missing.Version = 1
+	_, missingErr := store.PutDoc(ctx, missing)
+
+	// ASSERT
+	if read.Version != 1 {
+		t.Fatalf("new doc should start at version 1, found %d", read.Version)
+	}
+
+	if !docshelf.CheckConflict(staleErr) {
+		t.Fatal("stale write should conflict")
+	}
+
+	if fetched.Version != 3 || fetched.Content != second.Content {
+		t.Fatal("unversioned write should always succeed")
+	}
+
+	if !docshelf.CheckConflict(missingErr) {
+		t.Fatal("versioned write to a missing doc should conflict")
+	}
+}
+
+func Test_LockLifecycle(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	store, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	owner := xid.New().String()
+	other := xid.New().String()
+	doc := docshelf.Doc{
+		Path:      "test.md",
+		Title:     "Test Document",
+		Content:   "This is a test document, for testing purposes only",
+		CreatedBy: owner,
+		UpdatedBy: owner,
+	}
+
+	id, err := store.PutDoc(ctx, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	lock, err := store.LockDoc(ctx, id, owner, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, stolenErr := store.LockDoc(ctx, id, other, time.Hour)
+
+	locked, err := store.GetDoc(ctx, doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocked := doc
+	blocked.UpdatedBy = other
+	_, blockedErr := store.PutDoc(ctx, blocked)
+
+	if _, err := store.PutDoc(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	unlockErr := store.UnlockDoc(ctx, id, other)
+
+	if err := store.BreakLock(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PutDoc(ctx, blocked); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.LockDoc(ctx, id, other, -time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	expiredLock, err := store.LockDoc(ctx, id, owner, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.UnlockDoc(ctx, id, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	unlocked, err := store.GetDoc(ctx, doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, missingErr := store.LockDoc(ctx, xid.New().String(), owner, time.Hour)
+
+	// ASSERT
+	if lock.DocID != id || lock.UserID != owner {
+		t.Fatal("lock should belong to the owner")
+	}
+
+	if !docshelf.CheckLocked(stolenErr) {
+		t.Fatal("locking a doc locked by another user should fail")
+	}
+
+	if locked.Lock == nil || locked.Lock.UserID != owner {
+		t.Fatal("lock holder should be returned with the doc")
+	}
+
+	if !docshelf.CheckLocked(blockedErr) {
+		t.Fatal("writes from other users should be refused while the doc is locked")
+	}
+
+	if !docshelf.CheckLocked(unlockErr) {
+		t.Fatal("other users should not be able to unlock the doc")
+	}
+
+	if expiredLock.UserID != owner {
+		t.Fatal("expired locks should be replaceable")
+	}
+
+	if unlocked.Lock != nil {
+		t.Fatal("unlocked doc should not have a lock")
+	}
+
+	if !docshelf.CheckNotFound(missingErr) {
+		t.Fatal("locking a missing doc should not be found")
+	}
+}
+
+func Test_RemoveLockedDoc(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	store, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	owner := xid.New().String()
+	other := xid.New().String()
+	docs := []docshelf.Doc{
+		{Path: "guides/setup.md", Title: "Setup", Content: "setup", CreatedBy: owner, UpdatedBy: owner},
+		{Path: "guides/intro.md", Title: "Intro", Content: "intro", CreatedBy: owner, UpdatedBy: owner},
+	}
+
+	for _, doc := range docs {
+		if _, err := store.PutDoc(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := store.LockDoc(ctx, docs[0].Path, owner, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	docErr := store.RemoveDoc(ctx, docs[0].Path, other)
+	dirErr := store.RemoveDir(ctx, "guides", other)
+
+	remaining, err := store.ListTree(ctx, "guides")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveDir(ctx, "guides", owner); err != nil {
+		t.Fatal(err)
+	}
+
+	_, removedErr := store.GetDoc(ctx, docs[0].Path)
+
+	// ASSERT
+	if !docshelf.CheckLocked(docErr) {
+		t.Fatalf("removing a doc locked by another user should fail, got: %v", docErr)
+	}
+
+	if !docshelf.CheckLocked(dirErr) {
+		t.Fatalf("removing a folder with a doc locked by another user should fail, got: %v", dirErr)
+	}
+
+	if len(remaining) != 2 {
+		t.Fatal("nothing should be removed while a nested doc is locked")
+	}
+
+	if !docshelf.CheckNotFound(removedErr) {
+		t.Fatal("the lock holder should be able to remove the folder")
+	}
+}
+
+func Test_PathPolicies(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	store, err := New(dbName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	policyID, err := store.PutPolicy(ctx, docshelf.Policy{Users: []string{xid.New().String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	if err := store.PutPathPolicy(ctx, "/security/", policyID); err != nil {
+		t.Fatal(err)
+	}
+
+	missingErr := store.PutPathPolicy(ctx, "hr", xid.New().String())
+	rootErr := store.PutPathPolicy(ctx, "/", policyID)
+
+	attached, err := store.ListPathPolicies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemovePathPolicy(ctx, "security"); err != nil {
+		t.Fatal(err)
+	}
+
+	detached, err := store.ListPathPolicies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if len(attached) != 1 || attached["security"].ID != policyID {
+		t.Fatal("policy should be attached to the trimmed prefix")
+	}
+
+	if !docshelf.CheckNotFound(missingErr) {
+		t.Fatal("attaching a missing policy should not be found")
+	}
+
+	if rootErr == nil {
+		t.Fatal("attaching a policy to the root should fail")
+	}
+
+	if len(detached) != 0 {
+		t.Fatal("policy should be detached")
+	}
+}
+
+func Test_SessionLifecycle(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := New(dbName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// RUN
+	activeID, err := store.PutSession(ctx, docshelf.Session{UserID: "user", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expiredID, err := store.PutSession(ctx, docshelf.Session{UserID: "user", ExpiresAt: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active, err := store.GetSession(ctx, activeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RevokeSession(ctx, activeID); err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, err := store.GetSession(ctx, activeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := store.PurgeSessions(ctx, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, expiredErr := store.GetSession(ctx, expiredID)
+
+	// ASSERT
+	if !active.Active() || active.CreatedAt.IsZero() {
+		t.Fatal("expected new session to be active")
+	}
+
+	if revoked.Active() || revoked.RevokedAt == nil {
+		t.Fatal("expected revoked session to be inactive")
+	}
+
+	if purged != 1 {
+		t.Fatalf("expected 1 purged session, got %d", purged)
+	}
+
+	if !docshelf.CheckNotFound(expiredErr) {
+		t.Fatalf("expected expired session to be purged, got: %v", expiredErr)
+	}
+}
+
+func Test_TokenLifecycle(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := New(dbName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	token, _, err := docshelf.NewAPIToken("user", "ci", []docshelf.Scope{docshelf.ScopeWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, _, err := docshelf.NewAPIToken("other", "ci", []docshelf.Scope{docshelf.ScopeRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	id, err := store.PutToken(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PutToken(ctx, other); err != nil {
+		t.Fatal(err)
+	}
+
+	getToken, err := store.GetToken(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := store.ListTokens(ctx, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveToken(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+
+	_, removedErr := store.GetToken(ctx, id)
+
+	// ASSERT
+	if getToken.Hash != token.Hash || getToken.Name != token.Name {
+		t.Fatal("stored token doesn't match")
+	}
+
+	if len(tokens) != 1 || tokens[0].ID != id {
+		t.Fatalf("expected only the user's token to be listed, got: %v", tokens)
+	}
+
+	if !docshelf.CheckNotFound(removedErr) {
+		t.Fatalf("expected removed token to be not found, got: %v", removedErr)
+	}
+}
+
+func Test_PasswordResetLifecycle(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := New(dbName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	reset, _, err := docshelf.NewPasswordReset("user", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	if err := store.PutPasswordReset(ctx, reset); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.UsePasswordReset(ctx, reset.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	usedReset, err := store.GetPasswordReset(ctx, reset.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reuseErr := store.UsePasswordReset(ctx, reset.ID)
+	missingErr := store.UsePasswordReset(ctx, "missing")
+
+	// ASSERT
+	if usedReset.UsedAt == nil || usedReset.Active() {
+		t.Fatal("expected password reset to be used")
+	}
+
+	if !docshelf.CheckConflict(reuseErr) {
+		t.Fatalf("expected reusing a password reset to conflict, got: %v", reuseErr)
+	}
+
+	if !docshelf.CheckNotFound(missingErr) {
+		t.Fatalf("expected missing password reset to be not found, got: %v", missingErr)
+	}
+}
+
+func Test_LoginAttemptsAndAudit(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := New(dbName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	fail := func(attempts docshelf.LoginAttempts) docshelf.LoginAttempts {
+		attempts.Failures++
+		return attempts
+	}
+
+	now := time.Now()
+	old := docshelf.AuditEvent{Action: docshelf.AuditLoginFailed, Email: "old@docshelf.io", CreatedAt: now.Add(-time.Hour)}
+	recent := docshelf.AuditEvent{Action: docshelf.AuditLoginFailed, Email: "recent@docshelf.io", CreatedAt: now}
+
+	// RUN
+	if _, err := store.UpdateLoginAttempts(ctx, "account:user", fail); err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := store.UpdateLoginAttempts(ctx, "account:user", fail)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveLoginAttempts(ctx, "account:user"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, removedErr := store.GetLoginAttempts(ctx, "account:user")
+
+	for _, event := range []docshelf.AuditEvent{old, recent} {
+		if err := store.PutAuditEvent(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := store.ListAuditEvents(ctx, now.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if updated.Failures != 2 || updated.Key != "account:user" {
+		t.Fatalf("expected updates to build on each other, got: %+v", updated)
+	}
+
+	if !docshelf.CheckNotFound(removedErr) {
+		t.Fatalf("expected removed attempts to be not found, got: %v", removedErr)
+	}
+
+	if len(events) != 1 || events[0].Email != recent.Email || events[0].ID == "" {
+		t.Fatalf("expected only the recent event, got: %v", events)
+	}
+}
+
+func Test_SearchRemovedDoc(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	indexed := make(map[string]bool)
+	ti := mock.NewTextIndex(nil)
+	ti.IndexFn = func(ctx context.Context, doc docshelf.Doc) error {
+		indexed[doc.Path] = true
+		return nil
+	}
+
+	ti.DeleteFn = func(ctx context.Context, path string) error {
+		delete(indexed, path)
+		return nil
+	}
+
+	// a path bolt has never heard of stands in for a hit the index hasn't caught up on yet
+	ti.SearchFn = func(ctx context.Context, term string) ([]docshelf.SearchHit, error) {
+		hits := []docshelf.SearchHit{{Doc: docshelf.Doc{Path: "stale.md"}}}
+		for path := range indexed {
+			hits = append(hits, docshelf.SearchHit{Doc: docshelf.Doc{Path: path}})
+		}
+
+		return hits, nil
+	}
+
+	store, err := New(dbName, mock.NewFileStore(), ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	kept := docshelf.Doc{Path: "kept.md", Title: "Kept", Content: "still around"}
+	removed := docshelf.Doc{Path: "removed.md", Title: "Removed", Content: "going away"}
+	for _, doc := range []docshelf.Doc{kept, removed} {
+		if _, err := store.PutDoc(ctx, doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// RUN
+	if err := store.RemoveDoc(ctx, removed.Path, removed.UpdatedBy); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := store.ListDocs(ctx, docshelf.ListOptions{Query: "around"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := store.SearchDocs(ctx, "around")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if indexed[removed.Path] {
+		t.Fatal("removed doc should be deleted from the text index")
+	}
+
+	if len(docs.Docs) != 1 || docs.Docs[0].Path != kept.Path {
+		t.Fatalf("expected stale hits to be skipped, got: %v", docs.Docs)
+	}
+
+	if len(hits) != 1 || hits[0].Doc.Path != kept.Path || hits[0].Doc.Title != kept.Title {
+		t.Fatalf("expected stale search hits to be skipped and the rest filled in, got: %v", hits)
+	}
+}
+
+func Test_IndexTags(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	indexed := make(map[string][]string)
+	ti := mock.NewTextIndex(nil)
+	ti.IndexFn = func(ctx context.Context, doc docshelf.Doc) error {
+		indexed[doc.Path] = doc.Tags
+		return nil
+	}
+
+	store, err := New(dbName, mock.NewFileStore(), ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	doc := docshelf.Doc{Path: "tagged.md", Title: "Tagged", Content: "tag me", Tags: []string{"ignored"}}
+	if _, err := store.PutDoc(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	untagged := indexed[doc.Path]
+
+	// RUN
+	if err := store.TagDoc(ctx, doc.Path, "ops", "user/"+xid.New().String()); err != nil {
+		t.Fatal(err)
+	}
+
+	tagged := indexed[doc.Path]
+
+	doc.Content = "tag me again"
+	if _, err := store.PutDoc(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := indexed[doc.Path]
+
+	missingErr := store.TagDoc(ctx, "missing.md", "ops")
+
+	// ASSERT
+	if len(untagged) != 0 {
+		t.Fatalf("only stored tags should be indexed, got: %v", untagged)
+	}
+
+	if len(tagged) != 1 || tagged[0] != "ops" {
+		t.Fatalf("tagged doc should be indexed with its tags but not pins, got: %v", tagged)
+	}
+
+	if len(updated) != 1 || updated[0] != "ops" {
+		t.Fatalf("updated doc should keep its indexed tags, got: %v", updated)
+	}
+
+	if !docshelf.CheckNotFound(missingErr) {
+		t.Fatalf("tagging a missing doc should fail as not found, got: %v", missingErr)
+	}
+}
+
+func Test_TagManagement(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	indexed := make(map[string][]string)
+	ti := mock.NewTextIndex(nil)
+	ti.IndexFn = func(ctx context.Context, doc docshelf.Doc) error {
+		indexed[doc.Path] = doc.Tags
+		return nil
+	}
+
+	store, err := New(dbName, mock.NewFileStore(), ti)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	pin := "user/" + xid.New().String()
+	tags := map[string][]string{
+		"a.md": {"ops", "howto", pin},
+		"b.md": {"ops", "how-to"},
+		"c.md": {"people"},
+	}
+
+	for path, docTags := range tags {
+		if _, err := store.PutDoc(ctx, docshelf.Doc{Path: path, Title: path, Content: path}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.TagDoc(ctx, path, docTags...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	readAll := func(docshelf.Doc) bool { return true }
+
+	// RUN
+	listed, err := store.ListTags(ctx, readAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readable, err := store.ListTags(ctx, func(doc docshelf.Doc) bool { return doc.Path != "b.md" })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.UntagDoc(ctx, "c.md", "people", "missing"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RenameTag(ctx, "ops", "operations"); err != nil {
+		t.Fatal(err)
+	}
+
+	conflictErr := store.RenameTag(ctx, "howto", "how-to")
+	missingErr := store.RenameTag(ctx, "ops", "ops2")
+	pinErr := store.RenameTag(ctx, pin, "pinned")
+
+	if err := store.MergeTags(ctx, "how to", "howto", "how-to"); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := store.ListTags(ctx, readAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pinned, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{pin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if fmt.Sprint(listed) != "[{ops 2} {how-to 1} {howto 1} {people 1}]" {
+		t.Fatalf("unexpected tag counts: %v", listed)
+	}
+
+	if fmt.Sprint(readable) != "[{howto 1} {ops 1} {people 1}]" {
+		t.Fatalf("tag counts should only include readable docs, got: %v", readable)
+	}
+
+	if fmt.Sprint(merged) != "[{how to 2} {operations 2}]" {
+		t.Fatalf("unexpected tags after renaming and merging: %v", merged)
+	}
+
+	if !docshelf.CheckConflict(conflictErr) {
+		t.Fatalf("renaming onto an existing tag should conflict, got: %v", conflictErr)
+	}
+
+	if !docshelf.CheckNotFound(missingErr) {
+		t.Fatalf("renaming a missing tag should fail as not found, got: %v", missingErr)
+	}
+
+	if pinErr == nil {
+		t.Fatal("pins shouldn't be renamed")
+	}
+
+	if len(pinned.Docs) != 1 || pinned.Docs[0].Path != "a.md" {
+		t.Fatalf("pins should be left alone, got: %v", pinned.Docs)
+	}
+
+	sort.Strings(indexed["a.md"])
+	if fmt.Sprint(indexed["a.md"]) != "[how to operations]" || len(indexed["c.md"]) != 0 {
+		t.Fatalf("retagged docs should be re-indexed with their new tags, got: %v", indexed)
+	}
+}
+
+func Test_RetagTrash(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	store, err := New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	defer os.Remove(dbName) // cleanup database after test
+
+	for _, path := range []string{"a.md", "b.md"} {
+		if _, err := store.PutDoc(ctx, docshelf.Doc{Path: path, Title: path, Content: path}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.TagDoc(ctx, path, "ops", "howto", "people"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	trashed, err := store.GetDoc(ctx, "b.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveDoc(ctx, trashed.Path, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	if err := store.RenameTag(ctx, "ops", "operations"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.MergeTags(ctx, "how to", "howto"); err != nil {
+		t.Fatal(err)
+	}
+
+	trash, err := store.ListTrash(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RestoreDoc(ctx, trashed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := store.ListTags(ctx, func(docshelf.Doc) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if len(trash) != 1 || fmt.Sprint(trash[0].Tags) != "[people operations how to]" {
+		t.Fatalf("trashed docs should be retagged, got: %v", trash)
+	}
+
+	if fmt.Sprint(tags) != "[{how to 2} {operations 2} {people 2}]" {
+		t.Fatalf("restored docs shouldn't bring back renamed or merged tags, got: %v", tags)
+	}
+}
@@ -1,9 +1,11 @@
 package bolt
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/boltdb/bolt"
@@ -30,6 +32,19 @@ func (s Store) GetDoc(ctx context.Context, path string) (docshelf.Doc, error) {
 		return doc, errors.Wrap(err, "failed to fetch doc from bolt")
 	}
 
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		lock, err := getLock(tx, doc.ID)
+		doc.Lock = lock
+		return err
+	}); err != nil {
+		return doc, errors.Wrap(err, "failed to fetch doc lock from bolt")
+	}
+
+	// folders don't have any content
+	if doc.IsDir {
+		return doc, nil
+	}
+
 	content, err := s.fs.ReadFile(path)
 	if err != nil {
 		return doc, errors.Wrap(err, "failed to read file from file store")
@@ -39,150 +54,234 @@ func (s Store) GetDoc(ctx context.Context, path string) (docshelf.Doc, error) {
 	return doc, nil
 }
 
-// ListDocs fetches a slice of docshelf Document metadata from bolt. If a query is provided, then the configured
+// ListDocs fetches a page of docshelf Doc metadata from bolt. If a query is provided, then the configured
 // docshelf.TextIndex will be used to get a set of document paths. If tags are also provided, then they will be used
 // to further filter down the results. If no query is provided, but tags are, then the tags will filter down the entire
-// set of documents stored.
-func (s Store) ListDocs(ctx context.Context, query string, tags ...string) ([]docshelf.Doc, error) {
-	var docs []docshelf.Doc
-	var foundPaths []string
-
-	// do a full listing if no filters are given
-	if query == "" && len(tags) == 0 {
-		if err := s.db.View(func(tx *bolt.Tx) error {
-			b := tx.Bucket(docBucket)
-			if err := b.ForEach(func(k, v []byte) error {
+// set of documents stored. Docs are streamed through a docshelf.DocPager, so only a single page is held in memory.
+// Listings without a query or tags are read in order, from the doc bucket or the order bucket, seeking straight
+// to the cursor and stopping once the page is full. Filtered listings sorted by anything but path have to read
+// every matching doc for every page.
+func (s Store) ListDocs(ctx context.Context, opts docshelf.ListOptions) (docshelf.DocPage, error) {
+	var ranked []string
+	if opts.Query != "" {
+		hits, err := s.ti.Search(ctx, opts.Query)
+		if err != nil {
+			return docshelf.DocPage{}, err
+		}
+
+		for _, hit := range hits {
+			ranked = append(ranked, hit.Doc.Path)
+		}
+	}
+
+	pager, err := docshelf.NewDocPager(opts, ranked)
+	if err != nil {
+		return docshelf.DocPage{}, err
+	}
+
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		// do a full listing if no filters are given
+		if opts.Query == "" && len(opts.Tags) == 0 {
+			if pager.Sort() != docshelf.SortPath {
+				return s.listOrdered(ctx, tx, pager)
+			}
+
+			// bolt keeps docs sorted by path, so path listings can start right after the cursor
+			c := tx.Bucket(docBucket).Cursor()
+			k, v := c.First()
+			if after := pager.After(); after != "" && pager.Sort() == docshelf.SortPath {
+				k, v = c.Seek([]byte(after))
+				if string(k) == after {
+					k, v = c.Next()
+				}
+			}
+
+			for ; k != nil && !pager.Full(); k, v = c.Next() {
 				var doc docshelf.Doc
 				if err := json.Unmarshal(v, &doc); err != nil {
 					return err
 				}
 
-				docs = append(docs, doc)
-				return nil
-			}); err != nil {
-				return err
+				pager.Add(doc)
 			}
+
 			return nil
-		}); err != nil {
-			return nil, err
 		}
 
-		return docs, nil
-	}
+		paths := ranked
+		if len(opts.Tags) > 0 {
+			tagged, err := s.taggedPaths(ctx, tx, opts.Tags)
+			if err != nil {
+				return err
+			}
 
-	if query != "" {
-		var err error
-		foundPaths, err = s.ti.Search(ctx, query)
-		if err != nil {
-			return nil, err
+			if opts.Query == "" {
+				paths = tagged
+			} else {
+				paths = intersect(ranked, tagged)
+			}
 		}
-	}
 
-	// short circuit if no tags supplied
-	if len(tags) == 0 {
-		return s.listDocsTx(ctx, foundPaths)
-	}
+		// the text index can briefly lag behind bolt, so paths that no longer exist are skipped
+		for _, p := range pager.Order(paths) {
+			if pager.Full() {
+				break
+			}
 
-	var tagged []docshelf.Doc
-	if err := s.db.View(func(tx *bolt.Tx) error {
-		var err error
-		tagged, err = s.listTaggedDocs(ctx, tx, tags)
-		if err != nil {
-			return err
+			var doc docshelf.Doc
+			if err := s.getItem(ctx, tx, docBucket, p, &doc); err != nil {
+				if docshelf.CheckNotFound(err) {
+					continue
+				}
+
+				return err
+			}
+
+			pager.Add(doc)
 		}
 
 		return nil
 	}); err != nil {
-		return nil, errors.Wrap(err, "failed to list prefix from bolt")
+		return docshelf.DocPage{}, errors.Wrap(err, "failed to list docs from bolt")
 	}
 
-	// short circuit if no query was supplied
-	if query == "" {
-		return tagged, nil
-	}
+	return pager.Page(), nil
+}
 
-	if len(foundPaths) > 0 {
-		for _, doc := range tagged {
-			if contains(foundPaths, doc.Path) {
-				docs = append(docs, doc)
-			}
+// listOrdered adds docs to a pager in the order kept by the order bucket, starting right after the cursor.
+func (s Store) listOrdered(ctx context.Context, tx *bolt.Tx, pager *docshelf.DocPager) error {
+	pager.InOrder()
+	prefix := []byte(docOrderPrefix(pager.Sort()))
+	c := tx.Bucket(docOrderBucket).Cursor()
+	k, v := c.Seek(prefix)
+	if after := pager.AfterKey(); after != "" {
+		key := []byte(docOrderPrefix(pager.Sort()) + after)
+		k, v = c.Seek(key)
+		if bytes.Equal(k, key) {
+			k, v = c.Next()
 		}
 	}
 
-	return docs, nil
-}
-
-func (s Store) listDocsTx(ctx context.Context, paths []string) ([]docshelf.Doc, error) {
-	var docs []docshelf.Doc
-	if err := s.db.View(func(tx *bolt.Tx) error {
-		for _, p := range paths {
-			var doc docshelf.Doc
-			if err := s.getItem(ctx, tx, docBucket, p, &doc); err != nil {
-				return err
-			}
-
-			docs = append(docs, doc)
+	for ; k != nil && bytes.HasPrefix(k, prefix) && !pager.Full(); k, v = c.Next() {
+		var doc docshelf.Doc
+		if err := s.getItem(ctx, tx, docBucket, string(v), &doc); err != nil {
+			return err
 		}
 
-		return nil
-	}); err != nil {
-		return nil, err
+		pager.Add(doc)
 	}
 
-	return docs, nil
+	return nil
 }
 
-func (s Store) listTaggedDocs(ctx context.Context, tx *bolt.Tx, tags []string) ([]docshelf.Doc, error) {
-	var docIDs []string
-	for _, t := range tags {
-		var ids []string
-		if err := s.getItem(ctx, tx, tagBucket, t, &ids); err != nil {
-			continue
+// taggedPaths returns the paths of the docs that have every one of the given tags.
+func (s Store) taggedPaths(ctx context.Context, tx *bolt.Tx, tags []string) ([]string, error) {
+	var paths []string
+	for i, t := range tags {
+		var tagged []string
+		if err := s.getItem(ctx, tx, tagBucket, t, &tagged); err != nil && !docshelf.CheckNotFound(err) {
+			return nil, err
 		}
 
-		if docIDs == nil {
-			docIDs = ids
+		if i == 0 {
+			paths = tagged
 		} else {
-			docIDs = intersect(docIDs, ids)
+			paths = intersect(paths, tagged)
 		}
 	}
 
-	var docs []docshelf.Doc
-	for _, id := range docIDs {
-		var doc docshelf.Doc
-		if err := s.getItem(ctx, tx, docBucket, id, &doc); err != nil {
-			continue
+	return paths, nil
+}
+
+// SearchDocs finds the docs matching a search query, best matches first. Each doc comes with its tags, but
+// not pins. The text index can briefly lag behind bolt, so hits that no longer exist are skipped.
+func (s Store) SearchDocs(ctx context.Context, query string) ([]docshelf.SearchHit, error) {
+	hits, err := s.ti.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]docshelf.SearchHit, 0, len(hits))
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		tagged, err := s.tagsByPath(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, hit := range hits {
+			if err := s.getItem(ctx, tx, docBucket, hit.Doc.Path, &hit.Doc); err != nil {
+				if docshelf.CheckNotFound(err) {
+					continue
+				}
+
+				return err
+			}
+
+			hit.Doc.Tags = docshelf.WithoutPins(tagged[hit.Doc.Path])
+			found = append(found, hit)
 		}
 
-		docs = append(docs, doc)
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to fetch search hits from bolt")
 	}
 
-	return docs, nil
+	return found, nil
 }
 
 // PutDoc creates or updates an existing docshelf Doc in bolt. It will also store the Content in an underlying FileStore.
 func (s Store) PutDoc(ctx context.Context, doc docshelf.Doc) (string, error) {
 	// having no path is an invalid state
+	doc.Path = strings.Trim(doc.Path, "/")
 	if doc.Path == "" {
 		return "", errors.New("can not create a new doc without a path")
 	}
 
 	// TODO (erik): Should this be fetching by ID? Seems like some weird stuff could happen just pulling by path.
-	if existing, err := s.GetDoc(ctx, doc.Path); err != nil {
+	existing, err := s.GetDoc(ctx, doc.Path)
+	if err != nil {
 		if !docshelf.CheckNotFound(err) {
 			return "", errors.Wrap(err, "could not verify existing file")
 		}
 
+		if doc.Version != 0 {
+			return "", docshelf.NewErrConflict("doc has been removed since it was read")
+		}
+
 		// set one-time fields for new document
 		doc.ID = xid.New().String()
 		doc.CreatedAt = time.Now()
 	} else {
-		// need to enforce integrity of created* fields if the doc exists.
+		if existing.IsDir != doc.IsDir {
+			return "", errors.Errorf("can not replace a folder with a doc or vice versa: %s", doc.Path)
+		}
+
+		if existing.Lock.Blocks(doc.UpdatedBy) {
+			return "", docshelf.NewErrLocked("doc is locked by another user")
+		}
+
+		// a version of 0 means the write doesn't care what it's replacing
+		if doc.Version != 0 && doc.Version != existing.Version {
+			return "", docshelf.NewErrConflict("doc has been modified since it was read")
+		}
+
+		// need to enforce integrity of the ID and created* fields if the doc exists.
+		doc.ID = existing.ID
 		doc.CreatedBy = existing.CreatedBy
 		doc.CreatedAt = existing.CreatedAt
 	}
 
+	expected := existing.Version
+	doc.Version = expected + 1
 	doc.UpdatedAt = time.Now()
+	doc.Lock = nil // locks are stored separately
+
+	if doc.IsDir {
+		return s.putDir(ctx, doc, expected)
+	}
+
+	rev := docshelf.NewRevision(doc)
+	doc.Message = ""
 
 	// save content
 	if err := s.fs.WriteFile(doc.Path, []byte(doc.Content)); err != nil {
@@ -190,7 +289,7 @@ func (s Store) PutDoc(ctx context.Context, doc docshelf.Doc) (string, error) {
 	}
 
 	// full text index
-	if err := s.ti.Index(ctx, doc); err != nil {
+	if err := s.indexDoc(ctx, doc); err != nil {
 		return "", errors.Wrap(err, "failed to text index doc")
 	}
 
@@ -198,18 +297,36 @@ func (s Store) PutDoc(ctx context.Context, doc docshelf.Doc) (string, error) {
 
 	// save metadata
 	if err := s.db.Update(func(tx *bolt.Tx) error {
+		// the doc could have changed between reading it and writing content, so the version is checked again
+		if err := checkVersion(tx, doc.Path, expected); err != nil {
+			return err
+		}
+
+		previous, err := storedDoc(tx, doc.Path)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal doc from bolt")
+		}
+
 		if err := s.putItem(ctx, tx, docBucket, doc.Path, doc); err != nil {
 
 			return errors.Wrap(err, "failed to put doc into bolt")
 		}
 
+		if err := reorderDoc(tx, previous, doc); err != nil {
+			return errors.Wrap(err, "failed to save doc sort order in bolt")
+		}
+
 		if err := s.putItem(ctx, tx, docIDBucket, doc.ID, doc.Path); err != nil {
 			return errors.Wrap(err, "failed to save doc secondary index in bolt")
 		}
 
+		if err := s.putItem(ctx, tx, revisionBucket, revisionKey(rev.DocID, rev.ID), rev); err != nil {
+			return errors.Wrap(err, "failed to save doc revision in bolt")
+		}
+
 		return nil
 	}); err != nil {
-		if err := s.fs.RemoveFile(doc.Path); err != nil { // need to rollback file storage if doc fails
+		if err := s.rollbackContent(doc.Path, existing); err != nil { // need to rollback file storage if doc fails
 			return "", errors.Wrap(err, "failed to put cleanup file after bolt failure")
 		}
 
@@ -219,17 +336,55 @@ func (s Store) PutDoc(ctx context.Context, doc docshelf.Doc) (string, error) {
 	return doc.ID, nil
 }
 
-// TagDoc tags an existing document with the given tags.
-func (s Store) TagDoc(ctx context.Context, path string, tags ...string) error {
-	if _, err := xid.FromString(path); err == nil {
-		doc, err := s.GetDoc(ctx, path)
-		if err != nil {
-			return err
+// checkVersion makes sure the doc stored at path is still at the expected version. A version of 0 expects no
+// doc to exist at all.
+func checkVersion(tx *bolt.Tx, path string, expected int) error {
+	var current docshelf.Doc
+	if val := tx.Bucket(docBucket).Get([]byte(path)); val != nil {
+		if err := json.Unmarshal(val, &current); err != nil {
+			return errors.Wrap(err, "failed to unmarshal doc from bolt")
 		}
+	}
 
-		path = doc.Path
+	if current.Version != expected {
+		return docshelf.NewErrConflict("doc has been modified since it was read")
 	}
 
+	return nil
+}
+
+// rollbackContent puts back the content a failed write replaced. If there was no doc before the write, the
+// file is removed instead.
+func (s Store) rollbackContent(path string, previous docshelf.Doc) error {
+	if previous.ID == "" {
+		return s.fs.RemoveFile(path)
+	}
+
+	return s.fs.WriteFile(path, []byte(previous.Content))
+}
+
+// TagDoc tags an existing document with the given tags. The doc is re-indexed so it can be searched by its
+// new tags.
+func (s Store) TagDoc(ctx context.Context, path string, tags ...string) error {
+	doc, err := s.GetDoc(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if err := s.tagPath(ctx, doc.Path, tags...); err != nil {
+		return err
+	}
+
+	// pins aren't searchable, so there's nothing to re-index when only pinning
+	if doc.IsDir || !hasSearchableTag(tags) {
+		return nil
+	}
+
+	return errors.Wrap(s.indexDoc(ctx, doc), "failed to text index tagged doc")
+}
+
+// tagPath adds a doc path to each of the given tags.
+func (s Store) tagPath(ctx context.Context, path string, tags ...string) error {
 	if err := s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(tagBucket)
 		for _, t := range tags {
@@ -270,19 +425,216 @@ func (s Store) TagDoc(ctx context.Context, path string, tags ...string) error {
 	return nil
 }
 
-// RemoveDoc removes a docshelf Doc from bolt as well as the underlying FileStore.
-func (s Store) RemoveDoc(ctx context.Context, path string) error {
-	if err := s.fs.RemoveFile(path); err != nil {
-		return errors.Wrap(err, "failed to remove doc from file store")
+// MoveDoc relocates a docshelf Doc to a new path in bolt. The doc keeps its ID, tags, pins and revision history.
+// The content is moved within the underlying FileStore and re-indexed under the new path.
+func (s Store) MoveDoc(ctx context.Context, from, to, userID string) error {
+	to = strings.Trim(to, "/")
+	if to == "" {
+		return errors.New("can not move a doc without a destination path")
+	}
+
+	doc, err := s.GetDoc(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	from = doc.Path
+	if from == to {
+		return nil
+	}
+
+	if doc.IsDir {
+		return errors.New("moving folders is not supported")
+	}
+
+	if doc.Lock.Blocks(userID) {
+		return docshelf.NewErrLocked("doc is locked by another user")
+	}
+
+	// need to check the destination up front so existing content is never overwritten
+	if err := s.fetchItem(ctx, docBucket, to, &docshelf.Doc{}); err == nil {
+		return errors.Errorf("a doc already exists at %s", to)
+	} else if !docshelf.CheckNotFound(err) {
+		return errors.Wrap(err, "could not verify destination path")
+	}
+
+	// content has to exist at the destination before the metadata can point to it
+	if err := s.fs.WriteFile(to, []byte(doc.Content)); err != nil {
+		return errors.Wrap(err, "failed to write doc to new path in file store")
 	}
 
-	return errors.Wrap(s.db.Update(func(tx *bolt.Tx) error {
+	moved := doc
+	moved.Path = to
+	moved.Content = ""
+	moved.Lock = nil
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket(docBucket)
+		if b.Get([]byte(to)) != nil {
+			return errors.Errorf("a doc already exists at %s", to)
+		}
+
+		if err := s.putItem(ctx, tx, docBucket, to, moved); err != nil {
+			return errors.Wrap(err, "failed to put moved doc into bolt")
+		}
+
+		if err := b.Delete([]byte(from)); err != nil {
+			return errors.Wrap(err, "failed to remove old doc path from bolt")
+		}
 
-		if err := b.Delete([]byte(path)); err != nil {
+		if err := reorderDoc(tx, doc, moved); err != nil {
+			return errors.Wrap(err, "failed to save doc sort order in bolt")
+		}
+
+		if err := s.putItem(ctx, tx, docIDBucket, doc.ID, to); err != nil {
+			return errors.Wrap(err, "failed to save doc secondary index in bolt")
+		}
+
+		if _, err := s.replaceTaggedPath(ctx, tx, from, to); err != nil {
+			return errors.Wrap(err, "failed to move doc tags")
+		}
+
+		return nil
+	}); err != nil {
+		if err := s.fs.RemoveFile(to); err != nil { // need to rollback file storage if move fails
+			return errors.Wrap(err, "failed to cleanup file after bolt failure")
+		}
+
+		return err
+	}
+
+	if err := s.fs.RemoveFile(from); err != nil {
+		return errors.Wrap(err, "failed to remove doc from old path in file store")
+	}
+
+	moved.Content = doc.Content
+	if err := s.indexDoc(ctx, moved); err != nil {
+		return errors.Wrap(err, "failed to text index moved doc")
+	}
+
+	return errors.Wrap(s.ti.Delete(ctx, from), "failed to remove old doc path from text index")
+}
+
+// indexDoc adds a doc to the text index along with its tags. Pins are left out since they only mean
+// something to the user that made them.
+func (s Store) indexDoc(ctx context.Context, doc docshelf.Doc) error {
+	var tagged map[string][]string
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		tagged, err = s.tagsByPath(ctx, tx)
+		return err
+	}); err != nil {
+		return errors.Wrap(err, "failed to read tags for doc")
+	}
+
+	doc.Tags = docshelf.WithoutPins(tagged[doc.Path])
+	return s.ti.Index(ctx, doc)
+}
+
+// replaceTaggedPath swaps a doc path for a new one in every tag that references it. This includes user pins.
+// An empty replacement removes the path from its tags entirely. The tags that referenced the path are
+// returned.
+func (s Store) replaceTaggedPath(ctx context.Context, tx *bolt.Tx, from, to string) ([]string, error) {
+	b := tx.Bucket(tagBucket)
+	updates := make(map[string][]string)
+	if err := b.ForEach(func(k, v []byte) error {
+		var paths []string
+		if err := json.Unmarshal(v, &paths); err != nil {
 			return err
 		}
 
+		for i, p := range paths {
+			if p != from {
+				continue
+			}
+
+			if to == "" {
+				paths = append(paths[:i], paths[i+1:]...)
+			} else {
+				paths[i] = to
+			}
+
+			updates[string(k)] = paths
+			break
+		}
+
 		return nil
-	}), "failed to remove doc from bolt")
+	}); err != nil {
+		return nil, err
+	}
+
+	// buckets can't be modified while iterating over them, so updates are applied afterwards
+	tags := make([]string, 0, len(updates))
+	for tag, paths := range updates {
+		tags = append(tags, tag)
+		if len(paths) == 0 {
+			if err := b.Delete([]byte(tag)); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if err := s.putItem(ctx, tx, tagBucket, tag, paths); err != nil {
+			return nil, err
+		}
+	}
+
+	return tags, nil
+}
+
+// RemoveDoc moves a docshelf Doc into the trash. Its content and tags are kept alongside the metadata in bolt
+// so it can be restored later, and the content is removed from the underlying FileStore. Folders are removed
+// recursively. Docs locked by anyone other than the given user can't be removed.
+func (s Store) RemoveDoc(ctx context.Context, path, userID string) error {
+	doc, err := s.GetDoc(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if doc.IsDir {
+		return s.RemoveDir(ctx, doc.Path, userID)
+	}
+
+	if doc.Lock.Blocks(userID) {
+		return docshelf.NewErrLocked("doc is locked by another user")
+	}
+
+	deletedAt := time.Now()
+	doc.DeletedAt = &deletedAt
+	doc.Lock = nil
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		tags, err := s.replaceTaggedPath(ctx, tx, doc.Path, "")
+		if err != nil {
+			return err
+		}
+
+		doc.Tags = union(doc.Tags, tags)
+		if err := s.putItem(ctx, tx, trashBucket, doc.ID, doc); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(docIDBucket).Delete([]byte(doc.ID)); err != nil {
+			return err
+		}
+
+		if err := tx.Bucket(lockBucket).Delete([]byte(doc.ID)); err != nil {
+			return err
+		}
+
+		if err := reorderDoc(tx, doc, docshelf.Doc{}); err != nil {
+			return err
+		}
+
+		return tx.Bucket(docBucket).Delete([]byte(doc.Path))
+	}); err != nil {
+		return errors.Wrap(err, "failed to move doc to trash in bolt")
+	}
+
+	if err := s.fs.RemoveFile(doc.Path); err != nil {
+		return errors.Wrap(err, "failed to remove doc from file store")
+	}
+
+	return errors.Wrap(s.ti.Delete(ctx, doc.Path), "failed to remove doc from text index")
 }
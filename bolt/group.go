@@ -2,6 +2,7 @@ package bolt
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	"github.com/boltdb/bolt"
@@ -15,37 +16,168 @@ func (s Store) GetGroup(ctx context.Context, id string) (docshelf.Group, error)
 	var group docshelf.Group
 
 	if err := s.fetchItem(ctx, groupBucket, id, &group); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return group, err
+		}
+
 		return group, errors.Wrap(err, "failed to fetch group from bolt")
 	}
 
 	return group, nil
 }
 
-// PutGroup creates a new docshelf Group or updates an existing one in boltdb.
+// ListGroups returns all docshelf Groups stored in boltdb.
+func (s Store) ListGroups(ctx context.Context) ([]docshelf.Group, error) {
+	groups := make([]docshelf.Group, 0)
+
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(groupBucket).ForEach(func(k, v []byte) error {
+			var group docshelf.Group
+			if err := json.Unmarshal(v, &group); err != nil {
+				return err
+			}
+
+			groups = append(groups, group)
+			return nil
+		})
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list groups from bolt")
+	}
+
+	return groups, nil
+}
+
+// PutGroup creates a new docshelf Group or updates an existing one in boltdb. The Groups of every User
+// joining or leaving the Group are updated in the same transaction.
 func (s Store) PutGroup(ctx context.Context, group docshelf.Group) (string, error) {
 	if group.ID == "" {
 		group.ID = xid.New().String()
-		group.CreatedAt = time.Now()
 	}
 
+	group.CreatedAt = time.Now()
 	group.UpdatedAt = time.Now()
+	group.Users = union(group.Users, nil) // drops duplicates
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		var existing docshelf.Group
+		if err := s.getItem(ctx, tx, groupBucket, group.ID, &existing); err == nil {
+			group.CreatedAt = existing.CreatedAt
+		} else if !docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		if err := s.syncMembers(ctx, tx, group.ID, existing.Users, group.Users); err != nil {
+			return err
+		}
+
+		return s.putItem(ctx, tx, groupBucket, group.ID, group)
+	}); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return "", err
+		}
 
-	if err := s.storeItem(ctx, groupBucket, group.ID, group); err != nil {
 		return "", errors.Wrap(err, "failed to put group into bolt")
 	}
 
 	return group.ID, nil
 }
 
-// RemoveGroup deletes a docshelf Group from boltdb.
+// RemoveGroup deletes a docshelf Group from boltdb and removes it from the Groups of all of its Users.
 func (s Store) RemoveGroup(ctx context.Context, id string) error {
 	return errors.Wrap(s.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket(userBucket)
+		var group docshelf.Group
+		if err := s.getItem(ctx, tx, groupBucket, id, &group); err != nil {
+			if docshelf.CheckNotFound(err) {
+				return nil
+			}
 
-		if err := b.Delete([]byte(id)); err != nil {
 			return err
 		}
 
-		return nil
+		if err := s.syncMembers(ctx, tx, id, group.Users, nil); err != nil {
+			return err
+		}
+
+		return tx.Bucket(groupBucket).Delete([]byte(id))
 	}), "failed to remove group from bolt")
 }
+
+// AddGroupUsers adds Users to an existing docshelf Group in boltdb.
+func (s Store) AddGroupUsers(ctx context.Context, id string, userIDs ...string) error {
+	return s.updateMembers(ctx, id, func(users []string) []string {
+		return union(users, userIDs)
+	})
+}
+
+// RemoveGroupUsers removes Users from an existing docshelf Group in boltdb.
+func (s Store) RemoveGroupUsers(ctx context.Context, id string, userIDs ...string) error {
+	return s.updateMembers(ctx, id, func(users []string) []string {
+		return without(users, userIDs)
+	})
+}
+
+// updateMembers applies a change to the members of a Group and syncs the Users affected by it.
+func (s Store) updateMembers(ctx context.Context, id string, change func(users []string) []string) error {
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		var group docshelf.Group
+		if err := s.getItem(ctx, tx, groupBucket, id, &group); err != nil {
+			return err
+		}
+
+		before := group.Users
+		group.Users = change(append([]string{}, before...))
+		group.UpdatedAt = time.Now()
+
+		if err := s.syncMembers(ctx, tx, id, before, group.Users); err != nil {
+			return err
+		}
+
+		return s.putItem(ctx, tx, groupBucket, id, group)
+	}); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		return errors.Wrap(err, "failed to update group members in bolt")
+	}
+
+	return nil
+}
+
+// syncMembers updates the Groups of every User whose membership changed between before and after. Users
+// joining the Group must exist.
+func (s Store) syncMembers(ctx context.Context, tx *bolt.Tx, groupID string, before, after []string) error {
+	for _, userID := range without(after, before) {
+		var user docshelf.User
+		if err := s.getItem(ctx, tx, userBucket, userID, &user); err != nil {
+			if docshelf.CheckNotFound(err) {
+				return docshelf.NewErrNotFound("user does not exist: " + userID)
+			}
+
+			return err
+		}
+
+		user.Groups = union(user.Groups, []string{groupID})
+		if err := s.putItem(ctx, tx, userBucket, userID, user); err != nil {
+			return err
+		}
+	}
+
+	for _, userID := range without(before, after) {
+		var user docshelf.User
+		if err := s.getItem(ctx, tx, userBucket, userID, &user); err != nil {
+			if docshelf.CheckNotFound(err) {
+				continue // nothing to clean up
+			}
+
+			return err
+		}
+
+		user.Groups = without(user.Groups, []string{groupID})
+		if err := s.putItem(ctx, tx, userBucket, userID, user); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+)
+
+// LockDoc gives a user exclusive write access to a docshelf Doc in bolt for the given duration. Users can
+// extend locks they already hold, but a lock held by anyone else has to expire or be broken first.
+func (s Store) LockDoc(ctx context.Context, path, userID string, ttl time.Duration) (docshelf.Lock, error) {
+	docID, err := s.resolveDocID(ctx, path)
+	if err != nil {
+		return docshelf.Lock{}, err
+	}
+
+	now := time.Now()
+	lock := docshelf.Lock{
+		DocID:     docID,
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(docIDBucket).Get([]byte(docID)) == nil {
+			return docshelf.NewErrNotFound("doc does not exist")
+		}
+
+		current, err := getLock(tx, docID)
+		if err != nil {
+			return err
+		}
+
+		if current.Blocks(userID) {
+			return docshelf.NewErrLocked("doc is locked by another user")
+		}
+
+		// extending a lock shouldn't change when it was first taken
+		if current.Active() {
+			lock.CreatedAt = current.CreatedAt
+		}
+
+		return s.putItem(ctx, tx, lockBucket, docID, lock)
+	}); err != nil {
+		if docshelf.CheckNotFound(err) || docshelf.CheckLocked(err) {
+			return lock, err
+		}
+
+		return lock, errors.Wrap(err, "failed to lock doc in bolt")
+	}
+
+	return lock, nil
+}
+
+// UnlockDoc releases a lock held by the given user on a docshelf Doc in bolt. Releasing a lock that doesn't
+// exist or has already expired is not an error.
+func (s Store) UnlockDoc(ctx context.Context, path, userID string) error {
+	docID, err := s.resolveDocID(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		current, err := getLock(tx, docID)
+		if err != nil {
+			return err
+		}
+
+		if current.Blocks(userID) {
+			return docshelf.NewErrLocked("doc is locked by another user")
+		}
+
+		return tx.Bucket(lockBucket).Delete([]byte(docID))
+	}); err != nil {
+		if docshelf.CheckLocked(err) {
+			return err
+		}
+
+		return errors.Wrap(err, "failed to unlock doc in bolt")
+	}
+
+	return nil
+}
+
+// BreakLock releases the lock on a docshelf Doc in bolt regardless of who holds it.
+func (s Store) BreakLock(ctx context.Context, path string) error {
+	docID, err := s.resolveDocID(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(lockBucket).Delete([]byte(docID))
+	}), "failed to break doc lock in bolt")
+}
+
+// getLock returns the active lock for a doc, if there is one.
+func getLock(tx *bolt.Tx, docID string) (*docshelf.Lock, error) {
+	val := tx.Bucket(lockBucket).Get([]byte(docID))
+	if val == nil {
+		return nil, nil
+	}
+
+	var lock docshelf.Lock
+	if err := json.Unmarshal(val, &lock); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal lock from bolt")
+	}
+
+	if !lock.Active() {
+		return nil, nil
+	}
+
+	return &lock, nil
+}
@@ -0,0 +1,76 @@
+package bolt
+
+import (
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+)
+
+// indexedSorts are the orders bolt keeps an index of docs in, besides path which the doc bucket is already
+// sorted by.
+var indexedSorts = []docshelf.DocSort{docshelf.SortUpdated, docshelf.SortCreated, docshelf.SortTitle}
+
+// docOrderKey returns the key a doc is kept under in the order bucket. Each sort has its own section of the
+// bucket, and the value stored under a key is the doc's path.
+func docOrderKey(sort docshelf.DocSort, doc docshelf.Doc) []byte {
+	return []byte(docOrderPrefix(sort) + docshelf.IndexKey(sort, doc))
+}
+
+func docOrderPrefix(sort docshelf.DocSort) string {
+	return string(sort) + "/"
+}
+
+// reorderDoc moves a doc's entries in the order bucket from where the previous version of the doc sorted to
+// where the current one does. An empty previous doc only adds entries, and an empty current doc only removes
+// them.
+func reorderDoc(tx *bolt.Tx, previous, current docshelf.Doc) error {
+	b := tx.Bucket(docOrderBucket)
+	for _, sort := range indexedSorts {
+		if previous.Path != "" {
+			if err := b.Delete(docOrderKey(sort, previous)); err != nil {
+				return err
+			}
+		}
+
+		if current.Path != "" {
+			if err := b.Put(docOrderKey(sort, current), []byte(current.Path)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// storedDoc returns the metadata currently stored for a path, or an empty doc if there isn't any.
+func storedDoc(tx *bolt.Tx, path string) (docshelf.Doc, error) {
+	var doc docshelf.Doc
+	if val := tx.Bucket(docBucket).Get([]byte(path)); val != nil {
+		if err := json.Unmarshal(val, &doc); err != nil {
+			return doc, err
+		}
+	}
+
+	return doc, nil
+}
+
+// initDocOrder creates the order bucket, indexing every doc that was stored before it existed.
+func initDocOrder(tx *bolt.Tx) error {
+	if tx.Bucket(docOrderBucket) != nil {
+		return nil
+	}
+
+	if _, err := tx.CreateBucket(docOrderBucket); err != nil {
+		return err
+	}
+
+	return tx.Bucket(docBucket).ForEach(func(k, v []byte) error {
+		var doc docshelf.Doc
+		if err := json.Unmarshal(v, &doc); err != nil {
+			return err
+		}
+
+		return reorderDoc(tx, docshelf.Doc{}, doc)
+	})
+}
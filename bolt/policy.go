@@ -2,6 +2,8 @@ package bolt
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/boltdb/bolt"
@@ -15,22 +17,56 @@ func (s Store) GetPolicy(ctx context.Context, id string) (docshelf.Policy, error
 	var policy docshelf.Policy
 
 	if err := s.fetchItem(ctx, policyBucket, id, &policy); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return policy, err
+		}
+
 		return policy, errors.Wrap(err, "failed to fetch policy from bolt")
 	}
 
 	return policy, nil
 }
 
+// ListPolicies returns all docshelf Policies stored in boltdb.
+func (s Store) ListPolicies(ctx context.Context) ([]docshelf.Policy, error) {
+	policies := make([]docshelf.Policy, 0)
+
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(policyBucket).ForEach(func(k, v []byte) error {
+			var policy docshelf.Policy
+			if err := json.Unmarshal(v, &policy); err != nil {
+				return err
+			}
+
+			policies = append(policies, policy)
+			return nil
+		})
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list policies from bolt")
+	}
+
+	return policies, nil
+}
+
 // PutPolicy creates a new docshelf Policy or updates an existing one in boltdb.
 func (s Store) PutPolicy(ctx context.Context, policy docshelf.Policy) (string, error) {
 	if policy.ID == "" {
 		policy.ID = xid.New().String()
-		policy.CreatedAt = time.Now()
 	}
 
+	policy.CreatedAt = time.Now()
 	policy.UpdatedAt = time.Now()
 
-	if err := s.storeItem(ctx, policyBucket, policy.ID, policy); err != nil {
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		var existing docshelf.Policy
+		if err := s.getItem(ctx, tx, policyBucket, policy.ID, &existing); err == nil {
+			policy.CreatedAt = existing.CreatedAt
+		} else if !docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		return s.putItem(ctx, tx, policyBucket, policy.ID, policy)
+	}); err != nil {
 		return "", errors.Wrap(err, "failed to put policy into bolt")
 	}
 
@@ -40,7 +76,7 @@ func (s Store) PutPolicy(ctx context.Context, policy docshelf.Policy) (string, e
 // RemovePolicy deletes a policy from boltdb.
 func (s Store) RemovePolicy(ctx context.Context, id string) error {
 	return errors.Wrap(s.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket(userBucket)
+		b := tx.Bucket(policyBucket)
 
 		if err := b.Delete([]byte(id)); err != nil {
 			return err
@@ -49,3 +85,69 @@ func (s Store) RemovePolicy(ctx context.Context, id string) error {
 		return nil
 	}), "failed to remove policy from bolt")
 }
+
+// PutPathPolicy attaches an existing docshelf Policy to a path prefix in boltdb. Any Policy previously
+// attached to the same prefix is replaced.
+func (s Store) PutPathPolicy(ctx context.Context, path, policyID string) error {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return errors.New("policies can not be attached to the root of the shelf")
+	}
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(policyBucket).Get([]byte(policyID)) == nil {
+			return docshelf.NewErrNotFound("policy does not exist")
+		}
+
+		return s.putItem(ctx, tx, pathPolicyBucket, path, policyID)
+	}); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		return errors.Wrap(err, "failed to put path policy into bolt")
+	}
+
+	return nil
+}
+
+// RemovePathPolicy detaches whatever Policy is attached to a path prefix in boltdb.
+func (s Store) RemovePathPolicy(ctx context.Context, path string) error {
+	path = strings.Trim(path, "/")
+
+	return errors.Wrap(s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pathPolicyBucket).Delete([]byte(path))
+	}), "failed to remove path policy from bolt")
+}
+
+// ListPathPolicies returns every Policy attached to a path prefix in boltdb, keyed by prefix. Attachments
+// pointing at policies that no longer exist are skipped.
+func (s Store) ListPathPolicies(ctx context.Context) (map[string]docshelf.Policy, error) {
+	policies := make(map[string]docshelf.Policy)
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		pb := tx.Bucket(policyBucket)
+		return tx.Bucket(pathPolicyBucket).ForEach(func(k, v []byte) error {
+			var policyID string
+			if err := json.Unmarshal(v, &policyID); err != nil {
+				return err
+			}
+
+			val := pb.Get([]byte(policyID))
+			if val == nil {
+				return nil
+			}
+
+			var policy docshelf.Policy
+			if err := json.Unmarshal(val, &policy); err != nil {
+				return err
+			}
+
+			policies[string(k)] = policy
+			return nil
+		})
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list path policies from bolt")
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,53 @@
+package bolt
+
+import (
+	"context"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+)
+
+// GetPasswordReset fetches an existing docshelf PasswordReset from boltdb.
+func (s Store) GetPasswordReset(ctx context.Context, id string) (docshelf.PasswordReset, error) {
+	var reset docshelf.PasswordReset
+
+	if err := s.fetchItem(ctx, resetBucket, id, &reset); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return reset, err
+		}
+
+		return reset, errors.Wrap(err, "failed to fetch password reset from bolt")
+	}
+
+	return reset, nil
+}
+
+// PutPasswordReset stores a new docshelf PasswordReset in boltdb.
+func (s Store) PutPasswordReset(ctx context.Context, reset docshelf.PasswordReset) error {
+	if reset.ID == "" {
+		return errors.New("cannot store a password reset without an id")
+	}
+
+	return errors.Wrap(s.storeItem(ctx, resetBucket, reset.ID, reset), "failed to put password reset into bolt")
+}
+
+// UsePasswordReset marks a docshelf PasswordReset in boltdb as used. A PasswordReset can only be used once,
+// so using it again results in a conflict.
+func (s Store) UsePasswordReset(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var reset docshelf.PasswordReset
+		if err := s.getItem(ctx, tx, resetBucket, id, &reset); err != nil {
+			return err
+		}
+
+		if reset.UsedAt != nil {
+			return docshelf.NewErrConflict("password reset has already been used")
+		}
+
+		usedAt := time.Now()
+		reset.UsedAt = &usedAt
+		return errors.Wrap(s.putItem(ctx, tx, resetBucket, id, reset), "failed to use password reset in bolt")
+	})
+}
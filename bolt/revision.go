@@ -0,0 +1,83 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// ListRevisions fetches the revision history of a docshelf Doc from bolt, oldest first. Revision content
+// is omitted from the listing and can be retrieved with GetRevision.
+func (s Store) ListRevisions(ctx context.Context, path string) ([]docshelf.Revision, error) {
+	docID, err := s.resolveDocID(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	revs := make([]docshelf.Revision, 0)
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(revisionBucket).Cursor()
+		prefix := []byte(revisionKey(docID, ""))
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var rev docshelf.Revision
+			if err := json.Unmarshal(v, &rev); err != nil {
+				return err
+			}
+
+			rev.Content = ""
+			revs = append(revs, rev)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list revisions from bolt")
+	}
+
+	return revs, nil
+}
+
+// GetRevision fetches a single revision of a docshelf Doc from bolt, including its content.
+func (s Store) GetRevision(ctx context.Context, path, id string) (docshelf.Revision, error) {
+	var rev docshelf.Revision
+
+	docID, err := s.resolveDocID(ctx, path)
+	if err != nil {
+		return rev, err
+	}
+
+	if err := s.fetchItem(ctx, revisionBucket, revisionKey(docID, id), &rev); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return rev, err
+		}
+
+		return rev, errors.Wrap(err, "failed to fetch revision from bolt")
+	}
+
+	return rev, nil
+}
+
+// resolveDocID takes either a doc path or ID and returns the ID of the doc.
+func (s Store) resolveDocID(ctx context.Context, path string) (string, error) {
+	if _, err := xid.FromString(path); err == nil {
+		return path, nil
+	}
+
+	var doc docshelf.Doc
+	if err := s.fetchItem(ctx, docBucket, path, &doc); err != nil {
+		return "", err
+	}
+
+	return doc.ID, nil
+}
+
+// revision keys are prefixed with the doc ID so a doc's history can be scanned with a cursor. Since
+// xids are sortable, the history is naturally ordered by creation time.
+func revisionKey(docID, revID string) string {
+	return fmt.Sprintf("%s/%s", docID, revID)
+}
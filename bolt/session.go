@@ -0,0 +1,139 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// GetSession fetches an existing docshelf Session from boltdb.
+func (s Store) GetSession(ctx context.Context, id string) (docshelf.Session, error) {
+	var session docshelf.Session
+
+	if err := s.fetchItem(ctx, sessionBucket, id, &session); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return session, err
+		}
+
+		return session, errors.Wrap(err, "failed to fetch session from bolt")
+	}
+
+	return session, nil
+}
+
+// PutSession creates a new docshelf Session or updates an existing one in boltdb.
+func (s Store) PutSession(ctx context.Context, session docshelf.Session) (string, error) {
+	if session.ID == "" {
+		session.ID = xid.New().String()
+		session.CreatedAt = time.Now()
+	}
+
+	if err := s.storeItem(ctx, sessionBucket, session.ID, session); err != nil {
+		return "", errors.Wrap(err, "failed to put session into bolt")
+	}
+
+	return session.ID, nil
+}
+
+// RevokeSession marks a docshelf Session in boltdb as revoked so it can't be used anymore. Revoking a
+// Session that doesn't exist is not an error.
+func (s Store) RevokeSession(ctx context.Context, id string) error {
+	return errors.Wrap(s.db.Update(func(tx *bolt.Tx) error {
+		var session docshelf.Session
+		if err := s.getItem(ctx, tx, sessionBucket, id, &session); err != nil {
+			if docshelf.CheckNotFound(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if session.RevokedAt != nil {
+			return nil
+		}
+
+		revokedAt := time.Now()
+		session.RevokedAt = &revokedAt
+		return s.putItem(ctx, tx, sessionBucket, id, session)
+	}), "failed to revoke session in bolt")
+}
+
+// RevokeUserSessions marks every active docshelf Session belonging to a User in boltdb as revoked.
+func (s Store) RevokeUserSessions(ctx context.Context, userID string) (int, error) {
+	var revoked int
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(sessionBucket)
+		var sessions []docshelf.Session
+		if err := b.ForEach(func(k, v []byte) error {
+			var session docshelf.Session
+			if err := json.Unmarshal(v, &session); err != nil {
+				return err
+			}
+
+			if session.UserID == userID && session.RevokedAt == nil {
+				sessions = append(sessions, session)
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		revokedAt := time.Now()
+		for _, session := range sessions {
+			session.RevokedAt = &revokedAt
+			if err := s.putItem(ctx, tx, sessionBucket, session.ID, session); err != nil {
+				return err
+			}
+		}
+
+		revoked = len(sessions)
+		return nil
+	}); err != nil {
+		return 0, errors.Wrap(err, "failed to revoke user sessions in bolt")
+	}
+
+	return revoked, nil
+}
+
+// PurgeSessions deletes every docshelf Session from boltdb that expired before the given time.
+func (s Store) PurgeSessions(ctx context.Context, before time.Time) (int, error) {
+	var purged int
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(sessionBucket)
+		var expired []string
+		if err := b.ForEach(func(k, v []byte) error {
+			var session docshelf.Session
+			if err := json.Unmarshal(v, &session); err != nil {
+				return err
+			}
+
+			if session.ExpiresAt.Before(before) {
+				expired = append(expired, session.ID)
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// buckets can't be modified while iterating over them, so deletes are applied afterwards
+		for _, id := range expired {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+
+		purged = len(expired)
+		return nil
+	}); err != nil {
+		return 0, errors.Wrap(err, "failed to purge sessions from bolt")
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,263 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// TakeSnapshot captures the metadata, tag membership and content of every docshelf Doc currently stored
+// and saves it as a new Snapshot in bolt.
+func (s Store) TakeSnapshot(ctx context.Context, userID string) (string, error) {
+	var docs []docshelf.Doc
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		tags, err := s.tagsByPath(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(docBucket).ForEach(func(k, v []byte) error {
+			var doc docshelf.Doc
+			if err := json.Unmarshal(v, &doc); err != nil {
+				return err
+			}
+
+			doc.Tags = union(doc.Tags, tags[doc.Path])
+			docs = append(docs, doc)
+			return nil
+		})
+	}); err != nil {
+		return "", errors.Wrap(err, "failed to read docs for snapshot")
+	}
+
+	for i, doc := range docs {
+		if doc.IsDir {
+			continue
+		}
+
+		content, err := s.fs.ReadFile(doc.Path)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read content for snapshot: %s", doc.Path)
+		}
+
+		docs[i].Content = string(content)
+	}
+
+	snap := docshelf.Snapshot{
+		ID:        xid.New().String(),
+		DocCount:  len(docs),
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+	}
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := s.putItem(ctx, tx, snapshotBucket, snap.ID, snap); err != nil {
+			return err
+		}
+
+		for _, doc := range docs {
+			if err := s.putItem(ctx, tx, snapshotDocBucket, snapshotDocKey(snap.ID, doc.Path), doc); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return "", errors.Wrap(err, "failed to save snapshot in bolt")
+	}
+
+	return snap.ID, nil
+}
+
+// GetSnapshot fetches a Snapshot from bolt along with the metadata of every Doc it captured. Content is
+// omitted.
+func (s Store) GetSnapshot(ctx context.Context, id string) (docshelf.Snapshot, error) {
+	var snap docshelf.Snapshot
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		if err := s.getItem(ctx, tx, snapshotBucket, id, &snap); err != nil {
+			return err
+		}
+
+		docs, err := s.listSnapshotDocs(ctx, tx, id, "")
+		if err != nil {
+			return err
+		}
+
+		for i := range docs {
+			docs[i].Content = ""
+		}
+
+		snap.Docs = docs
+		return nil
+	}); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return snap, err
+		}
+
+		return snap, errors.Wrap(err, "failed to fetch snapshot from bolt")
+	}
+
+	return snap, nil
+}
+
+// ListSnapshots returns all Snapshots stored in bolt, oldest first. Captured Docs are omitted.
+func (s Store) ListSnapshots(ctx context.Context) ([]docshelf.Snapshot, error) {
+	snaps := make([]docshelf.Snapshot, 0)
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(snapshotBucket).ForEach(func(k, v []byte) error {
+			var snap docshelf.Snapshot
+			if err := json.Unmarshal(v, &snap); err != nil {
+				return err
+			}
+
+			snaps = append(snaps, snap)
+			return nil
+		})
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list snapshots from bolt")
+	}
+
+	return snaps, nil
+}
+
+// RestoreSnapshot writes every Doc captured by a Snapshot at or under the given path prefix back to the
+// shelf. Docs are restored through PutDoc, so created* fields and the text index stay consistent, and
+// each restore is recorded as a new revision authored by the given user. Docs created after the Snapshot
+// was taken are left alone. Nothing is restored if any captured Doc is locked by another user or has been
+// replaced by a folder, or vice versa.
+func (s Store) RestoreSnapshot(ctx context.Context, id, prefix, userID string) error {
+	var docs []docshelf.Doc
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		var snap docshelf.Snapshot
+		if err := s.getItem(ctx, tx, snapshotBucket, id, &snap); err != nil {
+			return err
+		}
+
+		var err error
+		docs, err = s.listSnapshotDocs(ctx, tx, id, prefix)
+		if err != nil {
+			return err
+		}
+
+		return s.checkRestore(ctx, tx, docs, userID)
+	}); err != nil {
+		if docshelf.CheckNotFound(err) || docshelf.CheckLocked(err) || docshelf.CheckConflict(err) {
+			return err
+		}
+
+		return errors.Wrap(err, "failed to read snapshot from bolt")
+	}
+
+	for _, doc := range docs {
+		tags := doc.Tags
+		doc.UpdatedBy = userID
+		doc.Version = 0 // restores always win over whatever is currently stored
+		doc.Message = fmt.Sprintf("restored from snapshot %s", id)
+		if _, err := s.PutDoc(ctx, doc); err != nil {
+			return errors.Wrapf(err, "failed to restore doc: %s", doc.Path)
+		}
+
+		if len(tags) > 0 {
+			if err := s.TagDoc(ctx, doc.Path, tags...); err != nil {
+				return errors.Wrapf(err, "failed to restore tags for doc: %s", doc.Path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkRestore makes sure every captured Doc can be written back over whatever is currently stored at its
+// path, so a restore doesn't stop halfway through.
+func (s Store) checkRestore(ctx context.Context, tx *bolt.Tx, docs []docshelf.Doc, userID string) error {
+	var locked, replaced []string
+	for _, doc := range docs {
+		var existing docshelf.Doc
+		if err := s.getItem(ctx, tx, docBucket, doc.Path, &existing); err != nil {
+			if docshelf.CheckNotFound(err) {
+				continue
+			}
+
+			return err
+		}
+
+		if existing.IsDir != doc.IsDir {
+			replaced = append(replaced, doc.Path)
+			continue
+		}
+
+		lock, err := getLock(tx, existing.ID)
+		if err != nil {
+			return err
+		}
+
+		if lock.Blocks(userID) {
+			locked = append(locked, doc.Path)
+		}
+	}
+
+	if len(locked) > 0 {
+		return docshelf.NewErrLocked(fmt.Sprintf("docs are locked by another user: %s", strings.Join(locked, ", ")))
+	}
+
+	if len(replaced) > 0 {
+		return docshelf.NewErrConflict(fmt.Sprintf("folders and docs have replaced each other: %s", strings.Join(replaced, ", ")))
+	}
+
+	return nil
+}
+
+// listSnapshotDocs returns the Docs captured by a Snapshot that are at or nested under the given path.
+func (s Store) listSnapshotDocs(ctx context.Context, tx *bolt.Tx, id, prefix string) ([]docshelf.Doc, error) {
+	docs := make([]docshelf.Doc, 0)
+	c := tx.Bucket(snapshotDocBucket).Cursor()
+	keyPrefix := []byte(snapshotDocKey(id, strings.Trim(prefix, "/")))
+	for k, v := c.Seek(keyPrefix); k != nil && bytes.HasPrefix(k, keyPrefix); k, v = c.Next() {
+		var doc docshelf.Doc
+		if err := json.Unmarshal(v, &doc); err != nil {
+			return nil, err
+		}
+
+		// the key scan also picks up siblings sharing the prefix, e.g. "hrx/" when restoring "hr"
+		if docshelf.InPath(doc.Path, prefix) {
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs, nil
+}
+
+// tagsByPath inverts the tag bucket into a mapping of doc paths to the tags applied to them.
+func (s Store) tagsByPath(ctx context.Context, tx *bolt.Tx) (map[string][]string, error) {
+	tags := make(map[string][]string)
+	if err := tx.Bucket(tagBucket).ForEach(func(k, v []byte) error {
+		var paths []string
+		if err := json.Unmarshal(v, &paths); err != nil {
+			return err
+		}
+
+		for _, p := range paths {
+			tags[p] = append(tags[p], string(k))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// snapshot doc keys are prefixed with the snapshot ID so that a single snapshot, or a path prefix within it,
+// can be scanned with a cursor.
+func snapshotDocKey(snapID, path string) string {
+	return fmt.Sprintf("%s/%s", snapID, path)
+}
@@ -0,0 +1,284 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+)
+
+// ListTags returns every tag in bolt along with how many readable docs have it, most used first. Pins aren't
+// included, and neither are tags without any readable docs.
+func (s Store) ListTags(ctx context.Context, canRead func(docshelf.Doc) bool) ([]docshelf.TagCount, error) {
+	counts := make([]docshelf.TagCount, 0)
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		readable := make(map[string]bool)
+		return tx.Bucket(tagBucket).ForEach(func(k, v []byte) error {
+			if docshelf.IsPin(string(k)) {
+				return nil
+			}
+
+			var paths []string
+			if err := json.Unmarshal(v, &paths); err != nil {
+				return err
+			}
+
+			var count int
+			for _, path := range paths {
+				ok, checked := readable[path]
+				if !checked {
+					var doc docshelf.Doc
+					if err := s.getItem(ctx, tx, docBucket, path, &doc); err != nil && !docshelf.CheckNotFound(err) {
+						return err
+					}
+
+					ok = doc.Path != "" && canRead(doc)
+					readable[path] = ok
+				}
+
+				if ok {
+					count++
+				}
+			}
+
+			if count > 0 {
+				counts = append(counts, docshelf.TagCount{Tag: string(k), Count: count})
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list tags from bolt")
+	}
+
+	docshelf.SortTagCounts(counts)
+
+	return counts, nil
+}
+
+// UntagDoc removes the given tags from an existing document. Tags left without any docs are deleted, and
+// tags the document doesn't have are ignored.
+func (s Store) UntagDoc(ctx context.Context, path string, tags ...string) error {
+	doc, err := s.GetDoc(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, t := range tags {
+			var paths []string
+			if err := s.getItem(ctx, tx, tagBucket, t, &paths); err != nil {
+				if docshelf.CheckNotFound(err) {
+					continue
+				}
+
+				return err
+			}
+
+			if err := s.putTag(ctx, tx, t, without(paths, []string{doc.Path})); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "failed to untag document")
+	}
+
+	// pins aren't searchable, so there's nothing to re-index when only unpinning
+	if doc.IsDir || !hasSearchableTag(tags) {
+		return nil
+	}
+
+	return errors.Wrap(s.indexDoc(ctx, doc), "failed to text index untagged doc")
+}
+
+// RenameTag gives a tag a new name on every doc that has it. Renaming onto a tag that already exists is a
+// conflict, those tags have to be merged instead.
+func (s Store) RenameTag(ctx context.Context, from, to string) error {
+	if err := checkTagNames(from, to); err != nil {
+		return err
+	}
+
+	if from == to {
+		return nil
+	}
+
+	var paths []string
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := s.getItem(ctx, tx, tagBucket, from, &paths); err != nil {
+			return err
+		}
+
+		if tx.Bucket(tagBucket).Get([]byte(to)) != nil {
+			return docshelf.NewErrConflict("a tag with that name already exists")
+		}
+
+		if err := s.putTag(ctx, tx, to, paths); err != nil {
+			return err
+		}
+
+		if err := s.retagTrash(ctx, tx, to, from); err != nil {
+			return err
+		}
+
+		return tx.Bucket(tagBucket).Delete([]byte(from))
+	}); err != nil {
+		if docshelf.CheckNotFound(err) || docshelf.CheckConflict(err) {
+			return err
+		}
+
+		return errors.Wrap(err, "failed to rename tag in bolt")
+	}
+
+	return s.reindexPaths(ctx, paths)
+}
+
+// MergeTags moves every doc with one of the from tags over to the into tag, which is created if it doesn't
+// exist yet. The from tags are deleted afterwards.
+func (s Store) MergeTags(ctx context.Context, into string, from ...string) error {
+	if err := checkTagNames(append([]string{into}, from...)...); err != nil {
+		return err
+	}
+
+	var moved []string
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		var merged []string
+		if err := s.getItem(ctx, tx, tagBucket, into, &merged); err != nil && !docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		for _, f := range from {
+			if f == into {
+				continue
+			}
+
+			var paths []string
+			if err := s.getItem(ctx, tx, tagBucket, f, &paths); err != nil {
+				return err
+			}
+
+			merged = union(merged, paths)
+			moved = union(moved, paths)
+			if err := tx.Bucket(tagBucket).Delete([]byte(f)); err != nil {
+				return err
+			}
+		}
+
+		if err := s.retagTrash(ctx, tx, into, from...); err != nil {
+			return err
+		}
+
+		return s.putTag(ctx, tx, into, merged)
+	}); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		return errors.Wrap(err, "failed to merge tags in bolt")
+	}
+
+	return s.reindexPaths(ctx, moved)
+}
+
+// putTag stores the paths that have a tag. Tags without any paths are deleted.
+func (s Store) putTag(ctx context.Context, tx *bolt.Tx, tag string, paths []string) error {
+	if len(paths) == 0 {
+		return tx.Bucket(tagBucket).Delete([]byte(tag))
+	}
+
+	return s.putItem(ctx, tx, tagBucket, tag, paths)
+}
+
+// retagTrash replaces the from tags with the into tag on every doc in the trash, so restoring a doc doesn't
+// bring back a tag that has since been renamed or merged away.
+func (s Store) retagTrash(ctx context.Context, tx *bolt.Tx, into string, from ...string) error {
+	var retagged []docshelf.Doc
+	if err := tx.Bucket(trashBucket).ForEach(func(k, v []byte) error {
+		var doc docshelf.Doc
+		if err := json.Unmarshal(v, &doc); err != nil {
+			return err
+		}
+
+		if remaining := without(doc.Tags, from); len(remaining) != len(doc.Tags) {
+			doc.Tags = union(remaining, []string{into})
+			retagged = append(retagged, doc)
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// bolt buckets can't be written to while they're being iterated over
+	for _, doc := range retagged {
+		if err := s.putItem(ctx, tx, trashBucket, doc.ID, doc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reindexPaths indexes the docs at the given paths again after their tags have changed.
+func (s Store) reindexPaths(ctx context.Context, paths []string) error {
+	var tagged map[string][]string
+	var docs []docshelf.Doc
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		if tagged, err = s.tagsByPath(ctx, tx); err != nil {
+			return err
+		}
+
+		for _, p := range paths {
+			var doc docshelf.Doc
+			if err := s.getItem(ctx, tx, docBucket, p, &doc); err != nil {
+				if docshelf.CheckNotFound(err) {
+					continue
+				}
+
+				return err
+			}
+
+			if !doc.IsDir {
+				docs = append(docs, doc)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return errors.Wrap(err, "failed to read retagged docs from bolt")
+	}
+
+	for _, doc := range docs {
+		content, err := s.fs.ReadFile(doc.Path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read retagged doc: %s", doc.Path)
+		}
+
+		doc.Content = string(content)
+		doc.Tags = docshelf.WithoutPins(tagged[doc.Path])
+		if err := s.ti.Index(ctx, doc); err != nil {
+			return errors.Wrapf(err, "failed to text index retagged doc: %s", doc.Path)
+		}
+	}
+
+	return nil
+}
+
+// checkTagNames makes sure tags being renamed or merged are regular, named tags.
+func checkTagNames(tags ...string) error {
+	for _, tag := range tags {
+		if tag == "" {
+			return errors.New("tags must have a name")
+		}
+
+		if docshelf.IsPin(tag) {
+			return errors.New("pins can not be renamed or merged")
+		}
+	}
+
+	return nil
+}
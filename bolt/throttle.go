@@ -0,0 +1,92 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// GetLoginAttempts fetches the docshelf LoginAttempts for a key from boltdb.
+func (s Store) GetLoginAttempts(ctx context.Context, key string) (docshelf.LoginAttempts, error) {
+	var attempts docshelf.LoginAttempts
+
+	if err := s.fetchItem(ctx, attemptBucket, key, &attempts); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return attempts, err
+		}
+
+		return attempts, errors.Wrap(err, "failed to fetch login attempts from bolt")
+	}
+
+	return attempts, nil
+}
+
+// UpdateLoginAttempts applies an update to the docshelf LoginAttempts for a key in boltdb. Keys without any
+// attempts yet start out empty. Bolt only allows a single writer, so the update is atomic.
+func (s Store) UpdateLoginAttempts(ctx context.Context, key string, update func(docshelf.LoginAttempts) docshelf.LoginAttempts) (docshelf.LoginAttempts, error) {
+	attempts := docshelf.LoginAttempts{Key: key}
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := s.getItem(ctx, tx, attemptBucket, key, &attempts); err != nil && !docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		attempts = update(attempts)
+		attempts.Key = key
+		return s.putItem(ctx, tx, attemptBucket, key, attempts)
+	}); err != nil {
+		return attempts, errors.Wrap(err, "failed to update login attempts in bolt")
+	}
+
+	return attempts, nil
+}
+
+// RemoveLoginAttempts clears the docshelf LoginAttempts for a key from boltdb.
+func (s Store) RemoveLoginAttempts(ctx context.Context, key string) error {
+	return errors.Wrap(s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(attemptBucket).Delete([]byte(key))
+	}), "failed to remove login attempts from bolt")
+}
+
+// PutAuditEvent records a new docshelf AuditEvent in boltdb.
+func (s Store) PutAuditEvent(ctx context.Context, event docshelf.AuditEvent) error {
+	if event.ID == "" {
+		event.ID = xid.New().String()
+	}
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	return errors.Wrap(s.storeItem(ctx, auditBucket, event.ID, event), "failed to put audit event into bolt")
+}
+
+// ListAuditEvents returns the docshelf AuditEvents recorded in boltdb since the given time, oldest first.
+func (s Store) ListAuditEvents(ctx context.Context, since time.Time) ([]docshelf.AuditEvent, error) {
+	events := make([]docshelf.AuditEvent, 0)
+
+	// xids sort by time, so events are already in order
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(auditBucket).ForEach(func(k, v []byte) error {
+			var event docshelf.AuditEvent
+			if err := json.Unmarshal(v, &event); err != nil {
+				return err
+			}
+
+			if !event.CreatedAt.Before(since) {
+				events = append(events, event)
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list audit events from bolt")
+	}
+
+	return events, nil
+}
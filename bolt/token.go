@@ -0,0 +1,75 @@
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// GetToken fetches an existing docshelf APIToken from boltdb.
+func (s Store) GetToken(ctx context.Context, id string) (docshelf.APIToken, error) {
+	var token docshelf.APIToken
+
+	if err := s.fetchItem(ctx, tokenBucket, id, &token); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return token, err
+		}
+
+		return token, errors.Wrap(err, "failed to fetch token from bolt")
+	}
+
+	return token, nil
+}
+
+// ListTokens returns all docshelf APITokens belonging to a User in boltdb.
+func (s Store) ListTokens(ctx context.Context, userID string) ([]docshelf.APIToken, error) {
+	tokens := make([]docshelf.APIToken, 0)
+
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tokenBucket).ForEach(func(k, v []byte) error {
+			var token docshelf.APIToken
+			if err := json.Unmarshal(v, &token); err != nil {
+				return err
+			}
+
+			if token.UserID == userID {
+				tokens = append(tokens, token)
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list tokens from bolt")
+	}
+
+	return tokens, nil
+}
+
+// PutToken creates a new docshelf APIToken or updates an existing one in boltdb.
+func (s Store) PutToken(ctx context.Context, token docshelf.APIToken) (string, error) {
+	if token.ID == "" {
+		token.ID = xid.New().String()
+	}
+
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	if err := s.storeItem(ctx, tokenBucket, token.ID, token); err != nil {
+		return "", errors.Wrap(err, "failed to put token into bolt")
+	}
+
+	return token.ID, nil
+}
+
+// RemoveToken deletes a docshelf APIToken from boltdb so it can't be used anymore.
+func (s Store) RemoveToken(ctx context.Context, id string) error {
+	return errors.Wrap(s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(tokenBucket).Delete([]byte(id))
+	}), "failed to remove token from bolt")
+}
@@ -0,0 +1,174 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+)
+
+// ListTrash returns the metadata of every docshelf Doc currently in the trash in bolt.
+func (s Store) ListTrash(ctx context.Context) ([]docshelf.Doc, error) {
+	docs := make([]docshelf.Doc, 0)
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(trashBucket).ForEach(func(k, v []byte) error {
+			var doc docshelf.Doc
+			if err := json.Unmarshal(v, &doc); err != nil {
+				return err
+			}
+
+			doc.Content = ""
+			docs = append(docs, doc)
+			return nil
+		})
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list trash from bolt")
+	}
+
+	return docs, nil
+}
+
+// RestoreDoc takes a docshelf Doc out of the trash and puts it back at its original path with its original
+// ID, content and tags. Restoring fails if another doc has since been created at the same path.
+func (s Store) RestoreDoc(ctx context.Context, id string) error {
+	var doc docshelf.Doc
+	if err := s.fetchItem(ctx, trashBucket, id, &doc); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		return errors.Wrap(err, "failed to fetch doc from trash")
+	}
+
+	// need to check the destination up front so existing content is never overwritten
+	if err := s.fetchItem(ctx, docBucket, doc.Path, &docshelf.Doc{}); err == nil {
+		return errors.Errorf("a doc already exists at %s", doc.Path)
+	} else if !docshelf.CheckNotFound(err) {
+		return errors.Wrap(err, "could not verify existing file")
+	}
+
+	if err := s.fs.WriteFile(doc.Path, []byte(doc.Content)); err != nil {
+		return errors.Wrap(err, "failed to write restored doc to file store")
+	}
+
+	restored := doc
+	restored.Content = ""
+	restored.DeletedAt = nil
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(docBucket).Get([]byte(doc.Path)) != nil {
+			return errors.Errorf("a doc already exists at %s", doc.Path)
+		}
+
+		if err := s.putItem(ctx, tx, docBucket, doc.Path, restored); err != nil {
+			return err
+		}
+
+		if err := reorderDoc(tx, docshelf.Doc{}, restored); err != nil {
+			return err
+		}
+
+		if err := s.putItem(ctx, tx, docIDBucket, doc.ID, doc.Path); err != nil {
+			return err
+		}
+
+		return tx.Bucket(trashBucket).Delete([]byte(doc.ID))
+	}); err != nil {
+		if err := s.fs.RemoveFile(doc.Path); err != nil { // need to rollback file storage if restore fails
+			return errors.Wrap(err, "failed to cleanup file after bolt failure")
+		}
+
+		return errors.Wrap(err, "failed to restore doc from trash in bolt")
+	}
+
+	if err := s.tagPath(ctx, doc.Path, doc.Tags...); err != nil {
+		return errors.Wrap(err, "failed to restore doc tags")
+	}
+
+	restored.Content = doc.Content
+	return errors.Wrap(s.indexDoc(ctx, restored), "failed to text index restored doc")
+}
+
+// PurgeDoc permanently removes a docshelf Doc and its revision history from the trash in bolt.
+func (s Store) PurgeDoc(ctx context.Context, id string) error {
+	if err := s.fetchItem(ctx, trashBucket, id, &docshelf.Doc{}); err != nil {
+		if docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		return errors.Wrap(err, "failed to fetch doc from trash")
+	}
+
+	return errors.Wrap(s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(trashBucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+
+		return s.purgeRevisions(ctx, tx, id)
+	}), "failed to purge doc from bolt")
+}
+
+// PurgeTrash permanently removes every docshelf Doc that was put in the trash before the given time. The
+// number of purged docs is returned.
+func (s Store) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	var purged int
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(trashBucket)
+		var expired []string
+		if err := b.ForEach(func(k, v []byte) error {
+			var doc docshelf.Doc
+			if err := json.Unmarshal(v, &doc); err != nil {
+				return err
+			}
+
+			if doc.DeletedAt != nil && doc.DeletedAt.Before(before) {
+				expired = append(expired, doc.ID)
+			}
+
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// buckets can't be modified while iterating over them, so deletes are applied afterwards
+		for _, id := range expired {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+
+			if err := s.purgeRevisions(ctx, tx, id); err != nil {
+				return err
+			}
+		}
+
+		purged = len(expired)
+		return nil
+	}); err != nil {
+		return 0, errors.Wrap(err, "failed to purge trash from bolt")
+	}
+
+	return purged, nil
+}
+
+func (s Store) purgeRevisions(ctx context.Context, tx *bolt.Tx, docID string) error {
+	b := tx.Bucket(revisionBucket)
+	prefix := []byte(revisionKey(docID, ""))
+
+	var keys [][]byte
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, k)
+	}
+
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package bolt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+)
+
+// ListTree returns the immediate children of a folder path from bolt. Docs nested more deeply are collapsed
+// into the folders containing them. An empty path lists the root of the shelf.
+func (s Store) ListTree(ctx context.Context, path string) ([]docshelf.Doc, error) {
+	var nested []docshelf.Doc
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		nested, err = s.listPrefix(ctx, tx, docshelf.DirPrefix(path))
+		return err
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to list tree from bolt")
+	}
+
+	return docshelf.TreeChildren(path, nested), nil
+}
+
+// RemoveDir recursively removes a folder and every docshelf Doc nested under it from bolt. Nothing is removed
+// if any nested doc is locked by someone other than the given user.
+func (s Store) RemoveDir(ctx context.Context, path, userID string) error {
+	prefix := docshelf.DirPrefix(path)
+	if prefix == "" {
+		return errors.New("can not remove the root folder")
+	}
+
+	var dir docshelf.Doc
+	var nested []docshelf.Doc
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		if err := s.getItem(ctx, tx, docBucket, prefix[:len(prefix)-1], &dir); err != nil && !docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		var err error
+		nested, err = s.listPrefix(ctx, tx, prefix)
+		if err != nil {
+			return errors.Wrap(err, "failed to list folder contents from bolt")
+		}
+
+		for _, doc := range nested {
+			lock, err := getLock(tx, doc.ID)
+			if err != nil {
+				return err
+			}
+
+			if lock.Blocks(userID) {
+				return docshelf.NewErrLocked(fmt.Sprintf("%s is locked by another user", doc.Path))
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// docs have to go through the trash, so only folders can be removed here
+	if dir.ID != "" && !dir.IsDir {
+		return docshelf.NewErrBadQuery("path is not a folder")
+	}
+
+	dirs := []string{prefix[:len(prefix)-1]}
+	for _, doc := range nested {
+		if doc.IsDir {
+			dirs = append(dirs, doc.Path)
+			continue
+		}
+
+		if err := s.RemoveDoc(ctx, doc.Path, userID); err != nil {
+			return errors.Wrapf(err, "failed to remove nested doc: %s", doc.Path)
+		}
+	}
+
+	return errors.Wrap(s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(docBucket)
+		for _, dir := range dirs {
+			var doc docshelf.Doc
+			if err := s.getItem(ctx, tx, docBucket, dir, &doc); err != nil {
+				continue // implied folders were never stored
+			}
+
+			if err := tx.Bucket(docIDBucket).Delete([]byte(doc.ID)); err != nil {
+				return err
+			}
+
+			if err := reorderDoc(tx, doc, docshelf.Doc{}); err != nil {
+				return err
+			}
+
+			if err := b.Delete([]byte(dir)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}), "failed to remove folder from bolt")
+}
+
+// putDir stores a folder as long as the stored version still matches the expected one. Folders only exist
+// as metadata, so nothing is written to the FileStore or text index.
+func (s Store) putDir(ctx context.Context, dir docshelf.Doc, expected int) (string, error) {
+	dir.Content = ""
+	dir.Message = ""
+
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := checkVersion(tx, dir.Path, expected); err != nil {
+			return err
+		}
+
+		previous, err := storedDoc(tx, dir.Path)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal folder from bolt")
+		}
+
+		if err := s.putItem(ctx, tx, docBucket, dir.Path, dir); err != nil {
+			return errors.Wrap(err, "failed to put folder into bolt")
+		}
+
+		if err := reorderDoc(tx, previous, dir); err != nil {
+			return errors.Wrap(err, "failed to save folder sort order in bolt")
+		}
+
+		return errors.Wrap(s.putItem(ctx, tx, docIDBucket, dir.ID, dir.Path), "failed to save folder secondary index in bolt")
+	}); err != nil {
+		return "", err
+	}
+
+	return dir.ID, nil
+}
+
+// listPrefix returns every doc whose path starts with the given prefix, sorted by path.
+func (s Store) listPrefix(ctx context.Context, tx *bolt.Tx, prefix string) ([]docshelf.Doc, error) {
+	docs := make([]docshelf.Doc, 0)
+	c := tx.Bucket(docBucket).Cursor()
+	for k, v := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, v = c.Next() {
+		var doc docshelf.Doc
+		if err := json.Unmarshal(v, &doc); err != nil {
+			return nil, err
+		}
+
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
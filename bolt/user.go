@@ -75,7 +75,8 @@ func (s Store) ListUsers(ctx context.Context) ([]docshelf.User, error) {
 	return users, nil
 }
 
-// PutUser creates a new docshelf User or updates an existing one in boltdb.
+// PutUser creates a new docshelf User or updates an existing one in boltdb. Group membership is managed
+// through the GroupStore, so the User's Groups are left untouched.
 func (s Store) PutUser(ctx context.Context, user docshelf.User) (string, error) {
 	if user.ID == "" {
 		if user.Email == "" {
@@ -89,6 +90,13 @@ func (s Store) PutUser(ctx context.Context, user docshelf.User) (string, error)
 	user.UpdatedAt = time.Now()
 
 	if err := s.db.Update(func(tx *bolt.Tx) error {
+		// group membership is managed through groups so both sides stay in sync
+		var existing docshelf.User
+		if err := s.getItem(ctx, tx, userBucket, user.ID, &existing); err != nil && !docshelf.CheckNotFound(err) {
+			return err
+		}
+
+		user.Groups = existing.Groups
 		if err := s.putItem(ctx, tx, userBucket, user.ID, user); err != nil {
 			return errors.Wrap(err, "failed to put user into bolt")
 		}
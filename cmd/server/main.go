@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/docshelf/docshelf"
 	"github.com/docshelf/docshelf/auth"
@@ -12,12 +15,12 @@ import (
 	"github.com/docshelf/docshelf/disk"
 	"github.com/docshelf/docshelf/dynamo"
 	"github.com/docshelf/docshelf/http"
+	"github.com/docshelf/docshelf/notify"
 	"github.com/docshelf/docshelf/s3"
 	"github.com/joho/godotenv"
 	"github.com/pkg/errors"
 	"github.com/rs/xid"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var log *logrus.Logger
@@ -33,6 +36,27 @@ type Config struct {
 	Host        string
 	Port        uint
 
+	// TrashRetention is how long removed docs are kept before being purged for good.
+	TrashRetention time.Duration
+
+	// Sessions
+	SessionSecret string
+	SessionTTL    time.Duration
+
+	// Login throttling
+	LoginMaxFailures uint
+	LoginLockout     time.Duration
+	TrustedProxies   []string
+
+	// Password resets
+	Notifier     string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+	ResetTTL     time.Duration
+	ResetURL     string
+
 	// Github auth
 	GithubClientID string
 	GithubSecret   string
@@ -40,22 +64,63 @@ type Config struct {
 	// Google auth
 	GoogleClientID string
 	GoogleSecret   string
+
+	// OpenID Connect auth
+	OIDCIssuer      string
+	OIDCClientID    string
+	OIDCSecret      string
+	OIDCRedirectURL string
+
+	// LDAP auth
+	LDAPURL          string
+	LDAPBindDN       string
+	LDAPBindPassword string
+	LDAPBaseDN       string
+	LDAPUserFilter   string
+	LDAPGroupBaseDN  string
+	LDAPGroupFilter  string
+	LDAPGroups       map[string]string
 }
 
 func configFromEnv() Config {
 	return Config{
-		Backend:        getEnvString("DS_BACKEND", "bolt"),
-		FileBackend:    getEnvString("DS_FILE_BACKEND", "disk"),
-		TextIndex:      getEnvString("DS_TEXT_INDEX", "bleve"),
-		S3Bucket:       getEnvString("DS_S3_BUCKET", ""),
-		FilePrefix:     getEnvString("DS_FILE_PREFIX", "documents"),
-		BoltPath:       getEnvString("DS_BOLTDB_PATH", "docshelf.db"),
-		Host:           getEnvString("DS_HOST", "localhost"),
-		Port:           getEnvUint("DS_PORT", 1337),
-		GithubClientID: getEnvString("DS_GITHUB_CLIENT_ID", ""),
-		GithubSecret:   getEnvString("DS_GITHUB_CLIENT_SECRET", ""),
-		GoogleClientID: getEnvString("DS_GOOGLE_CLIENT_ID", ""),
-		GoogleSecret:   getEnvString("DS_GOOGLE_CLIENT_SECRET", ""),
+		Backend:          getEnvString("DS_BACKEND", "bolt"),
+		FileBackend:      getEnvString("DS_FILE_BACKEND", "disk"),
+		TextIndex:        getEnvString("DS_TEXT_INDEX", "bleve"),
+		S3Bucket:         getEnvString("DS_S3_BUCKET", ""),
+		FilePrefix:       getEnvString("DS_FILE_PREFIX", "documents"),
+		BoltPath:         getEnvString("DS_BOLTDB_PATH", "docshelf.db"),
+		Host:             getEnvString("DS_HOST", "localhost"),
+		Port:             getEnvUint("DS_PORT", 1337),
+		TrashRetention:   getEnvDuration("DS_TRASH_RETENTION", 30*24*time.Hour),
+		SessionSecret:    getEnvString("DS_SESSION_SECRET", ""),
+		SessionTTL:       getEnvDuration("DS_SESSION_TTL", 24*time.Hour),
+		LoginMaxFailures: getEnvUint("DS_LOGIN_MAX_FAILURES", uint(auth.DefaultAccountPolicy.LockoutAfter)),
+		LoginLockout:     getEnvDuration("DS_LOGIN_LOCKOUT", auth.DefaultAccountPolicy.Lockout),
+		TrustedProxies:   getEnvList("DS_TRUSTED_PROXIES"),
+		Notifier:         getEnvString("DS_NOTIFIER", "log"),
+		SMTPAddr:         getEnvString("DS_SMTP_ADDR", ""),
+		SMTPUsername:     getEnvString("DS_SMTP_USERNAME", ""),
+		SMTPPassword:     getEnvString("DS_SMTP_PASSWORD", ""),
+		SMTPFrom:         getEnvString("DS_SMTP_FROM", ""),
+		ResetTTL:         getEnvDuration("DS_RESET_TTL", time.Hour),
+		ResetURL:         getEnvString("DS_RESET_URL", "http://localhost:9001/reset"),
+		GithubClientID:   getEnvString("DS_GITHUB_CLIENT_ID", ""),
+		GithubSecret:     getEnvString("DS_GITHUB_CLIENT_SECRET", ""),
+		GoogleClientID:   getEnvString("DS_GOOGLE_CLIENT_ID", ""),
+		GoogleSecret:     getEnvString("DS_GOOGLE_CLIENT_SECRET", ""),
+		OIDCIssuer:       getEnvString("DS_OIDC_ISSUER", ""),
+		OIDCClientID:     getEnvString("DS_OIDC_CLIENT_ID", ""),
+		OIDCSecret:       getEnvString("DS_OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnvString("DS_OIDC_REDIRECT_URL", ""),
+		LDAPURL:          getEnvString("DS_LDAP_URL", ""),
+		LDAPBindDN:       getEnvString("DS_LDAP_BIND_DN", ""),
+		LDAPBindPassword: getEnvString("DS_LDAP_BIND_PASSWORD", ""),
+		LDAPBaseDN:       getEnvString("DS_LDAP_BASE_DN", ""),
+		LDAPUserFilter:   getEnvString("DS_LDAP_USER_FILTER", ""),
+		LDAPGroupBaseDN:  getEnvString("DS_LDAP_GROUP_BASE_DN", ""),
+		LDAPGroupFilter:  getEnvString("DS_LDAP_GROUP_FILTER", ""),
+		LDAPGroups:       getEnvMap("DS_LDAP_GROUPS"),
 	}
 }
 
@@ -80,16 +145,72 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// the index can only look up authors once the backend holding users exists
+	if idx, ok := ti.(bleve.Index); ok {
+		idx.UseUserStore(backend)
+	}
+
 	// make sure there's a root user
 	if err := ensureRoot(backend, log); err != nil {
 		log.Fatal(err)
 	}
 
+	secret, err := getSessionSecret(cfg, log)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	notifier, err := getNotifier(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	proxies, err := http.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go purgeTrash(backend, cfg.TrashRetention, log)
+	go purgeSessions(backend, log)
+
 	server.UserStore = backend
-	server.DocHandler = http.NewDocHandler(backend, log)
+	server.TokenStore = backend
+	server.GroupStore = backend
+	server.PolicyStore = backend
+	server.SnapshotStore = backend
+	server.Sessions = auth.NewSessions(backend, secret, cfg.SessionTTL)
+	accountPolicy := auth.DefaultAccountPolicy
+	accountPolicy.LockoutAfter = int(cfg.LoginMaxFailures)
+	accountPolicy.Lockout = cfg.LoginLockout
+
+	throttle := auth.NewThrottle(backend, backend, accountPolicy, auth.DefaultIPPolicy)
+	server.Throttle = throttle
+	server.ResetThrottle = throttle.Scoped("reset")
+	server.AuditStore = backend
+	server.TrustedProxies = proxies
+	server.Passwords = auth.NewPasswords(backend, backend, backend, backend, notifier, cfg.ResetTTL, cfg.ResetURL)
+	server.DocHandler = http.NewDocHandler(backend, backend, log)
+	server.PolicyHandler = http.NewPolicyHandler(backend, backend, backend, log)
+	server.TagHandler = http.NewTagHandler(backend, backend, log)
 	server.AddAuth("basic", auth.NewBasic(backend))
 	server.AddAuth("github", auth.NewGithub(backend, cfg.GithubClientID, cfg.GithubSecret))
 	server.AddAuth("google", auth.NewGoogle(backend, cfg.GoogleClientID, cfg.GoogleSecret))
+	if cfg.OIDCIssuer != "" {
+		server.AddAuth("oidc", auth.NewOIDC(backend, cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCSecret, cfg.OIDCRedirectURL))
+	}
+
+	if cfg.LDAPURL != "" {
+		server.AddAuth("ldap", auth.NewLDAP(backend, backend, auth.LDAPConfig{
+			URL:          cfg.LDAPURL,
+			BindDN:       cfg.LDAPBindDN,
+			BindPassword: cfg.LDAPBindPassword,
+			BaseDN:       cfg.LDAPBaseDN,
+			UserFilter:   cfg.LDAPUserFilter,
+			GroupBaseDN:  cfg.LDAPGroupBaseDN,
+			GroupFilter:  cfg.LDAPGroupFilter,
+			GroupMapping: cfg.LDAPGroups,
+		}))
+	}
 
 	if err := server.Start(); err != nil {
 		log.Fatal(err)
@@ -98,18 +219,19 @@ func main() {
 
 func ensureRoot(us docshelf.UserStore, log *logrus.Logger) error {
 	token := xid.New().String()
-	// TODO (erik): Adjust the cost parameter once we can benchmark the time spent hashing the password.
-	hashed, err := bcrypt.GenerateFromPassword([]byte(token), 12)
+	hashed, err := auth.HashPassword(token)
 	if err != nil {
 		return err
 	}
 
 	root := docshelf.User{
 		Email: "root@docshelf.io",
-		Token: string(hashed),
+		Token: hashed,
+		Role:  docshelf.RoleAdmin,
 	}
 
-	if _, err := us.GetUser(context.Background(), "root@docshelf.io"); err != nil {
+	existing, err := us.GetUser(context.Background(), "root@docshelf.io")
+	if err != nil {
 		if docshelf.CheckNotFound(err) {
 			if _, err := us.PutUser(context.Background(), root); err != nil {
 				return err
@@ -122,9 +244,63 @@ func ensureRoot(us docshelf.UserStore, log *logrus.Logger) error {
 		return errors.Wrap(err, "failed to identify root user")
 	}
 
+	// root users created before roles existed need to be promoted
+	if existing.Role != docshelf.RoleAdmin {
+		existing.Role = docshelf.RoleAdmin
+		if _, err := us.PutUser(context.Background(), existing); err != nil {
+			return errors.Wrap(err, "failed to promote root user")
+		}
+	}
+
 	return nil
 }
 
+// purgeTrash periodically removes docs that have been in the trash for longer than the retention period.
+func purgeTrash(ds docshelf.DocStore, retention time.Duration, log *logrus.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		purged, err := ds.PurgeTrash(context.Background(), time.Now().Add(-retention))
+		if err != nil {
+			log.WithError(err).Error("failed to purge trash")
+			continue
+		}
+
+		if purged > 0 {
+			log.WithField("count", purged).Info("purged expired docs from trash")
+		}
+	}
+}
+
+// purgeSessions periodically removes sessions that have expired.
+func purgeSessions(ss docshelf.SessionStore, log *logrus.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for ; true; <-ticker.C {
+		if _, err := ss.PurgeSessions(context.Background(), time.Now()); err != nil {
+			log.WithError(err).Error("failed to purge sessions")
+		}
+	}
+}
+
+// getSessionSecret returns the key session tokens are signed with. Without a configured secret a random one
+// is generated, which means sessions won't survive a restart.
+func getSessionSecret(cfg Config, log *logrus.Logger) ([]byte, error) {
+	if cfg.SessionSecret != "" {
+		return []byte(cfg.SessionSecret), nil
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, errors.Wrap(err, "failed to generate session secret")
+	}
+
+	log.Warn("no DS_SESSION_SECRET set, using a random one. Sessions will be invalidated on restart")
+	return secret, nil
+}
+
 func getFileStore(cfg Config) (docshelf.FileStore, error) {
 	switch cfg.FileBackend {
 	case "s3":
@@ -166,6 +342,24 @@ func getBackend(cfg Config, fs docshelf.FileStore, ti docshelf.TextIndex) (docsh
 	}
 }
 
+func getNotifier(cfg Config) (docshelf.Notifier, error) {
+	switch cfg.Notifier {
+	case "smtp":
+		if cfg.SMTPAddr == "" {
+			return nil, errors.New("DS_SMTP_ADDR is required for the smtp notifier")
+		}
+
+		notifier, err := notify.NewSMTP(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create smtp notifier")
+		}
+
+		return notifier, nil
+	default:
+		return notify.NewLog(log), nil
+	}
+}
+
 func getTextIndex(cfg Config) (docshelf.TextIndex, error) {
 	switch cfg.TextIndex {
 	default:
@@ -194,3 +388,39 @@ func getEnvUint(key string, def uint) uint {
 
 	return uint(val)
 }
+
+func getEnvDuration(key string, def time.Duration) time.Duration {
+	val, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+
+	return val
+}
+
+// getEnvList parses a comma separated list of values.
+func getEnvList(key string) []string {
+	var vals []string
+	for _, val := range strings.Split(os.Getenv(key), ",") {
+		if val = strings.TrimSpace(val); val != "" {
+			vals = append(vals, val)
+		}
+	}
+
+	return vals
+}
+
+// getEnvMap parses a comma separated list of key=value pairs.
+func getEnvMap(key string) map[string]string {
+	vals := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			continue
+		}
+
+		vals[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	return vals
+}
@@ -0,0 +1,257 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines kept around each change when grouping hunks.
+const DefaultContext = 3
+
+// An Op describes what happened to a single line between two versions of a document.
+type Op string
+
+// Op enum values
+const (
+	OpUnchanged = Op("unchanged")
+	OpAdded     = Op("added")
+	OpRemoved   = Op("removed")
+)
+
+// A Line is a single line of a diff. OldLine and NewLine are the 1-based line numbers of the line in the old
+// and new versions respectively. Added lines have no OldLine and removed lines have no NewLine.
+type Line struct {
+	Op      Op     `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"oldLine,omitempty"`
+	NewLine int    `json:"newLine,omitempty"`
+}
+
+// A Hunk is a contiguous group of changed lines along with their surrounding context.
+type Hunk struct {
+	OldStart int    `json:"oldStart"`
+	OldLines int    `json:"oldLines"`
+	NewStart int    `json:"newStart"`
+	NewLines int    `json:"newLines"`
+	Lines    []Line `json:"lines"`
+}
+
+// Compare diffs the old and new content line by line and groups the result into hunks using DefaultContext.
+func Compare(old, new string) []Hunk {
+	return Group(Lines(old, new), DefaultContext)
+}
+
+// Lines returns the full line by line edit script that transforms old into new. It uses Myers' algorithm,
+// so the script is always as short as possible.
+func Lines(old, new string) []Line {
+	a := split(old)
+	b := split(new)
+	trace := shortestEdit(a, b)
+
+	// walk the trace backwards to recover the edit script
+	lines := make([]Line, 0, len(a)+len(b))
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		offset := d + 1
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			lines = append(lines, Line{Op: OpUnchanged, Text: a[x-1], OldLine: x, NewLine: y})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				lines = append(lines, Line{Op: OpAdded, Text: b[y-1], NewLine: y})
+			} else {
+				lines = append(lines, Line{Op: OpRemoved, Text: a[x-1], OldLine: x})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	// edits were collected from the end of the document, so they need to be flipped
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+
+	return lines
+}
+
+// Group collects an edit script into hunks, keeping up to context unchanged lines around every change.
+// Changes that are close enough for their context to overlap are merged into a single hunk.
+func Group(lines []Line, context int) []Hunk {
+	hunks := make([]Hunk, 0)
+
+	first, last := -1, -1
+	for i, line := range lines {
+		if line.Op == OpUnchanged {
+			continue
+		}
+
+		start := max(i-context, 0)
+		if first >= 0 && start > last+context+1 {
+			// context doesn't overlap with the current hunk, so it's finished
+			hunks = append(hunks, newHunk(lines, first, min(last+context+1, len(lines))))
+			first = -1
+		}
+
+		if first < 0 {
+			first = start
+		}
+
+		last = i
+	}
+
+	if first >= 0 {
+		hunks = append(hunks, newHunk(lines, first, min(last+context+1, len(lines))))
+	}
+
+	return hunks
+}
+
+// Unified renders hunks as a unified diff between the named old and new documents.
+func Unified(oldName, newName string, hunks []Hunk) string {
+	buf := bytes.NewBuffer(make([]byte, 0, 1024))
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range hunks {
+		fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(hunk.OldStart, hunk.OldLines), hunkRange(hunk.NewStart, hunk.NewLines))
+		for _, line := range hunk.Lines {
+			switch line.Op {
+			case OpAdded:
+				buf.WriteString("+")
+			case OpRemoved:
+				buf.WriteString("-")
+			default:
+				buf.WriteString(" ")
+			}
+
+			buf.WriteString(line.Text)
+			buf.WriteString("\n")
+		}
+	}
+
+	return buf.String()
+}
+
+// newHunk builds a hunk out of lines[start:end] and computes the line ranges it covers. When a side of
+// the hunk has no lines, its start points at the line preceding the hunk, which is how unified diffs
+// represent pure insertions and deletions.
+func newHunk(lines []Line, start, end int) Hunk {
+	hunk := Hunk{Lines: append([]Line{}, lines[start:end]...)}
+	for _, line := range lines[:start] {
+		if line.Op != OpAdded {
+			hunk.OldStart++
+		}
+
+		if line.Op != OpRemoved {
+			hunk.NewStart++
+		}
+	}
+
+	for _, line := range hunk.Lines {
+		if line.Op != OpAdded {
+			hunk.OldLines++
+		}
+
+		if line.Op != OpRemoved {
+			hunk.NewLines++
+		}
+	}
+
+	if hunk.OldLines > 0 {
+		hunk.OldStart++
+	}
+
+	if hunk.NewLines > 0 {
+		hunk.NewStart++
+	}
+
+	return hunk
+}
+
+// shortestEdit runs the forward pass of Myers' algorithm, returning a copy of the frontier for every edit
+// distance explored so the edit script can be recovered afterwards. Only the diagonals reachable at each
+// distance are copied, so the trace for distance d is indexed with an offset of d+1.
+func shortestEdit(a, b []string) [][]int {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	trace := make([][]int, 0)
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int{}, v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return trace
+			}
+		}
+	}
+
+	return trace
+}
+
+// split breaks content into lines. A single trailing newline doesn't count as an extra empty line.
+func split(content string) []string {
+	if content == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,101 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/docshelf/docshelf/diff"
+)
+
+func Test_Lines(t *testing.T) {
+	// SETUP
+	old := "one\ntwo\nthree\n"
+	new := "one\nthree\nfour\n"
+
+	// RUN
+	lines := diff.Lines(old, new)
+
+	// ASSERT
+	expected := []diff.Line{
+		{Op: diff.OpUnchanged, Text: "one", OldLine: 1, NewLine: 1},
+		{Op: diff.OpRemoved, Text: "two", OldLine: 2},
+		{Op: diff.OpUnchanged, Text: "three", OldLine: 3, NewLine: 2},
+		{Op: diff.OpAdded, Text: "four", NewLine: 3},
+	}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d", len(expected), len(lines))
+	}
+
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Fatalf("line %d: expected %+v, got %+v", i, expected[i], lines[i])
+		}
+	}
+}
+
+func Test_LinesEmpty(t *testing.T) {
+	// RUN
+	added := diff.Lines("", "one\ntwo")
+	removed := diff.Lines("one\ntwo", "")
+	same := diff.Compare("one\ntwo", "one\ntwo")
+
+	// ASSERT
+	if len(added) != 2 || added[0].Op != diff.OpAdded || added[1].Op != diff.OpAdded {
+		t.Fatal("expected every line to be added")
+	}
+
+	if len(removed) != 2 || removed[0].Op != diff.OpRemoved || removed[1].Op != diff.OpRemoved {
+		t.Fatal("expected every line to be removed")
+	}
+
+	if len(same) != 0 {
+		t.Fatal("identical content shouldn't produce hunks")
+	}
+}
+
+func Test_Group(t *testing.T) {
+	// SETUP
+	old := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	new := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nK\nl\n"
+
+	// RUN
+	hunks := diff.Compare(old, new)
+	merged := diff.Group(diff.Lines(old, new), 5)
+
+	// ASSERT
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(hunks))
+	}
+
+	if hunks[0].OldStart != 1 || hunks[0].OldLines != 5 || hunks[0].NewStart != 1 || hunks[0].NewLines != 5 {
+		t.Fatalf("unexpected range for first hunk: %+v", hunks[0])
+	}
+
+	if hunks[1].OldStart != 8 || hunks[1].OldLines != 5 || hunks[1].NewStart != 8 || hunks[1].NewLines != 5 {
+		t.Fatalf("unexpected range for second hunk: %+v", hunks[1])
+	}
+
+	if len(merged) != 1 {
+		t.Fatal("overlapping context should merge hunks")
+	}
+}
+
+func Test_Unified(t *testing.T) {
+	// SETUP
+	old := "one\ntwo\nthree\n"
+	new := "one\nthree\nfour\n"
+
+	// RUN
+	unified := diff.Unified("a/test.md", "b/test.md", diff.Compare(old, new))
+	inserted := diff.Unified("a/test.md", "b/test.md", diff.Compare("", "one\n"))
+
+	// ASSERT
+	expected := "--- a/test.md\n+++ b/test.md\n@@ -1,3 +1,3 @@\n one\n-two\n three\n+four\n"
+	if unified != expected {
+		t.Fatalf("unexpected unified diff:\n%s", unified)
+	}
+
+	if inserted != "--- a/test.md\n+++ b/test.md\n@@ -0,0 +1 @@\n+one\n" {
+		t.Fatalf("unexpected unified diff for insertion:\n%s", inserted)
+	}
+}
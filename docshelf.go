@@ -2,6 +2,7 @@ package docshelf
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
@@ -11,6 +12,7 @@ type User struct {
 	Email     string     `json:"email"`
 	Name      string     `json:"name"`
 	Token     string     `json:"token"`
+	Role      Role       `json:"role,omitempty"`
 	Groups    []string   `json:"groups"`
 	Pinned    []string   `json:"pinned"`
 	CreatedAt time.Time  `json:"createdAt"`
@@ -18,7 +20,43 @@ type User struct {
 	DeletedAt *time.Time `json:"deletedAt"`
 }
 
-// A Group is collection of Users for the purposes of granting access.
+// A Role determines what a User is allowed to do across all of docshelf. Users without a Role are treated
+// as editors.
+type Role string
+
+// Role enum values
+const (
+	RoleViewer = Role("viewer")
+	RoleEditor = Role("editor")
+	RoleAdmin  = Role("admin")
+)
+
+// Valid reports whether the Role is one docshelf knows about. An empty Role is valid and means editor.
+func (r Role) Valid() bool {
+	return r.rank() > 0
+}
+
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleEditor, "":
+		return 2
+	case RoleAdmin:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// HasRole reports whether a User has at least the given Role. Admins can do everything editors can, and
+// editors everything viewers can.
+func (u User) HasRole(role Role) bool {
+	return u.Role.rank() >= role.rank()
+}
+
+// A Group is collection of Users for the purposes of granting access. Membership is recorded on both sides,
+// so every User in a Group also lists the Group in their Groups.
 type Group struct {
 	ID        string    `json:"id"`
 	Name      string    `json:"name"`
@@ -29,17 +67,91 @@ type Group struct {
 
 // A Doc is a full docshelf document. This includes metadata as well as content.
 type Doc struct {
+	ID        string     `json:"id"`
+	Path      string     `json:"path"`
+	Title     string     `json:"title"`
+	IsDir     bool       `json:"isDir"`
+	Content   string     `json:"content,omitempty"`
+	Policy    *Policy    `json:"policy"`
+	Tags      []string   `json:"tags"`
+	Message   string     `json:"message,omitempty"`
+	Version   int        `json:"version"`
+	Lock      *Lock      `json:"lock,omitempty"`
+	CreatedBy string     `json:"createdBy"`
+	UpdatedBy string     `json:"updatedBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+// A TagCount is a tag along with the number of Docs that have it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// A SearchHit is a Doc that matched a search, along with how well it matched and why. Fragments holds
+// snippets of each matched field with the matching terms wrapped in <mark> tags.
+type SearchHit struct {
+	Doc       Doc                 `json:"doc"`
+	Score     float64             `json:"score"`
+	Fields    []string            `json:"fields"`
+	Fragments map[string][]string `json:"fragments,omitempty"`
+}
+
+// A Lock gives a single user exclusive write access to a Doc until it expires.
+type Lock struct {
+	DocID     string    `json:"docId"`
+	UserID    string    `json:"userId"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Active reports whether the Lock is still in effect. A nil Lock is never active.
+func (l *Lock) Active() bool {
+	return l != nil && time.Now().Before(l.ExpiresAt)
+}
+
+// Blocks reports whether the Lock prevents the given user from writing to the Doc.
+func (l *Lock) Blocks(userID string) bool {
+	return l.Active() && l.UserID != userID
+}
+
+// A Session is a login handed out to a User. Sessions expire on their own, but can also be revoked early,
+// e.g. when the User logs out.
+type Session struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"userId"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Active reports whether the Session can still be used.
+func (s Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// A Revision is an immutable record of a Doc's content at the time it was saved.
+type Revision struct {
 	ID        string    `json:"id"`
+	DocID     string    `json:"docId"`
 	Path      string    `json:"path"`
 	Title     string    `json:"title"`
-	IsDir     bool      `json:"isDir"`
 	Content   string    `json:"content,omitempty"`
-	Policy    *Policy   `json:"policy"`
-	Tags      []string  `json:"tags"`
+	Message   string    `json:"message"`
+	CreatedBy string    `json:"createdBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// A Snapshot is a point in time capture of every Doc on the shelf. Docs captured in a Snapshot carry their
+// tag membership in Tags.
+type Snapshot struct {
+	ID        string    `json:"id"`
+	DocCount  int       `json:"docCount"`
+	Docs      []Doc     `json:"docs,omitempty"`
 	CreatedBy string    `json:"createdBy"`
-	UpdatedBy string    `json:"updatedBy"`
 	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 // An Policy defines the users and groups that have access to a particular file path.
@@ -55,10 +167,33 @@ type Policy struct {
 // A DocStore knows how to store and retrieve docshelf documents.
 type DocStore interface {
 	GetDoc(ctx context.Context, path string) (Doc, error)
-	ListDocs(ctx context.Context, query string, tags ...string) ([]Doc, error)
+	ListDocs(ctx context.Context, opts ListOptions) (DocPage, error)
+	SearchDocs(ctx context.Context, query string) ([]SearchHit, error)
 	PutDoc(ctx context.Context, doc Doc) (string, error)
 	TagDoc(ctx context.Context, path string, tags ...string) error
-	RemoveDoc(ctx context.Context, path string) error
+	UntagDoc(ctx context.Context, path string, tags ...string) error
+	MoveDoc(ctx context.Context, from, to, userID string) error
+	RemoveDoc(ctx context.Context, path, userID string) error
+	ListTree(ctx context.Context, path string) ([]Doc, error)
+	RemoveDir(ctx context.Context, path, userID string) error
+	ListTrash(ctx context.Context) ([]Doc, error)
+	RestoreDoc(ctx context.Context, id string) error
+	PurgeDoc(ctx context.Context, id string) error
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+	LockDoc(ctx context.Context, path, userID string, ttl time.Duration) (Lock, error)
+	UnlockDoc(ctx context.Context, path, userID string) error
+	BreakLock(ctx context.Context, path string) error
+	ListRevisions(ctx context.Context, path string) ([]Revision, error)
+	GetRevision(ctx context.Context, path, id string) (Revision, error)
+}
+
+// A TagStore knows how to list and manage the tags applied to docshelf Docs across the whole shelf. Pins
+// belong to individual Users, so they're never listed or changed through it. Listed tags only count the
+// Docs the given func reports as readable.
+type TagStore interface {
+	ListTags(ctx context.Context, canRead func(Doc) bool) ([]TagCount, error)
+	RenameTag(ctx context.Context, from, to string) error
+	MergeTags(ctx context.Context, into string, from ...string) error
 }
 
 // A UserStore knows how to store and retrieve docshelf users.
@@ -70,18 +205,104 @@ type UserStore interface {
 	RemoveUser(ctx context.Context, id string) error
 }
 
+// A TokenStore knows how to store and retrieve the APITokens belonging to docshelf Users.
+type TokenStore interface {
+	GetToken(ctx context.Context, id string) (APIToken, error)
+	ListTokens(ctx context.Context, userID string) ([]APIToken, error)
+	PutToken(ctx context.Context, token APIToken) (string, error)
+	RemoveToken(ctx context.Context, id string) error
+}
+
 // A GroupStore knows how to store and retrieve docshelf Groups.
 type GroupStore interface {
 	GetGroup(ctx context.Context, id string) (Group, error)
+	ListGroups(ctx context.Context) ([]Group, error)
 	PutGroup(ctx context.Context, group Group) (string, error)
 	RemoveGroup(ctx context.Context, id string) error
+	AddGroupUsers(ctx context.Context, id string, userIDs ...string) error
+	RemoveGroupUsers(ctx context.Context, id string, userIDs ...string) error
 }
 
 // A PolicyStore knows how to store and retrieve docshelf Policies.
 type PolicyStore interface {
 	GetPolicy(ctx context.Context, id string) (Policy, error)
+	ListPolicies(ctx context.Context) ([]Policy, error)
 	PutPolicy(ctx context.Context, policy Policy) (string, error)
-	RemovePolicy(ctx context.Context, id string) (string, error)
+	RemovePolicy(ctx context.Context, id string) error
+}
+
+// A PathPolicyStore knows how to attach docshelf Policies to path prefixes so Docs under them can inherit
+// access rules.
+type PathPolicyStore interface {
+	PutPathPolicy(ctx context.Context, path, policyID string) error
+	RemovePathPolicy(ctx context.Context, path string) error
+	ListPathPolicies(ctx context.Context) (map[string]Policy, error)
+}
+
+// A SessionStore knows how to store and revoke docshelf Sessions.
+type SessionStore interface {
+	GetSession(ctx context.Context, id string) (Session, error)
+	PutSession(ctx context.Context, session Session) (string, error)
+	RevokeSession(ctx context.Context, id string) error
+	RevokeUserSessions(ctx context.Context, userID string) (int, error)
+	PurgeSessions(ctx context.Context, before time.Time) (int, error)
+}
+
+// A SessionManager knows how to hand out, verify and end the session tokens given to authenticated Users.
+type SessionManager interface {
+	StartSession(ctx context.Context, user User) (string, Session, error)
+	VerifySession(ctx context.Context, token string) (Session, error)
+	EndSession(ctx context.Context, token string) error
+}
+
+// A PasswordResetStore knows how to store docshelf PasswordResets and make sure each one is only used once.
+type PasswordResetStore interface {
+	GetPasswordReset(ctx context.Context, id string) (PasswordReset, error)
+	PutPasswordReset(ctx context.Context, reset PasswordReset) error
+	UsePasswordReset(ctx context.Context, id string) error
+}
+
+// A PasswordManager knows how to change and reset the passwords of Users logging in with basic auth.
+type PasswordManager interface {
+	ChangePassword(ctx context.Context, user User, current, password string) error
+	RequestReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+// A LoginAttemptStore knows how to keep track of failed login attempts. Updates have to be atomic so
+// concurrent attempts can't slip past the throttle.
+type LoginAttemptStore interface {
+	GetLoginAttempts(ctx context.Context, key string) (LoginAttempts, error)
+	UpdateLoginAttempts(ctx context.Context, key string, update func(LoginAttempts) LoginAttempts) (LoginAttempts, error)
+	RemoveLoginAttempts(ctx context.Context, key string) error
+}
+
+// A LoginThrottle knows how to slow down and lock out repeated failed logins. Attempt is called before
+// credentials are checked and returns how long the login has to wait if it isn't allowed yet. Finish is
+// called with the outcome once they have been.
+type LoginThrottle interface {
+	Attempt(ctx context.Context, email, ip string) (time.Duration, error)
+	Finish(ctx context.Context, email, ip string, success bool) error
+	Unlock(ctx context.Context, email, ip, actorID string) error
+}
+
+// An AuditStore knows how to record and list docshelf AuditEvents.
+type AuditStore interface {
+	PutAuditEvent(ctx context.Context, event AuditEvent) error
+	ListAuditEvents(ctx context.Context, since time.Time) ([]AuditEvent, error)
+}
+
+// A Notifier knows how to deliver messages to Users.
+type Notifier interface {
+	Notify(ctx context.Context, user User, subject, body string) error
+}
+
+// A SnapshotStore knows how to capture and restore point in time Snapshots of all docshelf documents.
+type SnapshotStore interface {
+	TakeSnapshot(ctx context.Context, userID string) (string, error)
+	GetSnapshot(ctx context.Context, id string) (Snapshot, error)
+	ListSnapshots(ctx context.Context) ([]Snapshot, error)
+	RestoreSnapshot(ctx context.Context, id, prefix, userID string) error
 }
 
 // An Authenticator knows how to authenticate user credentials.
@@ -92,9 +313,17 @@ type Authenticator interface {
 // A Backend is an aggregation of almost all docshelf store interfaces.
 type Backend interface {
 	DocStore
+	TagStore
 	UserStore
+	TokenStore
 	GroupStore
-	// PolicyStore
+	SnapshotStore
+	PolicyStore
+	PathPolicyStore
+	SessionStore
+	PasswordResetStore
+	LoginAttemptStore
+	AuditStore
 }
 
 // A FileStore knows how to store and retrieve docshelf document contents.
@@ -108,7 +337,88 @@ type FileStore interface {
 // An TextIndex knows how to index and search docshelf documents.
 type TextIndex interface {
 	Index(ctx context.Context, doc Doc) error
-	Search(ctx context.Context, term string) ([]string, error)
+	Search(ctx context.Context, term string) ([]SearchHit, error)
+	Delete(ctx context.Context, path string) error
+}
+
+// IsPin reports whether a tag is a User's pin rather than a regular tag. Pins are stored as tags named after
+// the User that pinned the Doc.
+func IsPin(tag string) bool {
+	return strings.HasPrefix(tag, "user/")
+}
+
+// WithoutPins returns the regular tags out of the given tags.
+func WithoutPins(tags []string) []string {
+	var regular []string
+	for _, tag := range tags {
+		if !IsPin(tag) {
+			regular = append(regular, tag)
+		}
+	}
+
+	return regular
+}
+
+// DirPrefix returns the prefix shared by every path nested under the given folder path. The root folder
+// is represented by an empty path.
+func DirPrefix(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+
+	return path + "/"
+}
+
+// InPath reports whether a path is the given scope itself or is nested under it. An empty scope covers the
+// whole shelf. Scopes only match whole path segments, so "hr" covers "hr/policy.md" but not "hrx/notes.md".
+func InPath(path, scope string) bool {
+	scope = strings.Trim(scope, "/")
+	return scope == "" || path == scope || strings.HasPrefix(path, scope+"/")
+}
+
+// TreeChildren reduces a sorted listing of every Doc nested under a folder path down to its immediate
+// children. Deeper Docs are collapsed into the folder that contains them, even if that folder was never
+// explicitly created. Folders are listed before Docs.
+func TreeChildren(path string, nested []Doc) []Doc {
+	prefix := DirPrefix(path)
+	dirs := make([]Doc, 0)
+	docs := make([]Doc, 0)
+	seen := make(map[string]bool)
+
+	for _, doc := range nested {
+		rest := strings.TrimPrefix(doc.Path, prefix)
+		if rest == "" {
+			continue
+		}
+
+		if idx := strings.Index(rest, "/"); idx >= 0 {
+			doc = Doc{Path: prefix + rest[:idx], Title: rest[:idx], IsDir: true}
+		}
+
+		if !doc.IsDir {
+			docs = append(docs, doc)
+			continue
+		}
+
+		if seen[doc.Path] {
+			// prefer explicitly created folders over implied ones
+			if doc.ID != "" {
+				for i := range dirs {
+					if dirs[i].Path == doc.Path {
+						dirs[i] = doc
+					}
+				}
+			}
+
+			continue
+		}
+
+		seen[doc.Path] = true
+		dirs = append(dirs, doc)
+	}
+
+	return append(dirs, docs...)
 }
 
 // ContentString returns a Doc's content as a string.
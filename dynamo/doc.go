@@ -2,11 +2,13 @@ package dynamo
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dyna "github.com/aws/aws-sdk-go-v2/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/expression"
 	"github.com/docshelf/docshelf"
 	"github.com/pkg/errors"
 	"github.com/rs/xid"
@@ -23,7 +25,7 @@ type Tag struct {
 func (s Store) GetDoc(ctx context.Context, path string) (docshelf.Doc, error) {
 	var doc docshelf.Doc
 
-	if _, err := xid.FromString(path); err != nil {
+	if _, err := xid.FromString(path); err == nil {
 		var docs []docshelf.Doc
 		if err := s.getItemsGsi(ctx, s.docTable, s.docIDIndex, "id", path, &docs); err != nil {
 			return doc, err
@@ -40,6 +42,24 @@ func (s Store) GetDoc(ctx context.Context, path string) (docshelf.Doc, error) {
 		return doc, err
 	}
 
+	if doc.Path == "" {
+		return doc, docshelf.NewErrNotFound("doc does not exist")
+	}
+
+	lock, err := s.getLock(ctx, doc.ID)
+	if err != nil {
+		return doc, err
+	}
+
+	if lock.Active() {
+		doc.Lock = lock
+	}
+
+	// folders don't have any content
+	if doc.IsDir {
+		return doc, nil
+	}
+
 	content, err := s.fs.ReadFile(path)
 	if err != nil {
 		return doc, err
@@ -49,129 +69,238 @@ func (s Store) GetDoc(ctx context.Context, path string) (docshelf.Doc, error) {
 	return doc, nil
 }
 
-// ListDocs fetches a slice of docshelf Document metadata from dynamodb. If a query is provided, then the configured
+// ListDocs fetches a page of docshelf Doc metadata from dynamodb. If a query is provided, then the configured
 // docshelf.TextIndex will be used to get a set of document paths. If tags are also provided, then they will be used
 // to further filter down the results. If no query is provided, but tags are, then the tags will filter down the entire
-// set of documents stored.
-func (s Store) ListDocs(ctx context.Context, query string, tags ...string) ([]docshelf.Doc, error) {
-	var docs []docshelf.Doc
-	var foundPaths []string
-
-	// do a full listing if no filters are given
-	if query == "" && len(tags) == 0 {
-		input := dynamodb.ScanInput{
-			TableName: aws.String(s.docTable),
+// set of documents stored. Docs are streamed through a docshelf.DocPager, so only a single page is held in memory.
+// Listings sorted by path only read as many docs as fit on the page, while any other sort has to scan every doc
+// for every page. Dynamo scans don't return items sorted by path, so unfiltered path listings come back in the
+// table's own order, which is stable from one page to the next but not alphabetical.
+func (s Store) ListDocs(ctx context.Context, opts docshelf.ListOptions) (docshelf.DocPage, error) {
+	var ranked []string
+	if opts.Query != "" {
+		hits, err := s.ti.Search(ctx, opts.Query)
+		if err != nil {
+			return docshelf.DocPage{}, err
 		}
 
-		res, err := s.client.ScanRequest(&input).Send()
-		if err != nil {
-			return nil, err
+		for _, hit := range hits {
+			ranked = append(ranked, hit.Doc.Path)
 		}
+	}
 
-		if err := dyna.UnmarshalListOfMaps(res.Items, &docs); err != nil {
-			return nil, err
+	pager, err := docshelf.NewDocPager(opts, ranked)
+	if err != nil {
+		return docshelf.DocPage{}, err
+	}
+
+	// do a full listing if no filters are given
+	if opts.Query == "" && len(opts.Tags) == 0 {
+		if err := s.scanDocs(ctx, pager); err != nil {
+			return docshelf.DocPage{}, errors.Wrap(err, "failed to scan docs from dynamo")
 		}
 
-		return docs, nil
+		return pager.Page(), nil
 	}
 
-	if query != "" {
-		var err error
-		foundPaths, err = s.ti.Search(ctx, query)
+	paths := ranked
+	if len(opts.Tags) > 0 {
+		tagged, err := s.taggedPaths(ctx, opts.Tags)
 		if err != nil {
-			return nil, err
+			return docshelf.DocPage{}, err
 		}
-	}
 
-	if len(tags) == 0 {
-		return s.listDocs(ctx, foundPaths)
+		if opts.Query == "" {
+			paths = tagged
+		} else {
+			paths = intersect(ranked, tagged)
+		}
 	}
 
-	tagged, err := s.listTaggedDocs(ctx, tags)
-	if err != nil {
-		return nil, err
+	for _, path := range pager.Order(paths) {
+		if pager.Full() {
+			break
+		}
+
+		var doc docshelf.Doc
+		if err := s.getItem(ctx, s.docTable, "path", path, &doc); err != nil {
+			return docshelf.DocPage{}, err
+		}
+
+		// the text index can briefly lag behind dynamo, so paths that no longer exist are skipped
+		if doc.ID == "" {
+			continue
+		}
+
+		pager.Add(doc)
 	}
 
-	if query == "" {
-		return tagged, nil
+	return pager.Page(), nil
+}
+
+// scanDocs feeds the docs in the doc table to a pager. The scan is read one dynamo page at a time, following
+// LastEvaluatedKey, so the whole table is never held in memory at once. Path listings start the scan after the
+// cursor and stop once the page is full.
+func (s Store) scanDocs(ctx context.Context, pager *docshelf.DocPager) error {
+	input := dynamodb.ScanInput{
+		TableName: aws.String(s.docTable),
 	}
 
-	if len(foundPaths) > 0 {
-		for _, doc := range tagged {
-			if contains(foundPaths, doc.Path) {
-				docs = append(docs, doc)
+	if pager.Sort() == docshelf.SortPath {
+		// one extra doc is read to know whether there's another page
+		input.Limit = aws.Int64(int64(pager.Limit() + 1))
+		if after := pager.After(); after != "" {
+			key, err := makeKey("path", after)
+			if err != nil {
+				return err
 			}
+
+			input.ExclusiveStartKey = key
 		}
 	}
 
-	return docs, nil
-}
+	for {
+		res, err := s.client.ScanRequest(&input).Send()
+		if err != nil {
+			return err
+		}
 
-func (s Store) listDocs(ctx context.Context, paths []string) ([]docshelf.Doc, error) {
-	var docs []docshelf.Doc
-	for _, path := range paths {
-		var doc docshelf.Doc
-		if err := s.getItem(ctx, s.docTable, "path", path, &doc); err != nil {
-			return nil, err
+		var docs []docshelf.Doc
+		if err := dyna.UnmarshalListOfMaps(res.Items, &docs); err != nil {
+			return err
 		}
 
-		docs = append(docs, doc)
-	}
+		for _, doc := range docs {
+			pager.Add(doc)
+		}
 
-	return docs, nil
+		if len(res.LastEvaluatedKey) == 0 || pager.Full() {
+			return nil
+		}
+
+		input.ExclusiveStartKey = res.LastEvaluatedKey
+	}
 }
 
-func (s Store) listTaggedDocs(ctx context.Context, tags []string) ([]docshelf.Doc, error) {
+// taggedPaths returns the paths of the docs that have every one of the given tags.
+func (s Store) taggedPaths(ctx context.Context, tags []string) ([]string, error) {
 	var paths []string
-	for _, t := range tags {
+	for i, t := range tags {
 		var tag Tag
 		if err := s.getItem(ctx, s.tagTable, "tag", t, &tag); err != nil {
 			return nil, err
 		}
 
-		if paths == nil {
+		if i == 0 {
 			paths = tag.Paths
 		} else {
 			paths = intersect(paths, tag.Paths)
 		}
 	}
 
-	var docs []docshelf.Doc
-	for _, path := range paths {
-		var doc docshelf.Doc
-		if err := s.getItem(ctx, s.docTable, "path", path, &doc); err != nil {
-			return nil, err
+	return paths, nil
+}
+
+// SearchDocs finds the docs matching a search query, best matches first. Each doc comes with its tags, but
+// not pins. The text index can briefly lag behind dynamo, so hits that no longer exist are skipped.
+func (s Store) SearchDocs(ctx context.Context, query string) ([]docshelf.SearchHit, error) {
+	hits, err := s.ti.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	tagged, err := s.tagsByPath(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read tags for search hits")
+	}
+
+	found := make([]docshelf.SearchHit, 0, len(hits))
+	for _, hit := range hits {
+		if err := s.getItem(ctx, s.docTable, "path", hit.Doc.Path, &hit.Doc); err != nil {
+			return nil, errors.Wrap(err, "failed to fetch search hit from dynamo")
 		}
 
-		docs = append(docs, doc)
+		if hit.Doc.ID == "" {
+			continue
+		}
+
+		hit.Doc.Tags = docshelf.WithoutPins(tagged[hit.Doc.Path])
+		found = append(found, hit)
 	}
 
-	return docs, nil
+	return found, nil
 }
 
 // PutDoc creates or updates an existing docshelf Doc in dynamodb. It will also store the
 // Content in an underlying FileStore.
 func (s Store) PutDoc(ctx context.Context, doc docshelf.Doc) (string, error) {
 	// having no path is an invalid state
+	doc.Path = strings.Trim(doc.Path, "/")
 	if doc.Path == "" {
 		return "", errors.New("doc must have a valid path")
 	}
 
-	if existing, err := s.GetDoc(ctx, doc.Path); err != nil {
-		if !docshelf.CheckNotFound(err) {
-			return "", errors.Wrap(err, "could not verify existing file")
+	existing, err := s.GetDoc(ctx, doc.Path)
+	if err != nil && !docshelf.CheckNotFound(err) {
+		return "", errors.Wrap(err, "could not verify existing file")
+	}
+
+	if existing.ID == "" {
+		if doc.Version != 0 {
+			return "", docshelf.NewErrConflict("doc has been removed since it was read")
 		}
 
 		// set one-time fields for new document
 		doc.ID = xid.New().String()
 		doc.CreatedAt = time.Now()
 	} else {
-		// need to enforce integrity of created* fields if the doc exists.
+		if existing.IsDir != doc.IsDir {
+			return "", errors.Errorf("can not replace a folder with a doc or vice versa: %s", doc.Path)
+		}
+
+		if existing.Lock.Blocks(doc.UpdatedBy) {
+			return "", docshelf.NewErrLocked("doc is locked by another user")
+		}
+
+		// a version of 0 means the write doesn't care what it's replacing
+		if doc.Version != 0 && doc.Version != existing.Version {
+			return "", docshelf.NewErrConflict("doc has been modified since it was read")
+		}
+
+		// need to enforce integrity of the ID and created* fields if the doc exists.
+		doc.ID = existing.ID
 		doc.CreatedBy = existing.CreatedBy
 		doc.CreatedAt = existing.CreatedAt
 	}
 
+	doc.Version = existing.Version + 1
 	doc.UpdatedAt = time.Now()
+	doc.Lock = nil // locks are stored separately
+
+	// folders only exist as metadata, so nothing is written to the FileStore or text index
+	if doc.IsDir {
+		doc.Content = ""
+		doc.Message = ""
+
+		put, err := s.versionedPut(doc, existing)
+		if err != nil {
+			return "", err
+		}
+
+		input := dynamodb.TransactWriteItemsInput{TransactItems: []dynamodb.TransactWriteItem{{Put: put}}}
+		if _, err := s.client.TransactWriteItemsRequest(&input).Send(); err != nil {
+			if checkConditionFailed(err) {
+				return "", docshelf.NewErrConflict("folder has been modified since it was read")
+			}
+
+			return "", errors.Wrap(err, "failed to put folder into dynamo")
+		}
+
+		return doc.ID, nil
+	}
+
+	rev := docshelf.NewRevision(doc)
+	doc.Message = ""
 
 	// save content
 	if err := s.fs.WriteFile(doc.Path, []byte(doc.Content)); err != nil {
@@ -179,47 +308,117 @@ func (s Store) PutDoc(ctx context.Context, doc docshelf.Doc) (string, error) {
 	}
 
 	// full text index
-	if err := s.ti.Index(ctx, doc); err != nil {
+	if err := s.indexDoc(ctx, doc); err != nil {
 		return "", errors.Wrap(err, "failed to text index doc")
 	}
 
 	doc.Content = "" // need to clear content before storing doc
 
-	marshaled, err := dyna.MarshalMap(&doc)
+	put, err := s.versionedPut(doc, existing)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to marshal doc for dynamo")
+		return "", err
 	}
 
-	input := dynamodb.PutItemInput{
-		TableName: aws.String(s.docTable),
-		Item:      marshaled,
+	marshaledRev, err := dyna.MarshalMap(&rev)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal revision for dynamo")
+	}
+
+	// metadata and revision are written together so history can't drift from the doc
+	input := dynamodb.TransactWriteItemsInput{
+		TransactItems: []dynamodb.TransactWriteItem{
+			{Put: put},
+			{Put: &dynamodb.Put{TableName: aws.String(s.revTable), Item: marshaledRev}},
+		},
 	}
 
 	// save metadata
-	if _, err := s.client.PutItemRequest(&input).Send(); err != nil {
-		if err := s.fs.RemoveFile(doc.Path); err != nil { // need to rollback file storage if doc failes
+	if _, err := s.client.TransactWriteItemsRequest(&input).Send(); err != nil {
+		if err := s.rollbackContent(doc.Path, existing); err != nil { // need to rollback file storage if doc failes
 			return "", errors.Wrapf(err, "cleanup failed for file: %s", doc.Path)
 		}
 
+		if checkConditionFailed(err) {
+			return "", docshelf.NewErrConflict("doc has been modified since it was read")
+		}
+
 		return "", errors.Wrap(err, "failed to put doc into dynamo")
 	}
 
 	return doc.ID, nil
 }
 
-// TagDoc tags an existing document with the given tags.
-// TODO (erik): This is a mirror of the bolt implementation. Need to research and find out
-// if there's a more efficient way to get this behavior out of dynamo.
-func (s Store) TagDoc(ctx context.Context, path string, tags ...string) error {
-	if _, err := xid.FromString(path); err == nil {
-		doc, err := s.GetDoc(ctx, path)
-		if err != nil {
-			return err
+// versionedPut builds a transactional put for a doc that only succeeds if the stored doc hasn't changed
+// since existing was read. Docs saved before versioning was introduced have no version at all.
+func (s Store) versionedPut(doc, existing docshelf.Doc) (*dynamodb.Put, error) {
+	marshaled, err := dyna.MarshalMap(&doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal doc for dynamo")
+	}
+
+	cond := expression.Name("path").AttributeNotExists()
+	if existing.ID != "" {
+		cond = expression.Name("version").Equal(expression.Value(existing.Version))
+		if existing.Version == 0 {
+			cond = expression.Name("version").AttributeNotExists().Or(cond)
 		}
+	}
 
-		path = doc.Path
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build version condition")
 	}
 
+	return &dynamodb.Put{
+		TableName:                 aws.String(s.docTable),
+		Item:                      marshaled,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}, nil
+}
+
+// rollbackContent puts back the content a failed write replaced. If there was no doc before the write, the
+// file is removed instead.
+func (s Store) rollbackContent(path string, previous docshelf.Doc) error {
+	if previous.ID == "" {
+		return s.fs.RemoveFile(path)
+	}
+
+	return s.fs.WriteFile(path, []byte(previous.Content))
+}
+
+// checkConditionFailed reports whether a write was rejected because one of its conditions didn't hold.
+func checkConditionFailed(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, dynamodb.ErrCodeConditionalCheckFailedException) ||
+		(strings.Contains(msg, dynamodb.ErrCodeTransactionCanceledException) && strings.Contains(msg, "ConditionalCheckFailed"))
+}
+
+// TagDoc tags an existing document with the given tags. The doc is re-indexed so it can be searched by its
+// new tags.
+func (s Store) TagDoc(ctx context.Context, path string, tags ...string) error {
+	doc, err := s.GetDoc(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if err := s.tagPath(ctx, doc.Path, tags...); err != nil {
+		return err
+	}
+
+	// pins aren't searchable, so there's nothing to re-index when only pinning
+	if doc.IsDir || !hasSearchableTag(tags) {
+		return nil
+	}
+
+	return errors.Wrap(s.indexDoc(ctx, doc), "failed to text index tagged doc")
+}
+
+// tagPath adds a doc path to each of the given tags.
+// TODO (erik): This is a mirror of the bolt implementation. Need to research and find out
+// if there's a more efficient way to get this behavior out of dynamo.
+func (s Store) tagPath(ctx context.Context, path string, tags ...string) error {
 	for _, t := range tags {
 		var tag Tag
 		if err := s.getItem(ctx, s.tagTable, "tag", t, &tag); err != nil {
@@ -254,25 +453,249 @@ func (s Store) TagDoc(ctx context.Context, path string, tags ...string) error {
 	return nil
 }
 
-// RemoveDoc removes a docshelf Doc from dynamo as well as the underlying FileStore.
-func (s Store) RemoveDoc(ctx context.Context, path string) error {
-	if err := s.fs.RemoveFile(path); err != nil {
-		return errors.Wrap(err, "failed to remove doc from file store")
+// MoveDoc relocates a docshelf Doc to a new path in dynamo. The doc keeps its ID, tags, pins and revision
+// history. The content is moved within the underlying FileStore and re-indexed under the new path.
+func (s Store) MoveDoc(ctx context.Context, from, to, userID string) error {
+	to = strings.Trim(to, "/")
+	if to == "" {
+		return errors.New("doc must be moved to a valid path")
 	}
 
-	key, err := makeKey("path", path)
+	doc, err := s.GetDoc(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	from = doc.Path
+	if from == to {
+		return nil
+	}
+
+	if doc.IsDir {
+		return errors.New("moving folders is not supported")
+	}
+
+	if doc.Lock.Blocks(userID) {
+		return docshelf.NewErrLocked("doc is locked by another user")
+	}
+
+	// need to check the destination up front so existing content is never overwritten
+	var existing docshelf.Doc
+	if err := s.getItem(ctx, s.docTable, "path", to, &existing); err != nil {
+		return errors.Wrap(err, "could not verify destination path")
+	}
+
+	if existing.Path != "" {
+		return errors.Errorf("a doc already exists at %s", to)
+	}
+
+	tagged, err := s.tagsByPath(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to read tags for doc")
+	}
+
+	// the doc is written and deleted in the same transaction as each of its tags
+	if len(tagged[from])+2 > maxTransactItems {
+		return errors.Errorf("can not move a doc with more than %d tags and pins", maxTransactItems-2)
+	}
+
+	// content has to exist at the destination before the metadata can point to it
+	if err := s.fs.WriteFile(to, []byte(doc.Content)); err != nil {
+		return errors.Wrap(err, "failed to write doc to new path in file store")
+	}
+
+	moved := doc
+	moved.Path = to
+	moved.Content = ""
+	moved.Lock = nil
+
+	marshaled, err := dyna.MarshalMap(&moved)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal doc for dynamo")
+	}
+
+	oldKey, err := makeKey("path", from)
 	if err != nil {
 		return errors.Wrap(err, "failed to make key")
 	}
 
-	input := dynamodb.DeleteItemInput{
-		TableName: aws.String(s.docTable),
-		Key:       key,
+	// the doc table's secondary ID index follows the item, so only the doc and its tags need rewriting
+	items := []dynamodb.TransactWriteItem{
+		{Put: &dynamodb.Put{
+			TableName:                aws.String(s.docTable),
+			Item:                     marshaled,
+			ConditionExpression:      aws.String("attribute_not_exists(#path)"),
+			ExpressionAttributeNames: map[string]string{"#path": "path"},
+		}},
+		{Delete: &dynamodb.Delete{TableName: aws.String(s.docTable), Key: oldKey}},
+	}
+
+	for _, t := range tagged[from] {
+		var tag Tag
+		if err := s.getItem(ctx, s.tagTable, "tag", t, &tag); err != nil {
+			return err
+		}
+
+		for i, p := range tag.Paths {
+			if p == from {
+				tag.Paths[i] = to
+			}
+		}
+
+		marshaledTag, err := dyna.MarshalMap(&tag)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, dynamodb.TransactWriteItem{
+			Put: &dynamodb.Put{TableName: aws.String(s.tagTable), Item: marshaledTag},
+		})
 	}
 
-	if _, err := s.client.DeleteItemRequest(&input).Send(); err != nil {
-		return errors.Wrap(err, "failed to delete doc from dynamo")
+	input := dynamodb.TransactWriteItemsInput{TransactItems: items}
+	if _, err := s.client.TransactWriteItemsRequest(&input).Send(); err != nil {
+		if err := s.fs.RemoveFile(to); err != nil { // need to rollback file storage if move fails
+			return errors.Wrapf(err, "cleanup failed for file: %s", to)
+		}
+
+		return errors.Wrap(err, "failed to move doc in dynamo")
 	}
 
-	return nil
+	if err := s.fs.RemoveFile(from); err != nil {
+		return errors.Wrap(err, "failed to remove doc from old path in file store")
+	}
+
+	moved.Content = doc.Content
+	if err := s.indexDoc(ctx, moved); err != nil {
+		return errors.Wrap(err, "failed to text index moved doc")
+	}
+
+	return errors.Wrap(s.ti.Delete(ctx, from), "failed to remove old doc path from text index")
+}
+
+// indexDoc adds a doc to the text index along with its tags. Pins are left out since they only mean
+// something to the user that made them.
+func (s Store) indexDoc(ctx context.Context, doc docshelf.Doc) error {
+	tagged, err := s.tagsByPath(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to read tags for doc")
+	}
+
+	doc.Tags = docshelf.WithoutPins(tagged[doc.Path])
+	return s.ti.Index(ctx, doc)
+}
+
+// RemoveDoc moves a docshelf Doc into the trash. Its content and tags are kept alongside the metadata in
+// dynamo so it can be restored later, and the content is removed from the underlying FileStore. Folders are
+// removed recursively. Docs locked by anyone other than the given user can't be removed.
+func (s Store) RemoveDoc(ctx context.Context, path, userID string) error {
+	doc, err := s.GetDoc(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	if doc.ID == "" {
+		return docshelf.NewErrNotFound("doc does not exist")
+	}
+
+	if doc.IsDir {
+		return s.RemoveDir(ctx, doc.Path, userID)
+	}
+
+	if doc.Lock.Blocks(userID) {
+		return docshelf.NewErrLocked("doc is locked by another user")
+	}
+
+	tagged, err := s.tagsByPath(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to read tags for doc")
+	}
+
+	// the doc is trashed, deleted and unlocked in the same transaction as each of its tags
+	if len(tagged[doc.Path])+3 > maxTransactItems {
+		return errors.Errorf("can not remove a doc with more than %d tags and pins", maxTransactItems-3)
+	}
+
+	deletedAt := time.Now()
+	doc.DeletedAt = &deletedAt
+	doc.Lock = nil
+	doc.Tags = union(doc.Tags, tagged[doc.Path])
+
+	marshaled, err := dyna.MarshalMap(&doc)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal doc for dynamo")
+	}
+
+	key, err := makeKey("path", doc.Path)
+	if err != nil {
+		return errors.Wrap(err, "failed to make key")
+	}
+
+	lockKey, err := makeKey("docId", doc.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to make key")
+	}
+
+	items := []dynamodb.TransactWriteItem{
+		{Put: &dynamodb.Put{TableName: aws.String(s.trashTable), Item: marshaled}},
+		{Delete: &dynamodb.Delete{TableName: aws.String(s.docTable), Key: key}},
+		{Delete: &dynamodb.Delete{TableName: aws.String(s.lockTable), Key: lockKey}},
+	}
+
+	for _, t := range tagged[doc.Path] {
+		item, err := s.untagItem(ctx, t, doc.Path)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, item)
+	}
+
+	input := dynamodb.TransactWriteItemsInput{TransactItems: items}
+	if _, err := s.client.TransactWriteItemsRequest(&input).Send(); err != nil {
+		return errors.Wrap(err, "failed to move doc to trash in dynamo")
+	}
+
+	if err := s.fs.RemoveFile(doc.Path); err != nil {
+		return errors.Wrap(err, "failed to remove doc from file store")
+	}
+
+	return errors.Wrap(s.ti.Delete(ctx, doc.Path), "failed to remove doc from text index")
+}
+
+// untagItem builds a transactional write that removes a path from a tag. Tags left without any paths are
+// deleted.
+func (s Store) untagItem(ctx context.Context, t, path string) (dynamodb.TransactWriteItem, error) {
+	var tag Tag
+	if err := s.getItem(ctx, s.tagTable, "tag", t, &tag); err != nil {
+		return dynamodb.TransactWriteItem{}, err
+	}
+
+	paths := make([]string, 0, len(tag.Paths))
+	for _, p := range tag.Paths {
+		if p != path {
+			paths = append(paths, p)
+		}
+	}
+
+	if len(paths) == 0 {
+		key, err := makeKey("tag", t)
+		if err != nil {
+			return dynamodb.TransactWriteItem{}, errors.Wrap(err, "failed to make key")
+		}
+
+		return dynamodb.TransactWriteItem{
+			Delete: &dynamodb.Delete{TableName: aws.String(s.tagTable), Key: key},
+		}, nil
+	}
+
+	tag.Paths = paths
+	marshaled, err := dyna.MarshalMap(&tag)
+	if err != nil {
+		return dynamodb.TransactWriteItem{}, err
+	}
+
+	return dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{TableName: aws.String(s.tagTable), Item: marshaled},
+	}, nil
 }
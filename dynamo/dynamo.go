@@ -20,11 +20,22 @@ import (
 )
 
 const (
-	defUserTable   = "docshelf_user"
-	defDocTable    = "docshelf_doc"
-	defTagTable    = "docshelf_tag"
-	defGroupTable  = "docshelf_group"
-	defPolicyTable = "docshelf_policy"
+	defUserTable    = "docshelf_user"
+	defDocTable     = "docshelf_doc"
+	defTagTable     = "docshelf_tag"
+	defGroupTable   = "docshelf_group"
+	defPolicyTable  = "docshelf_policy"
+	defRevTable     = "docshelf_revision"
+	defSnapTable    = "docshelf_snapshot"
+	defSnapDocTable = "docshelf_snapshot_doc"
+	defTrashTable   = "docshelf_trash"
+	defLockTable    = "docshelf_lock"
+	defPathPolTable = "docshelf_path_policy"
+	defSessionTable = "docshelf_session"
+	defTokenTable   = "docshelf_token"
+	defResetTable   = "docshelf_password_reset"
+	defAttemptTable = "docshelf_login_attempt"
+	defAuditTable   = "docshelf_audit"
 )
 
 // A Store has methods that know how to interact with docshelf data in Dynamo.
@@ -34,11 +45,22 @@ type Store struct {
 	ti     docshelf.TextIndex
 	log    *logrus.Logger
 
-	userTable   string
-	docTable    string
-	tagTable    string
-	groupTable  string
-	policyTable string
+	userTable    string
+	docTable     string
+	tagTable     string
+	groupTable   string
+	policyTable  string
+	revTable     string
+	snapTable    string
+	snapDocTable string
+	trashTable   string
+	lockTable    string
+	pathPolTable string
+	sessionTable string
+	tokenTable   string
+	resetTable   string
+	attemptTable string
+	auditTable   string
 
 	userEmailIndex string
 	docIDIndex     string
@@ -60,15 +82,26 @@ func New(fs docshelf.FileStore, ti docshelf.TextIndex, logger *logrus.Logger) (S
 	svc := dynamodb.New(cfg)
 
 	store := Store{
-		client:      svc,
-		fs:          fs,
-		ti:          ti,
-		log:         logger,
-		userTable:   env.GetEnvString("DS_DYNAMO_USER_TABLE", defUserTable),
-		docTable:    env.GetEnvString("DS_DYNAMO_DOC_TABLE", defDocTable),
-		tagTable:    env.GetEnvString("DS_DYNAMO_TAG_TABLE", defTagTable),
-		groupTable:  env.GetEnvString("DS_DYNAMO_GROUP_TABLE", defGroupTable),
-		policyTable: env.GetEnvString("DS_DYNAMO_POLICY_TABLE", defPolicyTable),
+		client:       svc,
+		fs:           fs,
+		ti:           ti,
+		log:          logger,
+		userTable:    env.GetEnvString("DS_DYNAMO_USER_TABLE", defUserTable),
+		docTable:     env.GetEnvString("DS_DYNAMO_DOC_TABLE", defDocTable),
+		tagTable:     env.GetEnvString("DS_DYNAMO_TAG_TABLE", defTagTable),
+		groupTable:   env.GetEnvString("DS_DYNAMO_GROUP_TABLE", defGroupTable),
+		policyTable:  env.GetEnvString("DS_DYNAMO_POLICY_TABLE", defPolicyTable),
+		revTable:     env.GetEnvString("DS_DYNAMO_REVISION_TABLE", defRevTable),
+		snapTable:    env.GetEnvString("DS_DYNAMO_SNAPSHOT_TABLE", defSnapTable),
+		snapDocTable: env.GetEnvString("DS_DYNAMO_SNAPSHOT_DOC_TABLE", defSnapDocTable),
+		trashTable:   env.GetEnvString("DS_DYNAMO_TRASH_TABLE", defTrashTable),
+		lockTable:    env.GetEnvString("DS_DYNAMO_LOCK_TABLE", defLockTable),
+		pathPolTable: env.GetEnvString("DS_DYNAMO_PATH_POLICY_TABLE", defPathPolTable),
+		sessionTable: env.GetEnvString("DS_DYNAMO_SESSION_TABLE", defSessionTable),
+		tokenTable:   env.GetEnvString("DS_DYNAMO_TOKEN_TABLE", defTokenTable),
+		resetTable:   env.GetEnvString("DS_DYNAMO_PASSWORD_RESET_TABLE", defResetTable),
+		attemptTable: env.GetEnvString("DS_DYNAMO_LOGIN_ATTEMPT_TABLE", defAttemptTable),
+		auditTable:   env.GetEnvString("DS_DYNAMO_AUDIT_TABLE", defAuditTable),
 	}
 
 	// set secondary indices
@@ -93,9 +126,13 @@ func (s Store) getItem(ctx context.Context, table, keyName, key string, out inte
 		return err
 	}
 
+	return s.getItemByKey(ctx, table, k, out)
+}
+
+func (s Store) getItemByKey(ctx context.Context, table string, key map[string]dynamodb.AttributeValue, out interface{}) error {
 	input := dynamodb.GetItemInput{
 		TableName: aws.String(table),
-		Key:       k,
+		Key:       key,
 	}
 
 	res, err := s.client.GetItemRequest(&input).Send()
@@ -112,6 +149,40 @@ func (s Store) getItem(ctx context.Context, table, keyName, key string, out inte
 	return nil
 }
 
+func (s Store) putItem(ctx context.Context, table string, item interface{}) error {
+	marshaled, err := dyna.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.PutItemInput{
+		TableName: aws.String(table),
+		Item:      marshaled,
+	}
+
+	_, err = s.client.PutItemRequest(&input).Send()
+	return err
+}
+
+func (s Store) deleteItem(ctx context.Context, table, keyName, key string) error {
+	k, err := makeKey(keyName, key)
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.DeleteItemInput{
+		TableName: aws.String(table),
+		Key:       k,
+	}
+
+	_, err = s.client.DeleteItemRequest(&input).Send()
+	return err
+}
+
+func (s Store) getItems(ctx context.Context, table, keyName, key string, out interface{}) error {
+	return s.getItemsGsi(ctx, table, "", keyName, key, out)
+}
+
 func (s Store) getItemsGsi(ctx context.Context, table, idx, keyName, key string, out interface{}) error {
 	keyCond := expression.Key(keyName).Equal(expression.Value(key))
 	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
@@ -121,12 +192,16 @@ func (s Store) getItemsGsi(ctx context.Context, table, idx, keyName, key string,
 
 	input := dynamodb.QueryInput{
 		TableName:                 aws.String(table),
-		IndexName:                 aws.String(idx),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		KeyConditionExpression:    expr.KeyCondition(),
 	}
 
+	// querying the table itself when no secondary index is given
+	if idx != "" {
+		input.IndexName = aws.String(idx)
+	}
+
 	res, err := s.client.QueryRequest(&input).Send()
 	if err != nil {
 		if strings.Contains(err.Error(), dynamodb.ErrCodeResourceNotFoundException) {
@@ -143,6 +218,79 @@ func (s Store) getItemsGsi(ctx context.Context, table, idx, keyName, key string,
 	return nil
 }
 
+// queryItems runs a query against a table, following LastEvaluatedKey until every page has been read.
+func (s Store) queryItems(ctx context.Context, table string, keyCond expression.KeyConditionBuilder, out interface{}) error {
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.QueryInput{
+		TableName:                 aws.String(table),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+	}
+
+	var items []map[string]dynamodb.AttributeValue
+	for {
+		res, err := s.client.QueryRequest(&input).Send()
+		if err != nil {
+			return err
+		}
+
+		items = append(items, res.Items...)
+		if len(res.LastEvaluatedKey) == 0 {
+			break
+		}
+
+		input.ExclusiveStartKey = res.LastEvaluatedKey
+	}
+
+	return dyna.UnmarshalListOfMaps(items, out)
+}
+
+// scanItems scans an entire table, following LastEvaluatedKey until every page has been read.
+func (s Store) scanItems(ctx context.Context, table string, out interface{}) error {
+	return s.scanItemsFilter(ctx, table, nil, out)
+}
+
+// scanItemsFilter scans an entire table, keeping only the items matching the given filter. A nil filter keeps
+// every item.
+func (s Store) scanItemsFilter(ctx context.Context, table string, filter *expression.ConditionBuilder, out interface{}) error {
+	input := dynamodb.ScanInput{
+		TableName: aws.String(table),
+	}
+
+	if filter != nil {
+		expr, err := expression.NewBuilder().WithFilter(*filter).Build()
+		if err != nil {
+			return err
+		}
+
+		input.ExpressionAttributeNames = expr.Names()
+		input.ExpressionAttributeValues = expr.Values()
+		input.FilterExpression = expr.Filter()
+	}
+
+	var items []map[string]dynamodb.AttributeValue
+	for {
+		res, err := s.client.ScanRequest(&input).Send()
+		if err != nil {
+			return err
+		}
+
+		items = append(items, res.Items...)
+		if len(res.LastEvaluatedKey) == 0 {
+			break
+		}
+
+		input.ExclusiveStartKey = res.LastEvaluatedKey
+	}
+
+	return dyna.UnmarshalListOfMaps(items, out)
+}
+
 // ensureTables concurrently ensures dynamo tables are created. Doing this in parallel
 // significantly reduces the wait time for dynamo to be bootstrapped.
 func (s Store) ensureTables() error {
@@ -188,6 +336,94 @@ func (s Store) ensureTables() error {
 		}
 	}()
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.revTable, revTableInput(s.revTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.snapTable, snapTableInput(s.snapTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.snapDocTable, snapDocTableInput(s.snapDocTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.trashTable, trashTableInput(s.trashTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.lockTable, lockTableInput(s.lockTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.pathPolTable, pathPolTableInput(s.pathPolTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.sessionTable, sessionTableInput(s.sessionTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.tokenTable, tokenTableInput(s.tokenTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.resetTable, resetTableInput(s.resetTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.attemptTable, attemptTableInput(s.attemptTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := s.ensureTable(s.auditTable, auditTableInput(s.auditTable)); err != nil {
+			ensureErr = err
+		}
+	}()
+
 	wg.Wait()
 	return ensureErr
 }
@@ -345,6 +581,216 @@ func policyTableInput(policyTable string) dynamodb.CreateTableInput {
 	}
 }
 
+func revTableInput(revTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("docId"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	rangeKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("id"),
+		KeyType:       dynamodb.KeyTypeRange,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("docId", dynamodb.ScalarAttributeTypeS),
+		makeAttrDef("id", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(revTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey, rangeKey},
+	}
+}
+
+func snapTableInput(snapTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("id"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("id", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(snapTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey},
+	}
+}
+
+func snapDocTableInput(snapDocTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("snapshotId"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	rangeKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("path"),
+		KeyType:       dynamodb.KeyTypeRange,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("snapshotId", dynamodb.ScalarAttributeTypeS),
+		makeAttrDef("path", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(snapDocTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey, rangeKey},
+	}
+}
+
+func trashTableInput(trashTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("id"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("id", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(trashTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey},
+	}
+}
+
+func lockTableInput(lockTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("docId"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("docId", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(lockTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey},
+	}
+}
+
+func pathPolTableInput(pathPolTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("path"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("path", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(pathPolTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey},
+	}
+}
+
+func sessionTableInput(sessionTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("id"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("id", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(sessionTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey},
+	}
+}
+
+func tokenTableInput(tokenTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("id"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("id", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(tokenTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey},
+	}
+}
+
+func resetTableInput(resetTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("id"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("id", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(resetTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey},
+	}
+}
+
+func attemptTableInput(attemptTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("key"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("key", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(attemptTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey},
+	}
+}
+
+func auditTableInput(auditTable string) dynamodb.CreateTableInput {
+	hashKey := dynamodb.KeySchemaElement{
+		AttributeName: aws.String("id"),
+		KeyType:       dynamodb.KeyTypeHash,
+	}
+
+	attrDef := []dynamodb.AttributeDefinition{
+		makeAttrDef("id", dynamodb.ScalarAttributeTypeS),
+	}
+
+	return dynamodb.CreateTableInput{
+		TableName:            aws.String(auditTable),
+		BillingMode:          dynamodb.BillingModePayPerRequest,
+		AttributeDefinitions: attrDef,
+		KeySchema:            []dynamodb.KeySchemaElement{hashKey},
+	}
+}
+
 // TODO (erik): Duplicated code shared with bolt backend. Should probably consolidate.
 func intersect(left, right []string) []string {
 	intersection := make([]string, 0)
@@ -366,3 +812,39 @@ func contains(slice []string, el string) bool {
 
 	return false
 }
+
+func union(left, right []string) []string {
+	combined := make([]string, 0, len(left)+len(right))
+	for _, slice := range [][]string{left, right} {
+		for _, el := range slice {
+			if !contains(combined, el) {
+				combined = append(combined, el)
+			}
+		}
+	}
+
+	return combined
+}
+
+// without returns the elements of left that aren't in right.
+func without(left, right []string) []string {
+	remaining := make([]string, 0, len(left))
+	for _, el := range left {
+		if !contains(right, el) {
+			remaining = append(remaining, el)
+		}
+	}
+
+	return remaining
+}
+
+// hasSearchableTag reports whether any of the tags are regular tags rather than pins.
+func hasSearchableTag(tags []string) bool {
+	for _, tag := range tags {
+		if !docshelf.IsPin(tag) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,6 +2,7 @@ package dynamo
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"testing"
 
@@ -30,6 +31,50 @@ func init() {
 	if err := os.Setenv("DS_DYNAMO_POLICY_TABLE", "ds_test_policy"); err != nil {
 		panic("This should never happen")
 	}
+
+	if err := os.Setenv("DS_DYNAMO_REVISION_TABLE", "ds_test_revision"); err != nil {
+		panic("This should never happen")
+	}
+
+	if err := os.Setenv("DS_DYNAMO_SNAPSHOT_TABLE", "ds_test_snapshot"); err != nil {
+		panic("This should never happen")
+	}
+
+	if err := os.Setenv("DS_DYNAMO_SNAPSHOT_DOC_TABLE", "ds_test_snapshot_doc"); err != nil {
+		panic("This should never happen")
+	}
+
+	if err := os.Setenv("DS_DYNAMO_TRASH_TABLE", "ds_test_trash"); err != nil {
+		panic("This should never happen")
+	}
+
+	if err := os.Setenv("DS_DYNAMO_LOCK_TABLE", "ds_test_lock"); err != nil {
+		panic("This should never happen")
+	}
+
+	if err := os.Setenv("DS_DYNAMO_PATH_POLICY_TABLE", "ds_test_path_policy"); err != nil {
+		panic("This should never happen")
+	}
+
+	if err := os.Setenv("DS_DYNAMO_SESSION_TABLE", "ds_test_session"); err != nil {
+		panic("This should never happen")
+	}
+
+	if err := os.Setenv("DS_DYNAMO_TOKEN_TABLE", "ds_test_token"); err != nil {
+		panic("This should never happen")
+	}
+
+	if err := os.Setenv("DS_DYNAMO_PASSWORD_RESET_TABLE", "ds_test_password_reset"); err != nil {
+		panic("This should never happen")
+	}
+
+	if err := os.Setenv("DS_DYNAMO_LOGIN_ATTEMPT_TABLE", "ds_test_login_attempt"); err != nil {
+		panic("This should never happen")
+	}
+
+	if err := os.Setenv("DS_DYNAMO_AUDIT_TABLE", "ds_test_audit"); err != nil {
+		panic("This should never happen")
+	}
 }
 
 func checkIntegrationTest() bool {
@@ -184,7 +229,7 @@ func Test_PutGetRemoveDoc(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if err := store.RemoveDoc(ctx, doc.Path); err != nil {
+	if err := store.RemoveDoc(ctx, doc.Path, doc.UpdatedBy); err != nil {
 		t.Fatal(err)
 	}
 
@@ -237,13 +282,13 @@ func Test_ListDocs(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	list, err := store.ListDocs(ctx, "")
+	list, err := store.ListDocs(ctx, docshelf.ListOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// ASSERT
-	if len(list) != 2 {
+	if len(list.Docs) != 2 {
 		t.Fatal("listing didn't return enough results")
 	}
 }
@@ -294,31 +339,31 @@ func Test_TagLifecycle(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	testTag, err := store.ListDocs(ctx, "", "test")
+	testTag, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"test"}})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	oneTag, err := store.ListDocs(ctx, "", "one")
+	oneTag, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"one"}})
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	twoTag, err := store.ListDocs(ctx, "", "two")
+	twoTag, err := store.ListDocs(ctx, docshelf.ListOptions{Tags: []string{"two"}})
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// ASSERT
-	if len(testTag) != 2 {
+	if len(testTag.Docs) != 2 {
 		t.Fatal("listing didn't return correct number of results")
 	}
 
-	if len(oneTag) != 1 && oneTag[0].Path == doc1.Path {
+	if len(oneTag.Docs) != 1 && oneTag.Docs[0].Path == doc1.Path {
 		t.Fatal("listing returned wrong results for tag 'one'")
 	}
 
-	if len(twoTag) != 1 && twoTag[0].Path == doc2.Path {
+	if len(twoTag.Docs) != 1 && twoTag.Docs[0].Path == doc2.Path {
 		t.Fatal("listing returned wrong results for tag 'two'")
 	}
 }
@@ -336,9 +381,19 @@ func Test_PutGetRemoveGroup(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	var userIDs []string
+	for i := 0; i < 3; i++ {
+		userID, err := store.PutUser(ctx, docshelf.User{Email: xid.New().String() + "@test.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		userIDs = append(userIDs, userID)
+	}
+
 	group := docshelf.Group{
 		Name:  "test",
-		Users: []string{xid.New().String(), xid.New().String(), xid.New().String()},
+		Users: userIDs,
 	}
 
 	// RUN
@@ -356,9 +411,7 @@ func Test_PutGetRemoveGroup(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if _, err := store.GetGroup(ctx, id); err != nil {
-		t.Fatal(err)
-	}
+	_, removedErr := store.GetGroup(ctx, id)
 
 	// ASSERT
 	if getGroup.Name != group.Name {
@@ -376,6 +429,10 @@ func Test_PutGetRemoveGroup(t *testing.T) {
 	if getGroup.UpdatedAt.IsZero() {
 		t.Fatal("group UpdatedAt not set properly")
 	}
+
+	if !docshelf.CheckNotFound(removedErr) {
+		t.Fatalf("expected removed group to be not found, got: %v", removedErr)
+	}
 }
 
 func Test_PutGetRemovePolicy(t *testing.T) {
@@ -411,9 +468,7 @@ func Test_PutGetRemovePolicy(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if _, err := store.GetPolicy(ctx, id); err != nil {
-		t.Fatal(err)
-	}
+	_, removedErr := store.GetPolicy(ctx, id)
 
 	// ASSERT
 
@@ -432,4 +487,100 @@ func Test_PutGetRemovePolicy(t *testing.T) {
 	if getPolicy.UpdatedAt.IsZero() {
 		t.Fatal("policy UpdatedAt not set properly")
 	}
+
+	if !docshelf.CheckNotFound(removedErr) {
+		t.Fatalf("expected removed policy to be not found, got: %v", removedErr)
+	}
+}
+
+func Test_RemoveDirRejectsDocs(t *testing.T) {
+	if !checkIntegrationTest() {
+		return
+	}
+
+	// SETUP
+	ctx := context.Background()
+
+	store, err := New(mock.NewFileStore(), mock.NewTextIndex(nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID := xid.New().String()
+	doc := docshelf.Doc{Path: "guides/setup.md", Title: "Setup", Content: "setup", CreatedBy: userID, UpdatedBy: userID}
+	if _, err := store.PutDoc(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+	defer store.RemoveDoc(ctx, doc.Path, userID)
+
+	// RUN
+	removeErr := store.RemoveDir(ctx, doc.Path, userID)
+
+	getDoc, err := store.GetDoc(ctx, doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if !docshelf.CheckBadQuery(removeErr) {
+		t.Fatalf("expected a bad query error, got: %v", removeErr)
+	}
+
+	if getDoc.ID == "" {
+		t.Fatal("doc was removed as a folder")
+	}
+}
+
+func Test_RemoveDocTransactionLimit(t *testing.T) {
+	if !checkIntegrationTest() {
+		return
+	}
+
+	// SETUP
+	ctx := context.Background()
+
+	store, err := New(mock.NewFileStore(), mock.NewTextIndex(nil), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userID := xid.New().String()
+	doc := docshelf.Doc{Path: "tagged.md", Title: "Tagged", Content: "lots of tags", CreatedBy: userID, UpdatedBy: userID}
+	if _, err := store.PutDoc(ctx, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tags := make([]string, 0, maxTransactItems-2)
+	for i := 0; i < cap(tags); i++ {
+		tags = append(tags, fmt.Sprintf("tag-%d", i))
+	}
+
+	if err := store.TagDoc(ctx, doc.Path, tags...); err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	removeErr := store.RemoveDoc(ctx, doc.Path, userID)
+
+	kept, err := store.GetDoc(ctx, doc.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.UntagDoc(ctx, doc.Path, tags[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RemoveDoc(ctx, doc.Path, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if removeErr == nil {
+		t.Fatal("removing a doc with more tags than fit in a transaction should fail")
+	}
+
+	if kept.ID == "" {
+		t.Fatal("doc shouldn't be removed when its tags can't be cleaned up")
+	}
 }
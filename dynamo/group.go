@@ -7,11 +7,15 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	dyna "github.com/aws/aws-sdk-go-v2/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/expression"
 	"github.com/docshelf/docshelf"
 	"github.com/pkg/errors"
 	"github.com/rs/xid"
 )
 
+// dynamo caps the number of items a single transaction can write.
+const maxTransactItems = 25
+
 // GetGroup fetches an existing docshelf Group from dynamodb.
 func (s Store) GetGroup(ctx context.Context, id string) (docshelf.Group, error) {
 	var group docshelf.Group
@@ -20,50 +24,222 @@ func (s Store) GetGroup(ctx context.Context, id string) (docshelf.Group, error)
 		return group, errors.Wrap(err, "failed to fetch group from dynamo")
 	}
 
+	if group.ID == "" {
+		return group, docshelf.NewErrNotFound("group does not exist")
+	}
+
 	return group, nil
 }
 
-// PutGroup creates a new docshelf User or updates an existing one in boltdb.
+// ListGroups returns all docshelf Groups stored in dynamodb.
+func (s Store) ListGroups(ctx context.Context) ([]docshelf.Group, error) {
+	groups := make([]docshelf.Group, 0)
+	if err := s.scanItems(ctx, s.groupTable, &groups); err != nil {
+		return nil, errors.Wrap(err, "failed to list groups from dynamo")
+	}
+
+	return groups, nil
+}
+
+// PutGroup creates a new docshelf Group or updates an existing one in dynamodb. The Groups of every User
+// joining or leaving the Group are updated in the same transaction.
 func (s Store) PutGroup(ctx context.Context, group docshelf.Group) (string, error) {
 	if group.ID == "" {
 		group.ID = xid.New().String()
+	}
+
+	var existing docshelf.Group
+	if err := s.getItem(ctx, s.groupTable, "id", group.ID, &existing); err != nil {
+		return "", errors.Wrap(err, "failed to fetch group from dynamo")
+	}
+
+	group.CreatedAt = existing.CreatedAt
+	if existing.ID == "" {
 		group.CreatedAt = time.Now()
 	}
 
 	group.UpdatedAt = time.Now()
+	group.Users = union(group.Users, nil) // drops duplicates
+
+	if err := s.saveGroup(ctx, existing, &group); err != nil {
+		return "", err
+	}
+
+	return group.ID, nil
+}
 
-	marshaled, err := dyna.MarshalMap(&group)
+// RemoveGroup deletes a docshelf Group from dynamo and removes it from the Groups of all of its Users.
+func (s Store) RemoveGroup(ctx context.Context, id string) error {
+	existing, err := s.GetGroup(ctx, id)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to marshal group for dynamo")
+		if docshelf.CheckNotFound(err) {
+			return nil
+		}
+
+		return err
 	}
 
-	input := dynamodb.PutItemInput{
-		TableName: aws.String(s.groupTable),
-		Item:      marshaled,
+	return s.saveGroup(ctx, existing, nil)
+}
+
+// AddGroupUsers adds Users to an existing docshelf Group in dynamo.
+func (s Store) AddGroupUsers(ctx context.Context, id string, userIDs ...string) error {
+	existing, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return err
 	}
 
-	if _, err := s.client.PutItemRequest(&input).Send(); err != nil {
-		return "", errors.Wrap(err, "failed to put group into dynamo")
+	group := existing
+	group.Users = union(existing.Users, userIDs)
+	group.UpdatedAt = time.Now()
+	return s.saveGroup(ctx, existing, &group)
+}
+
+// RemoveGroupUsers removes Users from an existing docshelf Group in dynamo.
+func (s Store) RemoveGroupUsers(ctx context.Context, id string, userIDs ...string) error {
+	existing, err := s.GetGroup(ctx, id)
+	if err != nil {
+		return err
 	}
 
-	return group.ID, nil
+	group := existing
+	group.Users = without(existing.Users, userIDs)
+	group.UpdatedAt = time.Now()
+	return s.saveGroup(ctx, existing, &group)
 }
 
-// RemoveGroup deletes a docshelf Group from dynamo.
-func (s Store) RemoveGroup(ctx context.Context, id string) error {
-	key, err := makeKey("id", id)
+// saveGroup writes a Group along with every User whose membership changed in a single transaction. A nil
+// Group removes the existing one. Every item is conditioned on not having changed since it was read, so
+// concurrent membership changes can't be lost.
+func (s Store) saveGroup(ctx context.Context, existing docshelf.Group, group *docshelf.Group) error {
+	var after []string
+	var item interface{}
+	groupID := existing.ID
+	if group != nil {
+		after = group.Users
+		groupID = group.ID
+		item = group
+	}
+
+	users, err := s.memberChanges(ctx, groupID, existing.Users, after)
 	if err != nil {
-		return errors.Wrap(err, "failed to make key to delete from dynamo")
+		return err
 	}
 
-	input := dynamodb.DeleteItemInput{
-		TableName: aws.String(s.groupTable),
-		Key:       key,
+	if len(users)+1 > maxTransactItems {
+		return errors.Errorf("can not change more than %d group members at once", maxTransactItems-1)
 	}
 
-	if _, err := s.client.DeleteItemRequest(&input).Send(); err != nil {
-		return errors.Wrap(err, "failed to remove group from dynamo")
+	groupCond := expression.Name("id").AttributeNotExists()
+	if existing.ID != "" {
+		groupCond = expression.Name("updatedAt").Equal(expression.Value(existing.UpdatedAt))
+	}
+
+	groupItem, err := s.conditionalWrite(s.groupTable, "id", groupID, item, groupCond)
+	if err != nil {
+		return err
+	}
+
+	items := []dynamodb.TransactWriteItem{groupItem}
+	for _, change := range users {
+		cond := expression.Name("updatedAt").Equal(expression.Value(change.readAt))
+		userItem, err := s.conditionalWrite(s.userTable, "id", change.user.ID, change.user, cond)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, userItem)
+	}
+
+	input := dynamodb.TransactWriteItemsInput{TransactItems: items}
+	if _, err := s.client.TransactWriteItemsRequest(&input).Send(); err != nil {
+		if checkConditionFailed(err) {
+			return docshelf.NewErrConflict("group or one of its members was modified concurrently")
+		}
+
+		return errors.Wrap(err, "failed to save group in dynamo")
 	}
 
 	return nil
 }
+
+type memberChange struct {
+	user   docshelf.User
+	readAt time.Time
+}
+
+// memberChanges loads every User whose membership changed between before and after and updates their
+// Groups. Users joining the Group must exist.
+func (s Store) memberChanges(ctx context.Context, groupID string, before, after []string) ([]memberChange, error) {
+	changes := make([]memberChange, 0)
+	for _, userID := range without(after, before) {
+		var user docshelf.User
+		if err := s.getItem(ctx, s.userTable, "id", userID, &user); err != nil {
+			return nil, err
+		}
+
+		if user.ID == "" {
+			return nil, docshelf.NewErrNotFound("user does not exist: " + userID)
+		}
+
+		readAt := user.UpdatedAt
+		user.Groups = union(user.Groups, []string{groupID})
+		user.UpdatedAt = time.Now()
+		changes = append(changes, memberChange{user, readAt})
+	}
+
+	for _, userID := range without(before, after) {
+		var user docshelf.User
+		if err := s.getItem(ctx, s.userTable, "id", userID, &user); err != nil {
+			return nil, err
+		}
+
+		if user.ID == "" {
+			continue // nothing to clean up
+		}
+
+		readAt := user.UpdatedAt
+		user.Groups = without(user.Groups, []string{groupID})
+		user.UpdatedAt = time.Now()
+		changes = append(changes, memberChange{user, readAt})
+	}
+
+	return changes, nil
+}
+
+// conditionalWrite builds a transactional put of the given item, or a delete of the key when the item is
+// nil, that only succeeds if the condition holds.
+func (s Store) conditionalWrite(table, keyName, key string, item interface{}, cond expression.ConditionBuilder) (dynamodb.TransactWriteItem, error) {
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return dynamodb.TransactWriteItem{}, errors.Wrap(err, "failed to build condition")
+	}
+
+	if item == nil {
+		k, err := makeKey(keyName, key)
+		if err != nil {
+			return dynamodb.TransactWriteItem{}, errors.Wrap(err, "failed to make key")
+		}
+
+		return dynamodb.TransactWriteItem{Delete: &dynamodb.Delete{
+			TableName:                 aws.String(table),
+			Key:                       k,
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		}}, nil
+	}
+
+	marshaled, err := dyna.MarshalMap(item)
+	if err != nil {
+		return dynamodb.TransactWriteItem{}, errors.Wrap(err, "failed to marshal item for dynamo")
+	}
+
+	return dynamodb.TransactWriteItem{Put: &dynamodb.Put{
+		TableName:                 aws.String(table),
+		Item:                      marshaled,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}}, nil
+}
@@ -0,0 +1,162 @@
+package dynamo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dyna "github.com/aws/aws-sdk-go-v2/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/expression"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+)
+
+// LockDoc gives a user exclusive write access to a docshelf Doc in dynamo for the given duration. Users can
+// extend locks they already hold, but a lock held by anyone else has to expire or be broken first.
+func (s Store) LockDoc(ctx context.Context, path, userID string, ttl time.Duration) (docshelf.Lock, error) {
+	doc, err := s.GetDoc(ctx, path)
+	if err != nil {
+		return docshelf.Lock{}, err
+	}
+
+	current, err := s.getLock(ctx, doc.ID)
+	if err != nil {
+		return docshelf.Lock{}, err
+	}
+
+	if current.Blocks(userID) {
+		return docshelf.Lock{}, docshelf.NewErrLocked("doc is locked by another user")
+	}
+
+	now := time.Now()
+	lock := docshelf.Lock{
+		DocID:     doc.ID,
+		UserID:    userID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	// extending a lock shouldn't change when it was first taken
+	if current.Active() {
+		lock.CreatedAt = current.CreatedAt
+	}
+
+	marshaled, err := dyna.MarshalMap(&lock)
+	if err != nil {
+		return lock, errors.Wrap(err, "failed to marshal lock for dynamo")
+	}
+
+	cond, err := lockCondition(current)
+	if err != nil {
+		return lock, err
+	}
+
+	input := dynamodb.PutItemInput{
+		TableName:                 aws.String(s.lockTable),
+		Item:                      marshaled,
+		ConditionExpression:       cond.Condition(),
+		ExpressionAttributeNames:  cond.Names(),
+		ExpressionAttributeValues: cond.Values(),
+	}
+
+	if _, err := s.client.PutItemRequest(&input).Send(); err != nil {
+		if checkConditionFailed(err) {
+			return lock, docshelf.NewErrLocked("doc is locked by another user")
+		}
+
+		return lock, errors.Wrap(err, "failed to lock doc in dynamo")
+	}
+
+	return lock, nil
+}
+
+// UnlockDoc releases a lock held by the given user on a docshelf Doc in dynamo. Releasing a lock that
+// doesn't exist or has already expired is not an error.
+func (s Store) UnlockDoc(ctx context.Context, path, userID string) error {
+	docID, err := s.resolveDocID(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.getLock(ctx, docID)
+	if err != nil {
+		return err
+	}
+
+	if current.Blocks(userID) {
+		return docshelf.NewErrLocked("doc is locked by another user")
+	}
+
+	if current == nil {
+		return nil
+	}
+
+	key, err := makeKey("docId", docID)
+	if err != nil {
+		return errors.Wrap(err, "failed to make key")
+	}
+
+	cond, err := lockCondition(current)
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.DeleteItemInput{
+		TableName:                 aws.String(s.lockTable),
+		Key:                       key,
+		ConditionExpression:       cond.Condition(),
+		ExpressionAttributeNames:  cond.Names(),
+		ExpressionAttributeValues: cond.Values(),
+	}
+
+	if _, err := s.client.DeleteItemRequest(&input).Send(); err != nil {
+		if checkConditionFailed(err) {
+			return docshelf.NewErrLocked("doc is locked by another user")
+		}
+
+		return errors.Wrap(err, "failed to unlock doc in dynamo")
+	}
+
+	return nil
+}
+
+// BreakLock releases the lock on a docshelf Doc in dynamo regardless of who holds it.
+func (s Store) BreakLock(ctx context.Context, path string) error {
+	docID, err := s.resolveDocID(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(s.deleteItem(ctx, s.lockTable, "docId", docID), "failed to break doc lock in dynamo")
+}
+
+// getLock returns the lock stored for a doc, if there is one. The lock may have already expired.
+func (s Store) getLock(ctx context.Context, docID string) (*docshelf.Lock, error) {
+	var lock docshelf.Lock
+	if err := s.getItem(ctx, s.lockTable, "docId", docID, &lock); err != nil {
+		return nil, errors.Wrap(err, "failed to fetch lock from dynamo")
+	}
+
+	if lock.DocID == "" {
+		return nil, nil
+	}
+
+	return &lock, nil
+}
+
+// lockCondition builds a condition that only holds while the lock that was read is still the one stored.
+func lockCondition(current *docshelf.Lock) (expression.Expression, error) {
+	cond := expression.Name("docId").AttributeNotExists()
+	if current != nil {
+		cond = expression.Name("userId").Equal(expression.Value(current.UserID)).
+			And(expression.Name("expiresAt").Equal(expression.Value(current.ExpiresAt)))
+	}
+
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return expr, errors.Wrap(err, "failed to build lock condition")
+	}
+
+	return expr, nil
+}
@@ -2,6 +2,7 @@ package dynamo
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -20,13 +21,36 @@ func (s Store) GetPolicy(ctx context.Context, id string) (docshelf.Policy, error
 		return policy, errors.Wrap(err, "failed to fetch policy from dynamo")
 	}
 
+	if policy.ID == "" {
+		return policy, docshelf.NewErrNotFound("policy does not exist")
+	}
+
 	return policy, nil
 }
 
+// ListPolicies returns all docshelf Policies stored in dynamo.
+func (s Store) ListPolicies(ctx context.Context) ([]docshelf.Policy, error) {
+	policies := make([]docshelf.Policy, 0)
+	if err := s.scanItems(ctx, s.policyTable, &policies); err != nil {
+		return nil, errors.Wrap(err, "failed to list policies from dynamo")
+	}
+
+	return policies, nil
+}
+
 // PutPolicy creates a new docshelf Policy or updates an existing one in dynamo.
 func (s Store) PutPolicy(ctx context.Context, policy docshelf.Policy) (string, error) {
 	if policy.ID == "" {
 		policy.ID = xid.New().String()
+	}
+
+	var existing docshelf.Policy
+	if err := s.getItem(ctx, s.policyTable, "id", policy.ID, &existing); err != nil {
+		return "", errors.Wrap(err, "failed to fetch policy from dynamo")
+	}
+
+	policy.CreatedAt = existing.CreatedAt
+	if existing.ID == "" {
 		policy.CreatedAt = time.Now()
 	}
 
@@ -67,3 +91,55 @@ func (s Store) RemovePolicy(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// A PathPolicy represents the dynamo data structure attaching a Policy to a path prefix.
+type PathPolicy struct {
+	Path     string `json:"path"`
+	PolicyID string `json:"policyId"`
+}
+
+// PutPathPolicy attaches an existing docshelf Policy to a path prefix in dynamo. Any Policy previously
+// attached to the same prefix is replaced.
+func (s Store) PutPathPolicy(ctx context.Context, path, policyID string) error {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return errors.New("policies can not be attached to the root of the shelf")
+	}
+
+	if _, err := s.GetPolicy(ctx, policyID); err != nil {
+		return err
+	}
+
+	attached := PathPolicy{Path: path, PolicyID: policyID}
+	return errors.Wrap(s.putItem(ctx, s.pathPolTable, attached), "failed to put path policy into dynamo")
+}
+
+// RemovePathPolicy detaches whatever Policy is attached to a path prefix in dynamo.
+func (s Store) RemovePathPolicy(ctx context.Context, path string) error {
+	return errors.Wrap(s.deleteItem(ctx, s.pathPolTable, "path", strings.Trim(path, "/")), "failed to remove path policy from dynamo")
+}
+
+// ListPathPolicies returns every Policy attached to a path prefix in dynamo, keyed by prefix. Attachments
+// pointing at policies that no longer exist are skipped.
+func (s Store) ListPathPolicies(ctx context.Context) (map[string]docshelf.Policy, error) {
+	var attached []PathPolicy
+	if err := s.scanItems(ctx, s.pathPolTable, &attached); err != nil {
+		return nil, errors.Wrap(err, "failed to list path policies from dynamo")
+	}
+
+	policies := make(map[string]docshelf.Policy)
+	for _, pp := range attached {
+		policy, err := s.GetPolicy(ctx, pp.PolicyID)
+		if err != nil {
+			if docshelf.CheckNotFound(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		policies[pp.Path] = policy
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,84 @@
+package dynamo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dyna "github.com/aws/aws-sdk-go-v2/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/expression"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+)
+
+// GetPasswordReset fetches an existing docshelf PasswordReset from dynamo.
+func (s Store) GetPasswordReset(ctx context.Context, id string) (docshelf.PasswordReset, error) {
+	var reset docshelf.PasswordReset
+
+	if err := s.getItem(ctx, s.resetTable, "id", id, &reset); err != nil {
+		return reset, errors.Wrap(err, "failed to fetch password reset from dynamo")
+	}
+
+	if reset.ID == "" {
+		return reset, docshelf.NewErrNotFound("password reset does not exist")
+	}
+
+	return reset, nil
+}
+
+// PutPasswordReset stores a new docshelf PasswordReset in dynamo.
+func (s Store) PutPasswordReset(ctx context.Context, reset docshelf.PasswordReset) error {
+	if reset.ID == "" {
+		return errors.New("cannot store a password reset without an id")
+	}
+
+	return errors.Wrap(s.putItem(ctx, s.resetTable, reset), "failed to put password reset into dynamo")
+}
+
+// UsePasswordReset marks a docshelf PasswordReset in dynamo as used. A PasswordReset can only be used once,
+// so using it again results in a conflict, even if both uses happen at the same time.
+func (s Store) UsePasswordReset(ctx context.Context, id string) error {
+	reset, err := s.GetPasswordReset(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if reset.UsedAt != nil {
+		return docshelf.NewErrConflict("password reset has already been used")
+	}
+
+	usedAt := time.Now()
+	reset.UsedAt = &usedAt
+	marshaled, err := dyna.MarshalMap(&reset)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal password reset for dynamo")
+	}
+
+	// unset pointers are stored as NULL, so the attribute may exist without being set
+	usedAtName := expression.Name("usedAt")
+	cond := expression.Name("id").AttributeExists().
+		And(usedAtName.AttributeNotExists().Or(usedAtName.AttributeType(expression.Null)))
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return errors.Wrap(err, "failed to build password reset condition")
+	}
+
+	input := dynamodb.PutItemInput{
+		TableName:                 aws.String(s.resetTable),
+		Item:                      marshaled,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	if _, err := s.client.PutItemRequest(&input).Send(); err != nil {
+		if checkConditionFailed(err) {
+			return docshelf.NewErrConflict("password reset has already been used")
+		}
+
+		return errors.Wrap(err, "failed to use password reset in dynamo")
+	}
+
+	return nil
+}
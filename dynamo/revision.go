@@ -0,0 +1,78 @@
+package dynamo
+
+import (
+	"context"
+
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// ListRevisions fetches the revision history of a docshelf Doc from dynamo, oldest first. Revision content
+// is omitted from the listing and can be retrieved with GetRevision.
+func (s Store) ListRevisions(ctx context.Context, path string) ([]docshelf.Revision, error) {
+	docID, err := s.resolveDocID(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	revs := make([]docshelf.Revision, 0)
+	if err := s.getItems(ctx, s.revTable, "docId", docID, &revs); err != nil {
+		return nil, errors.Wrap(err, "failed to list revisions from dynamo")
+	}
+
+	for i := range revs {
+		revs[i].Content = ""
+	}
+
+	return revs, nil
+}
+
+// GetRevision fetches a single revision of a docshelf Doc from dynamo, including its content.
+func (s Store) GetRevision(ctx context.Context, path, id string) (docshelf.Revision, error) {
+	var rev docshelf.Revision
+
+	docID, err := s.resolveDocID(ctx, path)
+	if err != nil {
+		return rev, err
+	}
+
+	key, err := makeKey("docId", docID)
+	if err != nil {
+		return rev, errors.Wrap(err, "failed to make key")
+	}
+
+	rangeKey, err := makeKey("id", id)
+	if err != nil {
+		return rev, errors.Wrap(err, "failed to make key")
+	}
+
+	key["id"] = rangeKey["id"]
+	if err := s.getItemByKey(ctx, s.revTable, key, &rev); err != nil {
+		return rev, errors.Wrap(err, "failed to fetch revision from dynamo")
+	}
+
+	if rev.ID == "" {
+		return rev, docshelf.NewErrNotFound("revision does not exist")
+	}
+
+	return rev, nil
+}
+
+// resolveDocID takes either a doc path or ID and returns the ID of the doc.
+func (s Store) resolveDocID(ctx context.Context, path string) (string, error) {
+	if _, err := xid.FromString(path); err == nil {
+		return path, nil
+	}
+
+	var doc docshelf.Doc
+	if err := s.getItem(ctx, s.docTable, "path", path, &doc); err != nil {
+		return "", err
+	}
+
+	if doc.ID == "" {
+		return "", docshelf.NewErrNotFound("")
+	}
+
+	return doc.ID, nil
+}
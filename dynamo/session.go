@@ -0,0 +1,111 @@
+package dynamo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/expression"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// GetSession fetches an existing docshelf Session from dynamo.
+func (s Store) GetSession(ctx context.Context, id string) (docshelf.Session, error) {
+	var session docshelf.Session
+
+	if err := s.getItem(ctx, s.sessionTable, "id", id, &session); err != nil {
+		return session, errors.Wrap(err, "failed to fetch session from dynamo")
+	}
+
+	if session.ID == "" {
+		return session, docshelf.NewErrNotFound("session does not exist")
+	}
+
+	return session, nil
+}
+
+// PutSession creates a new docshelf Session or updates an existing one in dynamo.
+func (s Store) PutSession(ctx context.Context, session docshelf.Session) (string, error) {
+	if session.ID == "" {
+		session.ID = xid.New().String()
+		session.CreatedAt = time.Now()
+	}
+
+	if err := s.putItem(ctx, s.sessionTable, session); err != nil {
+		return "", errors.Wrap(err, "failed to put session into dynamo")
+	}
+
+	return session.ID, nil
+}
+
+// RevokeSession marks a docshelf Session in dynamo as revoked so it can't be used anymore. Revoking a
+// Session that doesn't exist is not an error.
+func (s Store) RevokeSession(ctx context.Context, id string) error {
+	session, err := s.GetSession(ctx, id)
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	revokedAt := time.Now()
+	session.RevokedAt = &revokedAt
+	return errors.Wrap(s.putItem(ctx, s.sessionTable, session), "failed to revoke session in dynamo")
+}
+
+// RevokeUserSessions marks every active docshelf Session belonging to a User in dynamo as revoked.
+func (s Store) RevokeUserSessions(ctx context.Context, userID string) (int, error) {
+	filter := expression.Name("userId").Equal(expression.Value(userID))
+
+	var sessions []docshelf.Session
+	if err := s.scanItemsFilter(ctx, s.sessionTable, &filter, &sessions); err != nil {
+		return 0, errors.Wrap(err, "failed to list user sessions from dynamo")
+	}
+
+	var revoked int
+	revokedAt := time.Now()
+	for _, session := range sessions {
+		if session.RevokedAt != nil {
+			continue
+		}
+
+		session.RevokedAt = &revokedAt
+		if err := s.putItem(ctx, s.sessionTable, session); err != nil {
+			return revoked, errors.Wrap(err, "failed to revoke session in dynamo")
+		}
+
+		revoked++
+	}
+
+	return revoked, nil
+}
+
+// PurgeSessions deletes every docshelf Session from dynamo that expired before the given time.
+func (s Store) PurgeSessions(ctx context.Context, before time.Time) (int, error) {
+	var sessions []docshelf.Session
+	if err := s.scanItems(ctx, s.sessionTable, &sessions); err != nil {
+		return 0, errors.Wrap(err, "failed to list expired sessions from dynamo")
+	}
+
+	var purged int
+	for _, session := range sessions {
+		if !session.ExpiresAt.Before(before) {
+			continue
+		}
+
+		if err := s.deleteItem(ctx, s.sessionTable, "id", session.ID); err != nil {
+			return purged, errors.Wrap(err, "failed to purge session from dynamo")
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
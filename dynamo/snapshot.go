@@ -0,0 +1,221 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/expression"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// A snapshotDoc is the dynamo data structure of a Doc captured by a Snapshot.
+type snapshotDoc struct {
+	SnapshotID string `json:"snapshotId"`
+	docshelf.Doc
+}
+
+// TakeSnapshot captures the metadata, tag membership and content of every docshelf Doc currently stored
+// and saves it as a new Snapshot in dynamo.
+func (s Store) TakeSnapshot(ctx context.Context, userID string) (string, error) {
+	var docs []docshelf.Doc
+	if err := s.scanItems(ctx, s.docTable, &docs); err != nil {
+		return "", errors.Wrap(err, "failed to read docs for snapshot")
+	}
+
+	tags, err := s.tagsByPath(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read tags for snapshot")
+	}
+
+	snap := docshelf.Snapshot{
+		ID:        xid.New().String(),
+		DocCount:  len(docs),
+		CreatedBy: userID,
+		CreatedAt: time.Now(),
+	}
+
+	for _, doc := range docs {
+		if !doc.IsDir {
+			content, err := s.fs.ReadFile(doc.Path)
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to read content for snapshot: %s", doc.Path)
+			}
+
+			doc.Content = string(content)
+		}
+
+		doc.Tags = union(doc.Tags, tags[doc.Path])
+		if err := s.putItem(ctx, s.snapDocTable, snapshotDoc{snap.ID, doc}); err != nil {
+			return "", errors.Wrap(err, "failed to put snapshot doc into dynamo")
+		}
+	}
+
+	// metadata is written last so partially captured snapshots never show up in listings
+	if err := s.putItem(ctx, s.snapTable, snap); err != nil {
+		return "", errors.Wrap(err, "failed to put snapshot into dynamo")
+	}
+
+	return snap.ID, nil
+}
+
+// GetSnapshot fetches a Snapshot from dynamo along with the metadata of every Doc it captured. Content is
+// omitted.
+func (s Store) GetSnapshot(ctx context.Context, id string) (docshelf.Snapshot, error) {
+	var snap docshelf.Snapshot
+	if err := s.getItem(ctx, s.snapTable, "id", id, &snap); err != nil {
+		return snap, errors.Wrap(err, "failed to fetch snapshot from dynamo")
+	}
+
+	if snap.ID == "" {
+		return snap, docshelf.NewErrNotFound("snapshot does not exist")
+	}
+
+	docs, err := s.listSnapshotDocs(ctx, id, "")
+	if err != nil {
+		return snap, err
+	}
+
+	for i := range docs {
+		docs[i].Content = ""
+	}
+
+	snap.Docs = docs
+	return snap, nil
+}
+
+// ListSnapshots returns all Snapshots stored in dynamo. Captured Docs are omitted.
+func (s Store) ListSnapshots(ctx context.Context) ([]docshelf.Snapshot, error) {
+	snaps := make([]docshelf.Snapshot, 0)
+	if err := s.scanItems(ctx, s.snapTable, &snaps); err != nil {
+		return nil, errors.Wrap(err, "failed to list snapshots from dynamo")
+	}
+
+	return snaps, nil
+}
+
+// RestoreSnapshot writes every Doc captured by a Snapshot at or under the given path prefix back to the
+// shelf. Docs are restored through PutDoc, so created* fields and the text index stay consistent, and
+// each restore is recorded as a new revision authored by the given user. Docs created after the Snapshot
+// was taken are left alone. Nothing is restored if any captured Doc is locked by another user or has been
+// replaced by a folder, or vice versa.
+func (s Store) RestoreSnapshot(ctx context.Context, id, prefix, userID string) error {
+	var snap docshelf.Snapshot
+	if err := s.getItem(ctx, s.snapTable, "id", id, &snap); err != nil {
+		return errors.Wrap(err, "failed to fetch snapshot from dynamo")
+	}
+
+	if snap.ID == "" {
+		return docshelf.NewErrNotFound("snapshot does not exist")
+	}
+
+	docs, err := s.listSnapshotDocs(ctx, id, prefix)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkRestore(ctx, docs, userID); err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		tags := doc.Tags
+		doc.UpdatedBy = userID
+		doc.Version = 0 // restores always win over whatever is currently stored
+		doc.Message = fmt.Sprintf("restored from snapshot %s", id)
+		if _, err := s.PutDoc(ctx, doc); err != nil {
+			return errors.Wrapf(err, "failed to restore doc: %s", doc.Path)
+		}
+
+		if len(tags) > 0 {
+			if err := s.TagDoc(ctx, doc.Path, tags...); err != nil {
+				return errors.Wrapf(err, "failed to restore tags for doc: %s", doc.Path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkRestore makes sure every captured Doc can be written back over whatever is currently stored at its
+// path, so a restore doesn't stop halfway through.
+func (s Store) checkRestore(ctx context.Context, docs []docshelf.Doc, userID string) error {
+	var locked, replaced []string
+	for _, doc := range docs {
+		var existing docshelf.Doc
+		if err := s.getItem(ctx, s.docTable, "path", doc.Path, &existing); err != nil {
+			return errors.Wrap(err, "failed to fetch doc from dynamo")
+		}
+
+		if existing.ID == "" {
+			continue
+		}
+
+		if existing.IsDir != doc.IsDir {
+			replaced = append(replaced, doc.Path)
+			continue
+		}
+
+		lock, err := s.getLock(ctx, existing.ID)
+		if err != nil {
+			return err
+		}
+
+		if lock.Blocks(userID) {
+			locked = append(locked, doc.Path)
+		}
+	}
+
+	if len(locked) > 0 {
+		return docshelf.NewErrLocked(fmt.Sprintf("docs are locked by another user: %s", strings.Join(locked, ", ")))
+	}
+
+	if len(replaced) > 0 {
+		return docshelf.NewErrConflict(fmt.Sprintf("folders and docs have replaced each other: %s", strings.Join(replaced, ", ")))
+	}
+
+	return nil
+}
+
+// listSnapshotDocs returns the Docs captured by a Snapshot that are at or nested under the given path.
+func (s Store) listSnapshotDocs(ctx context.Context, id, prefix string) ([]docshelf.Doc, error) {
+	keyCond := expression.Key("snapshotId").Equal(expression.Value(id))
+	if scope := strings.Trim(prefix, "/"); scope != "" {
+		keyCond = keyCond.And(expression.Key("path").BeginsWith(scope))
+	}
+
+	var snapDocs []snapshotDoc
+	if err := s.queryItems(ctx, s.snapDocTable, keyCond, &snapDocs); err != nil {
+		return nil, errors.Wrap(err, "failed to list snapshot docs from dynamo")
+	}
+
+	// the key condition also matches siblings sharing the prefix, e.g. "hrx/" when restoring "hr"
+	docs := make([]docshelf.Doc, 0, len(snapDocs))
+	for _, snapDoc := range snapDocs {
+		if docshelf.InPath(snapDoc.Doc.Path, prefix) {
+			docs = append(docs, snapDoc.Doc)
+		}
+	}
+
+	return docs, nil
+}
+
+// tagsByPath inverts the tag table into a mapping of doc paths to the tags applied to them.
+func (s Store) tagsByPath(ctx context.Context) (map[string][]string, error) {
+	var tags []Tag
+	if err := s.scanItems(ctx, s.tagTable, &tags); err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string][]string)
+	for _, tag := range tags {
+		for _, p := range tag.Paths {
+			paths[p] = append(paths[p], tag.Tag)
+		}
+	}
+
+	return paths, nil
+}
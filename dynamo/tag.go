@@ -0,0 +1,291 @@
+package dynamo
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dyna "github.com/aws/aws-sdk-go-v2/service/dynamodb/dynamodbattribute"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+)
+
+// ListTags returns every tag in dynamo along with how many readable docs have it, most used first. Pins
+// aren't included, and neither are tags without any readable docs.
+func (s Store) ListTags(ctx context.Context, canRead func(docshelf.Doc) bool) ([]docshelf.TagCount, error) {
+	var tags []Tag
+	if err := s.scanItems(ctx, s.tagTable, &tags); err != nil {
+		return nil, errors.Wrap(err, "failed to scan tags from dynamo")
+	}
+
+	var docs []docshelf.Doc
+	if err := s.scanItems(ctx, s.docTable, &docs); err != nil {
+		return nil, errors.Wrap(err, "failed to scan docs from dynamo")
+	}
+
+	readable := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		readable[doc.Path] = canRead(doc)
+	}
+
+	counts := make([]docshelf.TagCount, 0, len(tags))
+	for _, tag := range tags {
+		if docshelf.IsPin(tag.Tag) {
+			continue
+		}
+
+		var count int
+		for _, path := range tag.Paths {
+			if readable[path] {
+				count++
+			}
+		}
+
+		if count > 0 {
+			counts = append(counts, docshelf.TagCount{Tag: tag.Tag, Count: count})
+		}
+	}
+
+	docshelf.SortTagCounts(counts)
+
+	return counts, nil
+}
+
+// UntagDoc removes the given tags from an existing document. Tags left without any docs are deleted, and
+// tags the document doesn't have are ignored.
+func (s Store) UntagDoc(ctx context.Context, path string, tags ...string) error {
+	doc, err := s.GetDoc(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	var items []dynamodb.TransactWriteItem
+	for _, t := range tags {
+		var tag Tag
+		if err := s.getItem(ctx, s.tagTable, "tag", t, &tag); err != nil {
+			return err
+		}
+
+		if !contains(tag.Paths, doc.Path) {
+			continue
+		}
+
+		item, err := s.untagItem(ctx, t, doc.Path)
+		if err != nil {
+			return err
+		}
+
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	input := dynamodb.TransactWriteItemsInput{TransactItems: items}
+	if _, err := s.client.TransactWriteItemsRequest(&input).Send(); err != nil {
+		return errors.Wrap(err, "failed to untag document in dynamo")
+	}
+
+	// pins aren't searchable, so there's nothing to re-index when only unpinning
+	if doc.IsDir || !hasSearchableTag(tags) {
+		return nil
+	}
+
+	return errors.Wrap(s.indexDoc(ctx, doc), "failed to text index untagged doc")
+}
+
+// RenameTag gives a tag a new name on every doc that has it. Renaming onto a tag that already exists is a
+// conflict, those tags have to be merged instead.
+func (s Store) RenameTag(ctx context.Context, from, to string) error {
+	if err := checkTagNames(from, to); err != nil {
+		return err
+	}
+
+	if from == to {
+		return nil
+	}
+
+	var tag, existing Tag
+	if err := s.getItem(ctx, s.tagTable, "tag", from, &tag); err != nil {
+		return err
+	}
+
+	if tag.Tag == "" {
+		return docshelf.NewErrNotFound("tag does not exist")
+	}
+
+	if err := s.getItem(ctx, s.tagTable, "tag", to, &existing); err != nil {
+		return err
+	}
+
+	if existing.Tag != "" {
+		return docshelf.NewErrConflict("a tag with that name already exists")
+	}
+
+	renamed, err := dyna.MarshalMap(&Tag{Tag: to, Paths: tag.Paths})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal tag for dynamo")
+	}
+
+	key, err := makeKey("tag", from)
+	if err != nil {
+		return errors.Wrap(err, "failed to make key")
+	}
+
+	input := dynamodb.TransactWriteItemsInput{
+		TransactItems: []dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{
+				TableName:                aws.String(s.tagTable),
+				Item:                     renamed,
+				ConditionExpression:      aws.String("attribute_not_exists(#tag)"),
+				ExpressionAttributeNames: map[string]string{"#tag": "tag"},
+			}},
+			{Delete: &dynamodb.Delete{TableName: aws.String(s.tagTable), Key: key}},
+		},
+	}
+
+	if _, err := s.client.TransactWriteItemsRequest(&input).Send(); err != nil {
+		return errors.Wrap(err, "failed to rename tag in dynamo")
+	}
+
+	if err := s.retagTrash(ctx, to, from); err != nil {
+		return err
+	}
+
+	return s.reindexPaths(ctx, tag.Paths)
+}
+
+// MergeTags moves every doc with one of the from tags over to the into tag, which is created if it doesn't
+// exist yet. The from tags are deleted afterwards.
+func (s Store) MergeTags(ctx context.Context, into string, from ...string) error {
+	if err := checkTagNames(append([]string{into}, from...)...); err != nil {
+		return err
+	}
+
+	var merged Tag
+	if err := s.getItem(ctx, s.tagTable, "tag", into, &merged); err != nil {
+		return err
+	}
+
+	merged.Tag = into
+	var moved []string
+	var items []dynamodb.TransactWriteItem
+	for _, f := range from {
+		if f == into {
+			continue
+		}
+
+		var tag Tag
+		if err := s.getItem(ctx, s.tagTable, "tag", f, &tag); err != nil {
+			return err
+		}
+
+		if tag.Tag == "" {
+			return docshelf.NewErrNotFound("tag does not exist")
+		}
+
+		key, err := makeKey("tag", f)
+		if err != nil {
+			return errors.Wrap(err, "failed to make key")
+		}
+
+		merged.Paths = union(merged.Paths, tag.Paths)
+		moved = union(moved, tag.Paths)
+		items = append(items, dynamodb.TransactWriteItem{
+			Delete: &dynamodb.Delete{TableName: aws.String(s.tagTable), Key: key},
+		})
+	}
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	marshaled, err := dyna.MarshalMap(&merged)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal tag for dynamo")
+	}
+
+	items = append(items, dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{TableName: aws.String(s.tagTable), Item: marshaled},
+	})
+
+	input := dynamodb.TransactWriteItemsInput{TransactItems: items}
+	if _, err := s.client.TransactWriteItemsRequest(&input).Send(); err != nil {
+		return errors.Wrap(err, "failed to merge tags in dynamo")
+	}
+
+	if err := s.retagTrash(ctx, into, from...); err != nil {
+		return err
+	}
+
+	return s.reindexPaths(ctx, moved)
+}
+
+// retagTrash replaces the from tags with the into tag on every doc in the trash, so restoring a doc doesn't
+// bring back a tag that has since been renamed or merged away.
+func (s Store) retagTrash(ctx context.Context, into string, from ...string) error {
+	var docs []docshelf.Doc
+	if err := s.scanItems(ctx, s.trashTable, &docs); err != nil {
+		return errors.Wrap(err, "failed to read trash from dynamo")
+	}
+
+	for _, doc := range docs {
+		remaining := without(doc.Tags, from)
+		if len(remaining) == len(doc.Tags) {
+			continue
+		}
+
+		doc.Tags = union(remaining, []string{into})
+		if err := s.putItem(ctx, s.trashTable, &doc); err != nil {
+			return errors.Wrapf(err, "failed to retag trashed doc: %s", doc.Path)
+		}
+	}
+
+	return nil
+}
+
+// reindexPaths indexes the docs at the given paths again after their tags have changed.
+func (s Store) reindexPaths(ctx context.Context, paths []string) error {
+	tagged, err := s.tagsByPath(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to read tags for retagged docs")
+	}
+
+	for _, path := range paths {
+		doc, err := s.GetDoc(ctx, path)
+		if err != nil {
+			if docshelf.CheckNotFound(err) {
+				continue
+			}
+
+			return errors.Wrapf(err, "failed to read retagged doc: %s", path)
+		}
+
+		if doc.IsDir {
+			continue
+		}
+
+		doc.Tags = docshelf.WithoutPins(tagged[doc.Path])
+		if err := s.ti.Index(ctx, doc); err != nil {
+			return errors.Wrapf(err, "failed to text index retagged doc: %s", doc.Path)
+		}
+	}
+
+	return nil
+}
+
+// checkTagNames makes sure tags being renamed or merged are regular, named tags.
+func checkTagNames(tags ...string) error {
+	for _, tag := range tags {
+		if tag == "" {
+			return errors.New("tags must have a name")
+		}
+
+		if docshelf.IsPin(tag) {
+			return errors.New("pins can not be renamed or merged")
+		}
+	}
+
+	return nil
+}
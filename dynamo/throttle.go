@@ -0,0 +1,128 @@
+package dynamo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dyna "github.com/aws/aws-sdk-go-v2/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/expression"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// how many times an update to login attempts is retried when it races with another one
+const maxAttemptRetries = 5
+
+// GetLoginAttempts fetches the docshelf LoginAttempts for a key from dynamo.
+func (s Store) GetLoginAttempts(ctx context.Context, key string) (docshelf.LoginAttempts, error) {
+	var attempts docshelf.LoginAttempts
+
+	if err := s.getItem(ctx, s.attemptTable, "key", key, &attempts); err != nil {
+		return attempts, errors.Wrap(err, "failed to fetch login attempts from dynamo")
+	}
+
+	if attempts.Key == "" {
+		return attempts, docshelf.NewErrNotFound("no login attempts for key")
+	}
+
+	return attempts, nil
+}
+
+// UpdateLoginAttempts applies an update to the docshelf LoginAttempts for a key in dynamo. Keys without any
+// attempts yet start out empty. The write is conditional on nothing else having changed the attempts in the
+// meantime, and is retried with fresh attempts if something did.
+func (s Store) UpdateLoginAttempts(ctx context.Context, key string, update func(docshelf.LoginAttempts) docshelf.LoginAttempts) (docshelf.LoginAttempts, error) {
+	for i := 0; i < maxAttemptRetries; i++ {
+		current, err := s.GetLoginAttempts(ctx, key)
+		if err != nil && !docshelf.CheckNotFound(err) {
+			return current, err
+		}
+
+		cond := expression.Name("key").AttributeNotExists()
+		if current.Key != "" {
+			cond = expression.Name("failures").Equal(expression.Value(current.Failures)).
+				And(expression.Name("lastFailure").Equal(expression.Value(current.LastFailure)))
+		}
+
+		current.Key = key
+		updated := update(current)
+		updated.Key = key
+		if err := s.putAttempts(updated, cond); err != nil {
+			if checkConditionFailed(err) {
+				continue
+			}
+
+			return updated, errors.Wrap(err, "failed to update login attempts in dynamo")
+		}
+
+		return updated, nil
+	}
+
+	return docshelf.LoginAttempts{}, docshelf.NewErrConflict("login attempts kept changing while being updated")
+}
+
+func (s Store) putAttempts(attempts docshelf.LoginAttempts, cond expression.ConditionBuilder) error {
+	marshaled, err := dyna.MarshalMap(&attempts)
+	if err != nil {
+		return err
+	}
+
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return err
+	}
+
+	input := dynamodb.PutItemInput{
+		TableName:                 aws.String(s.attemptTable),
+		Item:                      marshaled,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}
+
+	_, err = s.client.PutItemRequest(&input).Send()
+	return err
+}
+
+// RemoveLoginAttempts clears the docshelf LoginAttempts for a key from dynamo.
+func (s Store) RemoveLoginAttempts(ctx context.Context, key string) error {
+	return errors.Wrap(s.deleteItem(ctx, s.attemptTable, "key", key), "failed to remove login attempts from dynamo")
+}
+
+// PutAuditEvent records a new docshelf AuditEvent in dynamo.
+func (s Store) PutAuditEvent(ctx context.Context, event docshelf.AuditEvent) error {
+	if event.ID == "" {
+		event.ID = xid.New().String()
+	}
+
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	return errors.Wrap(s.putItem(ctx, s.auditTable, event), "failed to put audit event into dynamo")
+}
+
+// ListAuditEvents returns the docshelf AuditEvents recorded in dynamo since the given time, oldest first.
+func (s Store) ListAuditEvents(ctx context.Context, since time.Time) ([]docshelf.AuditEvent, error) {
+	var all []docshelf.AuditEvent
+	if err := s.scanItems(ctx, s.auditTable, &all); err != nil {
+		return nil, errors.Wrap(err, "failed to list audit events from dynamo")
+	}
+
+	events := make([]docshelf.AuditEvent, 0, len(all))
+	for _, event := range all {
+		if !event.CreatedAt.Before(since) {
+			events = append(events, event)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].CreatedAt.Before(events[j].CreatedAt)
+	})
+
+	return events, nil
+}
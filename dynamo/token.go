@@ -0,0 +1,60 @@
+package dynamo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/expression"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+	"github.com/rs/xid"
+)
+
+// GetToken fetches an existing docshelf APIToken from dynamo.
+func (s Store) GetToken(ctx context.Context, id string) (docshelf.APIToken, error) {
+	var token docshelf.APIToken
+
+	if err := s.getItem(ctx, s.tokenTable, "id", id, &token); err != nil {
+		return token, errors.Wrap(err, "failed to fetch token from dynamo")
+	}
+
+	if token.ID == "" {
+		return token, docshelf.NewErrNotFound("token does not exist")
+	}
+
+	return token, nil
+}
+
+// ListTokens returns all docshelf APITokens belonging to a User in dynamo.
+func (s Store) ListTokens(ctx context.Context, userID string) ([]docshelf.APIToken, error) {
+	filter := expression.Name("userId").Equal(expression.Value(userID))
+
+	tokens := make([]docshelf.APIToken, 0)
+	if err := s.scanItemsFilter(ctx, s.tokenTable, &filter, &tokens); err != nil {
+		return nil, errors.Wrap(err, "failed to list tokens from dynamo")
+	}
+
+	return tokens, nil
+}
+
+// PutToken creates a new docshelf APIToken or updates an existing one in dynamo.
+func (s Store) PutToken(ctx context.Context, token docshelf.APIToken) (string, error) {
+	if token.ID == "" {
+		token.ID = xid.New().String()
+	}
+
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	if err := s.putItem(ctx, s.tokenTable, token); err != nil {
+		return "", errors.Wrap(err, "failed to put token into dynamo")
+	}
+
+	return token.ID, nil
+}
+
+// RemoveToken deletes a docshelf APIToken from dynamo so it can't be used anymore.
+func (s Store) RemoveToken(ctx context.Context, id string) error {
+	return errors.Wrap(s.deleteItem(ctx, s.tokenTable, "id", id), "failed to remove token from dynamo")
+}
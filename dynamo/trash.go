@@ -0,0 +1,169 @@
+package dynamo
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dyna "github.com/aws/aws-sdk-go-v2/service/dynamodb/dynamodbattribute"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+)
+
+// ListTrash returns the metadata of every docshelf Doc currently in the trash in dynamo.
+func (s Store) ListTrash(ctx context.Context) ([]docshelf.Doc, error) {
+	docs := make([]docshelf.Doc, 0)
+	if err := s.scanItems(ctx, s.trashTable, &docs); err != nil {
+		return nil, errors.Wrap(err, "failed to list trash from dynamo")
+	}
+
+	for i := range docs {
+		docs[i].Content = ""
+	}
+
+	return docs, nil
+}
+
+// RestoreDoc takes a docshelf Doc out of the trash and puts it back at its original path with its original
+// ID, content and tags. Restoring fails if another doc has since been created at the same path.
+func (s Store) RestoreDoc(ctx context.Context, id string) error {
+	doc, err := s.getTrashed(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// need to check the destination up front so existing content is never overwritten
+	var existing docshelf.Doc
+	if err := s.getItem(ctx, s.docTable, "path", doc.Path, &existing); err != nil {
+		return errors.Wrap(err, "could not verify existing file")
+	}
+
+	if existing.Path != "" {
+		return errors.Errorf("a doc already exists at %s", doc.Path)
+	}
+
+	if err := s.fs.WriteFile(doc.Path, []byte(doc.Content)); err != nil {
+		return errors.Wrap(err, "failed to write restored doc to file store")
+	}
+
+	restored := doc
+	restored.Content = ""
+	restored.DeletedAt = nil
+
+	marshaled, err := dyna.MarshalMap(&restored)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal doc for dynamo")
+	}
+
+	key, err := makeKey("id", doc.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to make key")
+	}
+
+	input := dynamodb.TransactWriteItemsInput{
+		TransactItems: []dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{
+				TableName:                aws.String(s.docTable),
+				Item:                     marshaled,
+				ConditionExpression:      aws.String("attribute_not_exists(#path)"),
+				ExpressionAttributeNames: map[string]string{"#path": "path"},
+			}},
+			{Delete: &dynamodb.Delete{TableName: aws.String(s.trashTable), Key: key}},
+		},
+	}
+
+	if _, err := s.client.TransactWriteItemsRequest(&input).Send(); err != nil {
+		if err := s.fs.RemoveFile(doc.Path); err != nil { // need to rollback file storage if restore fails
+			return errors.Wrapf(err, "cleanup failed for file: %s", doc.Path)
+		}
+
+		return errors.Wrap(err, "failed to restore doc from trash in dynamo")
+	}
+
+	if err := s.tagPath(ctx, doc.Path, doc.Tags...); err != nil {
+		return errors.Wrap(err, "failed to restore doc tags")
+	}
+
+	restored.Content = doc.Content
+	return errors.Wrap(s.indexDoc(ctx, restored), "failed to text index restored doc")
+}
+
+// PurgeDoc permanently removes a docshelf Doc and its revision history from the trash in dynamo.
+func (s Store) PurgeDoc(ctx context.Context, id string) error {
+	if _, err := s.getTrashed(ctx, id); err != nil {
+		return err
+	}
+
+	return s.purge(ctx, id)
+}
+
+// PurgeTrash permanently removes every docshelf Doc that was put in the trash before the given time. The
+// number of purged docs is returned.
+func (s Store) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	docs, err := s.ListTrash(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int
+	for _, doc := range docs {
+		if doc.DeletedAt == nil || !doc.DeletedAt.Before(before) {
+			continue
+		}
+
+		if err := s.purge(ctx, doc.ID); err != nil {
+			return purged, err
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (s Store) getTrashed(ctx context.Context, id string) (docshelf.Doc, error) {
+	var doc docshelf.Doc
+	if err := s.getItem(ctx, s.trashTable, "id", id, &doc); err != nil {
+		return doc, errors.Wrap(err, "failed to fetch doc from trash")
+	}
+
+	if doc.ID == "" {
+		return doc, docshelf.NewErrNotFound("doc is not in the trash")
+	}
+
+	return doc, nil
+}
+
+// purge deletes the revision history of a trashed doc before the doc itself, so a failed purge can always
+// be retried.
+func (s Store) purge(ctx context.Context, id string) error {
+	var revs []docshelf.Revision
+	if err := s.getItems(ctx, s.revTable, "docId", id, &revs); err != nil {
+		return errors.Wrap(err, "failed to list revisions from dynamo")
+	}
+
+	for _, rev := range revs {
+		key, err := makeKey("docId", id)
+		if err != nil {
+			return errors.Wrap(err, "failed to make key")
+		}
+
+		rangeKey, err := makeKey("id", rev.ID)
+		if err != nil {
+			return errors.Wrap(err, "failed to make key")
+		}
+
+		key["id"] = rangeKey["id"]
+		input := dynamodb.DeleteItemInput{
+			TableName: aws.String(s.revTable),
+			Key:       key,
+		}
+
+		if _, err := s.client.DeleteItemRequest(&input).Send(); err != nil {
+			return errors.Wrap(err, "failed to delete revision from dynamo")
+		}
+	}
+
+	return errors.Wrap(s.deleteItem(ctx, s.trashTable, "id", id), "failed to purge doc from dynamo")
+}
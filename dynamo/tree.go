@@ -0,0 +1,98 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/expression"
+	"github.com/docshelf/docshelf"
+	"github.com/pkg/errors"
+)
+
+// ListTree returns the immediate children of a folder path from dynamo. Docs nested more deeply are collapsed
+// into the folders containing them. An empty path lists the root of the shelf.
+func (s Store) ListTree(ctx context.Context, path string) ([]docshelf.Doc, error) {
+	nested, err := s.listPrefix(ctx, docshelf.DirPrefix(path))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tree from dynamo")
+	}
+
+	return docshelf.TreeChildren(path, nested), nil
+}
+
+// RemoveDir recursively removes a folder and every docshelf Doc nested under it from dynamo. Nothing is
+// removed if any nested doc is locked by someone other than the given user.
+func (s Store) RemoveDir(ctx context.Context, path, userID string) error {
+	prefix := docshelf.DirPrefix(path)
+	if prefix == "" {
+		return errors.New("can not remove the root folder")
+	}
+
+	var dir docshelf.Doc
+	if err := s.getItem(ctx, s.docTable, "path", prefix[:len(prefix)-1], &dir); err != nil {
+		return errors.Wrap(err, "failed to get folder from dynamo")
+	}
+
+	// docs have to go through the trash, so only folders can be removed here
+	if dir.ID != "" && !dir.IsDir {
+		return docshelf.NewErrBadQuery("path is not a folder")
+	}
+
+	nested, err := s.listPrefix(ctx, prefix)
+	if err != nil {
+		return errors.Wrap(err, "failed to list folder contents from dynamo")
+	}
+
+	for _, doc := range nested {
+		lock, err := s.getLock(ctx, doc.ID)
+		if err != nil {
+			return err
+		}
+
+		if lock.Blocks(userID) {
+			return docshelf.NewErrLocked(fmt.Sprintf("%s is locked by another user", doc.Path))
+		}
+	}
+
+	dirs := []string{prefix[:len(prefix)-1]}
+	for _, doc := range nested {
+		if doc.IsDir {
+			dirs = append(dirs, doc.Path)
+			continue
+		}
+
+		if err := s.RemoveDoc(ctx, doc.Path, userID); err != nil {
+			return errors.Wrapf(err, "failed to remove nested doc: %s", doc.Path)
+		}
+	}
+
+	// implied folders were never stored, but deleting a missing item is harmless
+	for _, dir := range dirs {
+		if err := s.deleteItem(ctx, s.docTable, "path", dir); err != nil {
+			return errors.Wrap(err, "failed to remove folder from dynamo")
+		}
+	}
+
+	return nil
+}
+
+// listPrefix returns every doc whose path starts with the given prefix, sorted by path.
+func (s Store) listPrefix(ctx context.Context, prefix string) ([]docshelf.Doc, error) {
+	var filter *expression.ConditionBuilder
+	if prefix != "" {
+		cond := expression.Name("path").BeginsWith(prefix)
+		filter = &cond
+	}
+
+	docs := make([]docshelf.Doc, 0)
+	if err := s.scanItemsFilter(ctx, s.docTable, filter, &docs); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].Path < docs[j].Path
+	})
+
+	return docs, nil
+}
@@ -60,7 +60,8 @@ func (s Store) ListUsers(ctx context.Context) ([]docshelf.User, error) {
 	return users, nil
 }
 
-// PutUser creates a new docshelf User or updates an existing one in dynamodb.
+// PutUser creates a new docshelf User or updates an existing one in dynamodb. Group membership is managed
+// through the GroupStore, so the User's Groups are left untouched.
 func (s Store) PutUser(ctx context.Context, user docshelf.User) (string, error) {
 	if user.ID == "" {
 		if user.Email == "" {
@@ -72,6 +73,13 @@ func (s Store) PutUser(ctx context.Context, user docshelf.User) (string, error)
 
 	user.UpdatedAt = time.Now()
 
+	var existing docshelf.User
+	if err := s.getItem(ctx, s.userTable, "id", user.ID, &existing); err != nil {
+		return "", err
+	}
+
+	user.Groups = existing.Groups
+
 	marshaled, err := dyna.MarshalMap(&user)
 	if err != nil {
 		return "", err
@@ -12,6 +12,24 @@ type ErrRemoved struct {
 	msg string
 }
 
+// ErrConflict is a special error type for signaling that a write was based on
+// a stale version of an entity.
+type ErrConflict struct {
+	msg string
+}
+
+// ErrLocked is a special error type for signaling that an entity is locked by
+// another user.
+type ErrLocked struct {
+	msg string
+}
+
+// ErrBadQuery is a special error type for signaling that a search query or
+// listing options couldn't be understood.
+type ErrBadQuery struct {
+	msg string
+}
+
 // NewErrNotFound returns a new ErrNotFound as a normal error containing
 // the given message.
 func NewErrNotFound(msg string) error {
@@ -24,6 +42,24 @@ func NewErrRemoved(msg string) error {
 	return ErrRemoved{msg}
 }
 
+// NewErrConflict returns a new ErrConflict as a normal error containing the
+// given message.
+func NewErrConflict(msg string) error {
+	return ErrConflict{msg}
+}
+
+// NewErrLocked returns a new ErrLocked as a normal error containing the given
+// message.
+func NewErrLocked(msg string) error {
+	return ErrLocked{msg}
+}
+
+// NewErrBadQuery returns a new ErrBadQuery as a normal error containing the
+// given message.
+func NewErrBadQuery(msg string) error {
+	return ErrBadQuery{msg}
+}
+
 // Error implements the Error interface for ErrNotFound. Default messaging is
 // used if not supplied.
 func (e ErrNotFound) Error() string {
@@ -44,6 +80,36 @@ func (e ErrRemoved) Error() string {
 	return e.msg
 }
 
+// Error implements the Error interface for ErrConflict. Default messaging is
+// used if not supplied.
+func (e ErrConflict) Error() string {
+	if e.msg == "" {
+		return "entity has been modified"
+	}
+
+	return e.msg
+}
+
+// Error implements the Error interface for ErrLocked. Default messaging is
+// used if not supplied.
+func (e ErrLocked) Error() string {
+	if e.msg == "" {
+		return "entity is locked"
+	}
+
+	return e.msg
+}
+
+// Error implements the Error interface for ErrBadQuery. Default messaging is
+// used if not supplied.
+func (e ErrBadQuery) Error() string {
+	if e.msg == "" {
+		return "invalid query"
+	}
+
+	return e.msg
+}
+
 // CheckNotFound is a helper function for determining if an error type is
 // actually an ErrNotFound.
 func CheckNotFound(err error) bool {
@@ -57,3 +123,24 @@ func CheckRemoved(err error) bool {
 	_, ok := err.(ErrRemoved)
 	return ok
 }
+
+// CheckConflict is a helper function for determining if an error type is
+// actually an ErrConflict.
+func CheckConflict(err error) bool {
+	_, ok := err.(ErrConflict)
+	return ok
+}
+
+// CheckLocked is a helper function for determining if an error type is
+// actually an ErrLocked.
+func CheckLocked(err error) bool {
+	_, ok := err.(ErrLocked)
+	return ok
+}
+
+// CheckBadQuery is a helper function for determining if an error type is
+// actually an ErrBadQuery.
+func CheckBadQuery(err error) bool {
+	_, ok := err.(ErrBadQuery)
+	return ok
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/docshelf/docshelf"
+	"github.com/go-chi/chi"
+)
+
+// authorizeDoc fetches a Doc and makes sure the current user has at least the required access to it. If
+// they don't, or the Doc can't be fetched, an error response is written and false is returned.
+func (h DocHandler) authorizeDoc(w http.ResponseWriter, r *http.Request, path string, access docshelf.Access) (docshelf.Doc, bool) {
+	doc, err := h.docStore.GetDoc(r.Context(), path)
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return doc, false
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching document")
+		return doc, false
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return doc, false
+	}
+
+	allowed, err := h.canAccess(r.Context(), user, doc, access)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while verifying document access")
+		return doc, false
+	}
+
+	if !allowed {
+		forbidden(w, "you don't have access to this document")
+		return doc, false
+	}
+
+	return doc, true
+}
+
+// canAccess reports whether a user has at least the required access to a Doc. Policies inherited from the
+// Doc's path prefixes are taken into account.
+func (h DocHandler) canAccess(ctx context.Context, user docshelf.User, doc docshelf.Doc, access docshelf.Access) (bool, error) {
+	prefixes, err := h.pathPolicyStore.ListPathPolicies(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return docshelf.DocAccess(user, docshelf.WithEffectivePolicy(doc, prefixes)) >= access, nil
+}
+
+// filterReadable removes any Docs the current user isn't allowed to read.
+func (h DocHandler) filterReadable(r *http.Request, docs []docshelf.Doc) ([]docshelf.Doc, error) {
+	canRead, err := readChecker(r, h.pathPolicyStore)
+	if err != nil {
+		return nil, err
+	}
+
+	readable := make([]docshelf.Doc, 0, len(docs))
+	for _, doc := range docs {
+		if canRead(doc) {
+			readable = append(readable, doc)
+		}
+	}
+
+	return readable, nil
+}
+
+// readChecker returns a func reporting whether the current user is allowed to read a Doc. Path policies
+// are only fetched once, so it's cheap to call for every Doc in a listing.
+func readChecker(r *http.Request, pathPolicyStore docshelf.PathPolicyStore) (func(docshelf.Doc) bool, error) {
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	prefixes, err := pathPolicyStore.ListPathPolicies(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	return func(doc docshelf.Doc) bool {
+		return docshelf.CanRead(user, docshelf.WithEffectivePolicy(doc, prefixes))
+	}, nil
+}
+
+// GetEffectivePolicy handles requests for finding out which Policy applies to a Doc and whether it was
+// inherited from a path prefix.
+func (h DocHandler) GetEffectivePolicy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	doc, ok := h.authorizeDoc(w, r, id, docshelf.AccessRead)
+	if !ok {
+		return
+	}
+
+	prefixes, err := h.pathPolicyStore.ListPathPolicies(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while resolving policy")
+		return
+	}
+
+	data, err := json.Marshal(docshelf.ResolvePolicy(doc, prefixes))
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing policy")
+		return
+	}
+
+	okJSON(w, data)
+}
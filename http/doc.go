@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"text/template"
 
 	"github.com/docshelf/docshelf"
+	"github.com/docshelf/docshelf/diff"
 	"github.com/go-chi/chi"
 	"github.com/russross/blackfriday"
 	"github.com/sirupsen/logrus"
@@ -22,17 +24,31 @@ type TagReq struct {
 	Tags []string
 }
 
+// A DiffReq is a request to compare proposed content against an existing document. If Against is set, the
+// content of that document is used as the base of the comparison instead.
+type DiffReq struct {
+	Content string `json:"content"`
+	Against string `json:"against"`
+}
+
+// A MoveReq is a request to move a document to a new path.
+type MoveReq struct {
+	Path string `json:"path"`
+}
+
 // A DocHandler has methods that can handle HTTP requests for Docs.
 type DocHandler struct {
-	docStore docshelf.DocStore
-	log      *logrus.Logger
+	docStore        docshelf.DocStore
+	pathPolicyStore docshelf.PathPolicyStore
+	log             *logrus.Logger
 }
 
-// NewDocHandler returns a DocHandler struct using the given DocStore and Logger instance.
-func NewDocHandler(docStore docshelf.DocStore, logger *logrus.Logger) DocHandler {
+// NewDocHandler returns a DocHandler struct using the given DocStore, PathPolicyStore and Logger instance.
+func NewDocHandler(docStore docshelf.DocStore, pathPolicyStore docshelf.PathPolicyStore, logger *logrus.Logger) DocHandler {
 	return DocHandler{
-		docStore: docStore,
-		log:      logger,
+		docStore:        docStore,
+		pathPolicyStore: pathPolicyStore,
+		log:             logger,
 	}
 }
 
@@ -56,8 +72,56 @@ func (h DocHandler) PostDoc(w http.ResponseWriter, r *http.Request) {
 	doc.CreatedBy = user.ID
 	doc.UpdatedBy = user.ID
 
+	// updating an existing doc requires write access to it, and new docs need write access to their folder
+	existing, err := h.docStore.GetDoc(r.Context(), strings.Trim(doc.Path, "/"))
+	if err != nil {
+		if !docshelf.CheckNotFound(err) {
+			h.log.Error(err)
+			serverError(w, "something went wrong while verifying document access")
+			return
+		}
+
+		existing = docshelf.Doc{Path: doc.Path}
+	}
+
+	writable, err := h.canAccess(r.Context(), user, existing, docshelf.AccessWrite)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while verifying document access")
+		return
+	}
+
+	if !writable {
+		forbidden(w, "you don't have access to this document")
+		return
+	}
+
+	// policies are managed through the policy endpoints, so saving content never changes who has access
+	doc.Policy = existing.Policy
+
+	// If-Match takes precedence over whatever version was sent in the body
+	if match := r.Header.Get("If-Match"); match != "" {
+		version, err := parseETag(match)
+		if err != nil {
+			badRequest(w, "invalid If-Match header, could not save document")
+			return
+		}
+
+		doc.Version = version
+	}
+
 	id, err := h.docStore.PutDoc(r.Context(), doc)
 	if err != nil {
+		if docshelf.CheckConflict(err) {
+			conflict(w, "document has been modified since it was last read")
+			return
+		}
+
+		if docshelf.CheckLocked(err) {
+			locked(w, "document is locked by another user")
+			return
+		}
+
 		h.log.Error(err)
 		serverError(w, "something went wrong while saving document")
 		return
@@ -77,6 +141,9 @@ func (h DocHandler) PostDoc(w http.ResponseWriter, r *http.Request) {
 // which can be used later to find a user's pinned documents.
 func (h DocHandler) PinDoc(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
+	if _, ok := h.authorizeDoc(w, r, id, docshelf.AccessRead); !ok {
+		return
+	}
 
 	user, err := getContextUser(r.Context())
 	if err != nil {
@@ -94,24 +161,45 @@ func (h DocHandler) PinDoc(w http.ResponseWriter, r *http.Request) {
 	noContent(w)
 }
 
-// GetList handles requests for listing Docs by path prefix.
+// GetList handles requests for listing Docs a page at a time. Docs can be narrowed down with a search query
+// and tags, and ordered by the sort param. The cursor for the next page is sent in a Link header. Docs the
+// user can't read are left out after paging, so pages can come back short even when there are more to come.
 func (h DocHandler) GetList(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("query")
-	tags := strings.Split(r.URL.Query().Get("tags"), ",")
-	if len(tags) == 1 && tags[0] == "" {
-		tags = nil
+	params := r.URL.Query()
+	opts := docshelf.ListOptions{
+		Query:  params.Get("query"),
+		Sort:   docshelf.DocSort(params.Get("sort")),
+		Cursor: params.Get("cursor"),
 	}
 
-	docs, err := h.docStore.ListDocs(r.Context(), query, tags...)
+	if tags := params.Get("tags"); tags != "" {
+		opts.Tags = strings.Split(tags, ",")
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		var err error
+		if opts.Limit, err = strconv.Atoi(limit); err != nil || opts.Limit <= 0 {
+			badRequest(w, "limit must be a positive number")
+			return
+		}
+	}
+
+	page, err := h.docStore.ListDocs(r.Context(), opts)
 	if err != nil {
+		if docshelf.CheckBadQuery(err) {
+			badRequest(w, err.Error())
+			return
+		}
+
 		h.log.Error(err)
 		serverError(w, "something went wrong while listing documents")
 		return
 	}
 
-	// don't return 'null' values for empty results
-	if len(docs) == 0 {
-		okJSON(w, []byte("[]"))
+	docs, err := h.filterReadable(r, page.Docs)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while filtering documents")
 		return
 	}
 
@@ -122,6 +210,13 @@ func (h DocHandler) GetList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if page.Next != "" {
+		next := *r.URL
+		params.Set("cursor", page.Next)
+		next.RawQuery = params.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	}
+
 	okJSON(w, data)
 }
 
@@ -135,6 +230,10 @@ func (h DocHandler) PostTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, ok := h.authorizeDoc(w, r, id, docshelf.AccessWrite); !ok {
+		return
+	}
+
 	if err := h.docStore.TagDoc(r.Context(), id, tags...); err != nil {
 		h.log.Error(err)
 		serverError(w, "something went wrong while tagging document")
@@ -147,7 +246,30 @@ func (h DocHandler) PostTag(w http.ResponseWriter, r *http.Request) {
 // GetDoc handles requests for fetching specific Docs.
 func (h DocHandler) GetDoc(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	doc, err := h.docStore.GetDoc(r.Context(), id)
+	doc, ok := h.authorizeDoc(w, r, id, docshelf.AccessRead)
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing document")
+		return
+	}
+
+	w.Header().Set("ETag", makeETag(doc.Version))
+	okJSON(w, data)
+}
+
+// GetRevisions handles requests for listing the revision history of a specific Doc.
+func (h DocHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, ok := h.authorizeDoc(w, r, id, docshelf.AccessRead); !ok {
+		return
+	}
+
+	revs, err := h.docStore.ListRevisions(r.Context(), id)
 	if err != nil {
 		if docshelf.CheckNotFound(err) {
 			notFound(w)
@@ -155,24 +277,169 @@ func (h DocHandler) GetDoc(w http.ResponseWriter, r *http.Request) {
 		}
 
 		h.log.Error(err)
-		serverError(w, "something went wrong while fetching document")
+		serverError(w, "something went wrong while listing revisions")
 		return
 	}
 
-	data, err := json.Marshal(doc)
+	data, err := json.Marshal(revs)
 	if err != nil {
 		h.log.Error(err)
-		serverError(w, "something went wrong while serializing document")
+		serverError(w, "something went wrong while serializing revisions")
 		return
 	}
 
 	okJSON(w, data)
 }
 
+// GetRevision handles requests for fetching a specific revision of a Doc.
+func (h DocHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	revID := chi.URLParam(r, "revision")
+	if _, ok := h.authorizeDoc(w, r, id, docshelf.AccessRead); !ok {
+		return
+	}
+
+	rev, err := h.docStore.GetRevision(r.Context(), id, revID)
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching revision")
+		return
+	}
+
+	data, err := json.Marshal(rev)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing revision")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// PostDiff handles requests for previewing the line level changes proposed content would make to a Doc.
+// Hunks are returned as JSON unless the unified format is requested with ?format=unified.
+func (h DocHandler) PostDiff(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req DiffReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error(err)
+		badRequest(w, "invalid request body, could not diff document")
+		return
+	}
+
+	base := id
+	if req.Against != "" {
+		base = req.Against
+	}
+
+	doc, ok := h.authorizeDoc(w, r, base, docshelf.AccessRead)
+	if !ok {
+		return
+	}
+
+	hunks := diff.Compare(doc.Content, req.Content)
+	if r.URL.Query().Get("format") == "unified" {
+		okText(w, []byte(diff.Unified("a/"+doc.Path, "b/"+doc.Path, hunks)))
+		return
+	}
+
+	data, err := json.Marshal(hunks)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing diff")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// MoveDoc handles requests for moving a Doc to a new path.
+func (h DocHandler) MoveDoc(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	var req MoveReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path == "" {
+		badRequest(w, "invalid request body, could not move document")
+		return
+	}
+
+	if _, ok := h.authorizeDoc(w, r, id, docshelf.AccessWrite); !ok {
+		return
+	}
+
+	if _, err := h.docStore.GetDoc(r.Context(), req.Path); err == nil {
+		badRequest(w, "a document already exists at that path")
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return
+	}
+
+	// moved docs pick up the policies of their new path, so the destination has to be writable too
+	writable, err := h.canAccess(r.Context(), user, docshelf.Doc{Path: req.Path}, docshelf.AccessWrite)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while verifying document access")
+		return
+	}
+
+	if !writable {
+		forbidden(w, "you don't have access to the destination path")
+		return
+	}
+
+	if err := h.docStore.MoveDoc(r.Context(), id, req.Path, user.ID); err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		if docshelf.CheckLocked(err) {
+			locked(w, "document is locked by another user")
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while moving document")
+		return
+	}
+
+	noContent(w)
+}
+
 // DeleteDoc handles requests for removing specific Docs.
 func (h DocHandler) DeleteDoc(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
-	if err := h.docStore.RemoveDoc(r.Context(), id); err != nil {
+	if _, ok := h.authorizeDoc(w, r, id, docshelf.AccessWrite); !ok {
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return
+	}
+
+	if err := h.docStore.RemoveDoc(r.Context(), id, user.ID); err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		if docshelf.CheckLocked(err) {
+			locked(w, "document is locked by another user")
+			return
+		}
+
 		h.log.Error(err)
 		serverError(w, "something went wrong while deleting document")
 		return
@@ -192,6 +459,20 @@ func (h DocHandler) RenderDoc(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// rendered pages aren't behind authentication, so anonymous visitors are checked against the policy
+	user, _ := getContextUser(r.Context())
+	readable, err := h.canAccess(r.Context(), user, doc, docshelf.AccessRead)
+	if err != nil {
+		log.Error(err)
+		serverError(w, "could not render page")
+		return
+	}
+
+	if !readable {
+		forbidden(w, "you don't have access to this document")
+		return
+	}
+
 	dom := blackfriday.Run([]byte(doc.Content))
 	doc.Content = string(dom)
 
@@ -221,3 +502,19 @@ func (h DocHandler) RenderDoc(w http.ResponseWriter, r *http.Request) {
 
 	okHTML(w, output.Bytes())
 }
+
+// makeETag renders a Doc version as a strong ETag.
+func makeETag(version int) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// parseETag extracts the Doc version from an If-Match header value. A wildcard matches any version, which
+// is the same as not checking the version at all.
+func parseETag(etag string) (int, error) {
+	etag = strings.TrimSpace(etag)
+	if etag == "*" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(strings.Trim(strings.TrimPrefix(etag, "W/"), `"`))
+}
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docshelf/docshelf"
+	"github.com/docshelf/docshelf/bolt"
+	"github.com/docshelf/docshelf/mock"
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+const dbName = "http_test.db"
+
+// serveAs sends a request through the router as the given user.
+func serveAs(router chi.Router, user docshelf.User, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), userKey, user))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func Test_MoveDocDestination(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := bolt.New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	user := docshelf.User{ID: "editor", Role: docshelf.RoleEditor}
+	id, err := store.PutDoc(ctx, docshelf.Doc{Path: "drafts/plan.md", Content: "plan", CreatedBy: user.ID, UpdatedBy: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policyID, err := store.PutPolicy(ctx, docshelf.Policy{Users: []string{"someone else"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.PutPathPolicy(ctx, "private", policyID); err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	router.Post("/{id}/move", NewDocHandler(store, store, logrus.New()).MoveDoc)
+
+	// RUN
+	denied := serveAs(router, user, http.MethodPost, "/"+id+"/move", `{"path": "private/plan.md"}`)
+	_, deniedErr := store.GetDoc(ctx, "private/plan.md")
+
+	allowed := serveAs(router, user, http.MethodPost, "/"+id+"/move", `{"path": "public/plan.md"}`)
+	moved, err := store.GetDoc(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if denied.Code != http.StatusForbidden {
+		t.Fatalf("expected moving into an unwritable path to be forbidden, got %d", denied.Code)
+	}
+
+	if !docshelf.CheckNotFound(deniedErr) {
+		t.Fatal("forbidden move shouldn't create the doc at the destination")
+	}
+
+	if allowed.Code != http.StatusNoContent || moved.Path != "public/plan.md" {
+		t.Fatalf("expected move into a writable path to succeed, got %d", allowed.Code)
+	}
+}
+
+func Test_DeleteLockedDoc(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := bolt.New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	owner := docshelf.User{ID: "owner", Role: docshelf.RoleEditor}
+	other := docshelf.User{ID: "other", Role: docshelf.RoleEditor}
+	id, err := store.PutDoc(ctx, docshelf.Doc{Path: "guides/setup.md", Content: "setup", CreatedBy: owner.ID, UpdatedBy: owner.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.LockDoc(ctx, id, owner.ID, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	handler := NewDocHandler(store, store, logrus.New())
+	router.Delete("/doc/{id}", handler.DeleteDoc)
+	router.Delete("/tree", handler.DeleteDir)
+
+	// RUN
+	blockedDoc := serveAs(router, other, http.MethodDelete, "/doc/"+id, "")
+	blockedDir := serveAs(router, other, http.MethodDelete, "/tree?path=guides", "")
+	_, lockedErr := store.GetDoc(ctx, id)
+
+	allowed := serveAs(router, owner, http.MethodDelete, "/doc/"+id, "")
+	_, removedErr := store.GetDoc(ctx, id)
+
+	// ASSERT
+	if blockedDoc.Code != http.StatusLocked {
+		t.Fatalf("expected deleting a doc locked by another user to be refused, got %d", blockedDoc.Code)
+	}
+
+	if blockedDir.Code != http.StatusLocked {
+		t.Fatalf("expected deleting a folder with a locked doc to be refused, got %d", blockedDir.Code)
+	}
+
+	if lockedErr != nil {
+		t.Fatal("locked doc shouldn't have been removed")
+	}
+
+	if allowed.Code != http.StatusNoContent || !docshelf.CheckNotFound(removedErr) {
+		t.Fatalf("expected the lock holder to delete the doc, got %d", allowed.Code)
+	}
+}
+
+func Test_DocETag(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := bolt.New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	user := docshelf.User{ID: "editor", Role: docshelf.RoleEditor}
+	id, err := store.PutDoc(ctx, docshelf.Doc{Path: "plan.md", Content: "plan", CreatedBy: user.ID, UpdatedBy: user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewDocHandler(store, store, logrus.New())
+	router := chi.NewRouter()
+	router.Post("/doc", handler.PostDoc)
+	router.Get("/doc/{id}", handler.GetDoc)
+
+	// RUN
+	read := serveAs(router, user, http.MethodGet, "/doc/"+id, "")
+	etag := read.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodPost, "/doc", strings.NewReader(`{"path": "plan.md", "content": "first edit"}`))
+	req = req.WithContext(context.WithValue(req.Context(), userKey, user))
+	req.Header.Set("If-Match", etag)
+	fresh := httptest.NewRecorder()
+	router.ServeHTTP(fresh, req)
+
+	req = httptest.NewRequest(http.MethodPost, "/doc", strings.NewReader(`{"path": "plan.md", "content": "stale edit"}`))
+	req = req.WithContext(context.WithValue(req.Context(), userKey, user))
+	req.Header.Set("If-Match", etag)
+	stale := httptest.NewRecorder()
+	router.ServeHTTP(stale, req)
+
+	reread := serveAs(router, user, http.MethodGet, "/doc/"+id, "")
+	saved, err := store.GetDoc(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if etag != `"1"` {
+		t.Fatalf("expected the ETag to match the doc version, got %s", etag)
+	}
+
+	if fresh.Code != http.StatusOK {
+		t.Fatalf("expected a write matching the current ETag to succeed, got %d", fresh.Code)
+	}
+
+	if stale.Code != http.StatusConflict {
+		t.Fatalf("expected a write with a stale If-Match to conflict, got %d", stale.Code)
+	}
+
+	if saved.Content != "first edit" || reread.Header().Get("ETag") != makeETag(saved.Version) {
+		t.Fatalf("expected the stale write to be refused and the ETag to follow the version, got %q at %s", saved.Content, reread.Header().Get("ETag"))
+	}
+}
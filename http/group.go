@@ -0,0 +1,169 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/docshelf/docshelf"
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+// A MembersReq is a request to add Users to a Group.
+type MembersReq struct {
+	Users []string `json:"users"`
+}
+
+// A GroupHandler has methods that can handle HTTP requests for Groups.
+type GroupHandler struct {
+	groupStore docshelf.GroupStore
+	log        *logrus.Logger
+}
+
+// NewGroupHandler returns a GroupHandler struct using the given GroupStore and Logger instance.
+func NewGroupHandler(groupStore docshelf.GroupStore, logger *logrus.Logger) GroupHandler {
+	return GroupHandler{
+		groupStore: groupStore,
+		log:        logger,
+	}
+}
+
+// GetGroups handles requests for listing all Groups.
+func (h GroupHandler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.groupStore.ListGroups(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching group list")
+		return
+	}
+
+	data, err := json.Marshal(groups)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing group list")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// PostGroup handles requests for posting new (or updating existing) Groups.
+func (h GroupHandler) PostGroup(w http.ResponseWriter, r *http.Request) {
+	var group docshelf.Group
+	if err := json.NewDecoder(r.Body).Decode(&group); err != nil {
+		h.log.Error(err)
+		badRequest(w, "invalid request body, could not save group")
+		return
+	}
+
+	if group.Name == "" {
+		badRequest(w, "a group must have a name")
+		return
+	}
+
+	id, err := h.groupStore.PutGroup(r.Context(), group)
+	if err != nil {
+		h.handleMemberErr(w, err)
+		return
+	}
+
+	data, err := json.Marshal(ID{id})
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "group was saved, but the id couldn't be returned")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// GetGroup handles requests for fetching specific Groups.
+func (h GroupHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	group, err := h.groupStore.GetGroup(r.Context(), id)
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching group")
+		return
+	}
+
+	data, err := json.Marshal(group)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing group")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// DeleteGroup handles requests for deleting specific Groups.
+func (h GroupHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.groupStore.RemoveGroup(r.Context(), id); err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while deleting group")
+		return
+	}
+
+	noContent(w)
+}
+
+// PostGroupUsers handles requests for adding Users to a Group.
+func (h GroupHandler) PostGroupUsers(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req MembersReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error(err)
+		badRequest(w, "invalid request body, could not add group members")
+		return
+	}
+
+	if len(req.Users) == 0 {
+		badRequest(w, "at least one user is required")
+		return
+	}
+
+	if err := h.groupStore.AddGroupUsers(r.Context(), id, req.Users...); err != nil {
+		h.handleMemberErr(w, err)
+		return
+	}
+
+	noContent(w)
+}
+
+// DeleteGroupUser handles requests for removing a User from a Group.
+func (h GroupHandler) DeleteGroupUser(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	userID := chi.URLParam(r, "user")
+
+	if err := h.groupStore.RemoveGroupUsers(r.Context(), id, userID); err != nil {
+		h.handleMemberErr(w, err)
+		return
+	}
+
+	noContent(w)
+}
+
+// handleMemberErr responds to errors from changing a Group's membership. Missing users and groups are
+// both reported as not found by the store, so the message has to cover either case.
+func (h GroupHandler) handleMemberErr(w http.ResponseWriter, err error) {
+	if docshelf.CheckNotFound(err) {
+		badRequest(w, err.Error())
+		return
+	}
+
+	if docshelf.CheckConflict(err) {
+		conflict(w, "group was modified concurrently, try again")
+		return
+	}
+
+	h.log.Error(err)
+	serverError(w, "something went wrong while saving group members")
+}
@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"time"
 
 	"github.com/docshelf/docshelf"
 	"github.com/go-chi/chi"
@@ -20,10 +22,22 @@ type Server struct {
 	log            *logrus.Logger
 	authenticators map[string]docshelf.Authenticator
 
-	DocHandler  DocHandler
-	UserStore   docshelf.UserStore
-	GroupStore  docshelf.GroupStore
-	PolicyStore docshelf.PolicyStore
+	DocHandler    DocHandler
+	PolicyHandler PolicyHandler
+	TagHandler    TagHandler
+	UserStore     docshelf.UserStore
+	TokenStore    docshelf.TokenStore
+	GroupStore    docshelf.GroupStore
+	PolicyStore   docshelf.PolicyStore
+	SnapshotStore docshelf.SnapshotStore
+	Sessions      docshelf.SessionManager
+	Passwords     docshelf.PasswordManager
+	Throttle      docshelf.LoginThrottle
+	ResetThrottle docshelf.LoginThrottle
+	AuditStore    docshelf.AuditStore
+
+	// TrustedProxies are the reverse proxies allowed to report client addresses through X-Forwarded-For.
+	TrustedProxies []*net.IPNet
 }
 
 // NewServer returns a new Server struct.
@@ -61,6 +75,10 @@ func (s Server) CheckHandlers() error {
 		return errors.New("no UserStore set")
 	}
 
+	if s.TokenStore == nil {
+		return errors.New("no TokenStore set")
+	}
+
 	if s.GroupStore == nil {
 		return errors.New("no GroupStore set")
 	}
@@ -69,6 +87,26 @@ func (s Server) CheckHandlers() error {
 		return errors.New("no PolicyStore set")
 	}
 
+	if s.Sessions == nil {
+		return errors.New("no SessionManager set")
+	}
+
+	if s.Passwords == nil {
+		return errors.New("no PasswordManager set")
+	}
+
+	if s.Throttle == nil {
+		return errors.New("no LoginThrottle set")
+	}
+
+	if s.ResetThrottle == nil {
+		return errors.New("no reset LoginThrottle set")
+	}
+
+	if s.AuditStore == nil {
+		return errors.New("no AuditStore set")
+	}
+
 	if len(s.authenticators) == 0 {
 		return errors.New("no Authenticator set")
 	}
@@ -81,22 +119,51 @@ func (s Server) buildRoutes() chi.Router {
 	cors := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "If-Match", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"ETag", "Link"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	})
 
-	userHandler := NewUserHandler(s.UserStore, s.log)
+	userHandler := NewUserHandler(s.UserStore, s.TokenStore, s.log)
+	groupHandler := NewGroupHandler(s.GroupStore, s.log)
+	snapshotHandler := NewSnapshotHandler(s.SnapshotStore, s.log)
 	router.Use(cors.Handler)
+	adminOnly := RequireRole(docshelf.RoleAdmin)
 	router.Route("/api", func(r chi.Router) {
-		r.Use(Authentication(s.UserStore))
+		r.Use(Authentication(s.UserStore, s.TokenStore, s.Sessions))
 		r.Route("/user", func(r chi.Router) {
 			r.Get("/", userHandler.GetCurrentUser)
+			r.Post("/password", s.handleChangePassword)
 			r.Get("/list", userHandler.GetUsers)
-			r.Post("/", userHandler.PostUser)
+			r.Get("/tokens", userHandler.GetTokens)
+			r.Post("/tokens", userHandler.PostToken)
+			r.Delete("/tokens/{id}", userHandler.DeleteToken)
+			r.With(adminOnly).Post("/", userHandler.PostUser)
 			r.Get("/{id}", userHandler.GetUser)
-			r.Delete("/{id}", userHandler.DeleteUser)
+			r.With(adminOnly).Delete("/{id}", userHandler.DeleteUser)
+		})
+
+		r.Route("/group", func(r chi.Router) {
+			r.Get("/list", groupHandler.GetGroups)
+			r.With(adminOnly).Post("/", groupHandler.PostGroup)
+			r.Get("/{id}", groupHandler.GetGroup)
+			r.With(adminOnly).Delete("/{id}", groupHandler.DeleteGroup)
+			r.With(adminOnly).Post("/{id}/users", groupHandler.PostGroupUsers)
+			r.With(adminOnly).Delete("/{id}/users/{user}", groupHandler.DeleteGroupUser)
+		})
+
+		r.Route("/policy", func(r chi.Router) {
+			r.Use(adminOnly)
+			r.Get("/list", s.PolicyHandler.GetPolicies)
+			r.Post("/", s.PolicyHandler.PostPolicy)
+			r.Get("/path", s.PolicyHandler.GetPathPolicies)
+			r.Delete("/path", s.PolicyHandler.DetachPathPolicy)
+			r.Delete("/doc", s.PolicyHandler.DetachDocPolicy)
+			r.Get("/{id}", s.PolicyHandler.GetPolicy)
+			r.Delete("/{id}", s.PolicyHandler.DeletePolicy)
+			r.Post("/{id}/path", s.PolicyHandler.AttachPathPolicy)
+			r.Post("/{id}/doc", s.PolicyHandler.AttachDocPolicy)
 		})
 
 		r.Route("/doc", func(r chi.Router) {
@@ -104,14 +171,58 @@ func (s Server) buildRoutes() chi.Router {
 			r.Get("/list", s.DocHandler.GetList)
 			r.Post("/{id}/pin", s.DocHandler.PinDoc)
 			r.Post("/{id}/tag", s.DocHandler.PostTag)
+			r.Post("/{id}/diff", s.DocHandler.PostDiff)
+			r.Post("/{id}/move", s.DocHandler.MoveDoc)
+			r.Post("/{id}/lock", s.DocHandler.LockDoc)
+			r.Delete("/{id}/lock", s.DocHandler.UnlockDoc)
+			r.Get("/{id}/policy", s.DocHandler.GetEffectivePolicy)
+			r.Get("/{id}/revisions", s.DocHandler.GetRevisions)
+			r.Get("/{id}/revisions/{revision}", s.DocHandler.GetRevision)
 			r.Get("/{id}", s.DocHandler.GetDoc)
 			r.Delete("/{id}", s.DocHandler.DeleteDoc)
 		})
+
+		r.Route("/tag", func(r chi.Router) {
+			r.Get("/list", s.TagHandler.GetTags)
+			r.Post("/untag", s.DocHandler.UntagDoc)
+			r.With(adminOnly).Post("/rename", s.TagHandler.RenameTag)
+			r.With(adminOnly).Post("/merge", s.TagHandler.MergeTags)
+		})
+
+		r.Get("/search", s.DocHandler.GetSearch)
+
+		r.Route("/tree", func(r chi.Router) {
+			r.Get("/", s.DocHandler.GetTree)
+			r.Post("/", s.DocHandler.PostDir)
+			r.Delete("/", s.DocHandler.DeleteDir)
+		})
+
+		r.Route("/trash", func(r chi.Router) {
+			r.Get("/", s.DocHandler.GetTrash)
+			r.Post("/{id}/restore", s.DocHandler.RestoreDoc)
+			r.With(adminOnly).Delete("/{id}", s.DocHandler.PurgeDoc)
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(adminOnly)
+			r.Route("/snapshot", func(r chi.Router) {
+				r.Post("/", snapshotHandler.PostSnapshot)
+				r.Get("/list", snapshotHandler.GetSnapshots)
+				r.Get("/{id}", snapshotHandler.GetSnapshot)
+				r.Post("/{id}/restore", snapshotHandler.RestoreSnapshot)
+			})
+
+			r.Delete("/lock/{id}", s.DocHandler.BreakLock)
+			r.Post("/unlock", s.handleUnlock)
+			r.Get("/audit", s.handleGetAudit)
+		})
 	})
 
 	router.Get("/doc/{path}", s.DocHandler.RenderDoc)
 	router.Post("/login", s.handleLogin)
-	router.Get("/logout", handleLogout)
+	router.Get("/logout", s.handleLogout)
+	router.Post("/password/reset", s.handleRequestReset)
+	router.Post("/password/reset/confirm", s.handleResetPassword)
 	router.Get("/oauth/{provider}", s.handleOauth)
 
 	// router.Handle("/*", http.FileServer(http.Dir("./ui/dist/")))
@@ -138,36 +249,59 @@ func (s Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		provider = "google"
 	}
 
-	user, err := s.authenticators[provider].Authenticate(r.Context(), login.Email, login.Token)
+	// directory logins look just like basic ones, so the provider has to be asked for explicitly
+	if requested := r.URL.Query().Get("provider"); requested != "" {
+		provider = requested
+	}
+
+	authenticator, ok := s.authenticators[provider]
+	if !ok {
+		badRequest(w, "unknown authentication provider")
+		return
+	}
+
+	ip := s.clientIP(r)
+	wait, err := s.Throttle.Attempt(r.Context(), login.Email, ip)
+	if err != nil {
+		s.log.Error(err)
+		serverError(w, "something went wrong while checking login attempts")
+		return
+	}
+
+	if wait > 0 {
+		tooManyRequests(w, wait, "too many failed login attempts, try again later")
+		return
+	}
+
+	user, err := authenticator.Authenticate(r.Context(), login.Email, login.Token)
+	if finishErr := s.Throttle.Finish(r.Context(), login.Email, ip, err == nil); finishErr != nil {
+		s.log.WithError(finishErr).Error("failed to record login attempt")
+	}
+
 	if err != nil {
 		s.log.Error(err)
 		unauthorized(w, "invalid credentials")
 		return
 	}
 
-	// TODO (erik): This is a hack to make it easy to have "auth" during dev. This is *NOT* secure, by any means :D
-	identity := http.Cookie{
-		Name:     "session",
-		Value:    user.ID,
-		Path:     "/",
-		HttpOnly: true,
+	if err := s.startSession(w, r, user); err != nil {
+		s.log.Error(err)
+		serverError(w, "failed to start session")
+		return
 	}
 
-	http.SetCookie(w, &identity)
 	noContent(w)
 }
 
-func handleLogout(w http.ResponseWriter, r *http.Request) {
-
-	// TODO (erik): This is a hack to make it easy to have "auth" during dev. This is *NOT* secure, by any means :D
-	identity := http.Cookie{
-		Name:     "session",
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
+func (s Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil && cookie.Value != "" {
+		if err := s.Sessions.EndSession(r.Context(), cookie.Value); err != nil {
+			// the cookie gets cleared either way, an invalid token can't be used anyways
+			s.log.WithError(err).Warn("failed to end session")
+		}
 	}
 
-	http.SetCookie(w, &identity)
+	setSessionCookie(w, "", time.Unix(0, 0))
 	// need to force a refresh so the app figures the user is invalid
 	redirect(w, "http://localhost:9001")
 }
@@ -183,15 +317,38 @@ func (s Server) handleOauth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.startSession(w, r, user); err != nil {
+		s.log.Error(err)
+		serverError(w, "failed to start session")
+		return
+	}
+
+	redirect(w, "http://localhost:9001")
+}
+
+// startSession hands out a new session token for the user and sets it as the session cookie.
+func (s Server) startSession(w http.ResponseWriter, r *http.Request, user docshelf.User) error {
+	token, session, err := s.Sessions.StartSession(r.Context(), user)
+	if err != nil {
+		return err
+	}
+
+	setSessionCookie(w, token, session.ExpiresAt)
+	return nil
+}
+
+func setSessionCookie(w http.ResponseWriter, token string, expires time.Time) {
 	identity := http.Cookie{
-		Name:     "session",
-		Value:    user.ID,
+		Name:     sessionCookie,
+		Value:    token,
 		Path:     "/",
+		Expires:  expires,
 		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
 	}
 
 	http.SetCookie(w, &identity)
-	redirect(w, "http://localhost:9001")
 }
 
 // everything down here is setup for attaching certain data to the request context.
@@ -199,6 +356,8 @@ type contextKey string
 
 const userKey = contextKey("ds-user")
 
+const sessionCookie = "session"
+
 func getContextUser(ctx context.Context) (docshelf.User, error) {
 	if user, ok := ctx.Value(userKey).(docshelf.User); ok {
 		return user, nil
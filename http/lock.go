@@ -0,0 +1,132 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/docshelf/docshelf"
+	"github.com/go-chi/chi"
+)
+
+const (
+	defaultLockTTL = 30 * time.Minute
+	maxLockTTL     = 8 * time.Hour
+)
+
+// A LockReq is a request to lock a document for a period of time. TTL is a duration string like "45m" and
+// defaults to 30 minutes when left empty.
+type LockReq struct {
+	TTL string `json:"ttl"`
+}
+
+// LockDoc handles requests from users to take or extend an exclusive lock on a document.
+func (h DocHandler) LockDoc(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req LockReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		badRequest(w, "invalid request body, could not lock document")
+		return
+	}
+
+	ttl := defaultLockTTL
+	if req.TTL != "" {
+		parsed, err := time.ParseDuration(req.TTL)
+		if err != nil || parsed <= 0 || parsed > maxLockTTL {
+			badRequest(w, "lock ttl must be a positive duration no longer than 8h")
+			return
+		}
+
+		ttl = parsed
+	}
+
+	if _, ok := h.authorizeDoc(w, r, id, docshelf.AccessWrite); !ok {
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining lock owner")
+		return
+	}
+
+	lock, err := h.docStore.LockDoc(r.Context(), id, user.ID, ttl)
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		if docshelf.CheckLocked(err) {
+			locked(w, "document is locked by another user")
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while locking document")
+		return
+	}
+
+	data, err := json.Marshal(lock)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing lock")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// UnlockDoc handles requests from users to release a lock they hold on a document.
+func (h DocHandler) UnlockDoc(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if _, ok := h.authorizeDoc(w, r, id, docshelf.AccessWrite); !ok {
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining lock owner")
+		return
+	}
+
+	if err := h.docStore.UnlockDoc(r.Context(), id, user.ID); err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		if docshelf.CheckLocked(err) {
+			locked(w, "document is locked by another user")
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while unlocking document")
+		return
+	}
+
+	noContent(w)
+}
+
+// BreakLock handles requests from admins to release a document lock held by any user.
+func (h DocHandler) BreakLock(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.docStore.BreakLock(r.Context(), id); err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while breaking document lock")
+		return
+	}
+
+	noContent(w)
+}
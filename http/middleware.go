@@ -2,24 +2,45 @@ package http
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/docshelf/docshelf"
 )
 
-// Authentication is a middleware that verifies a user's identity before passing control
-// to the underlying HTTP handler. If the user is verified, their data is pulled and attached
-// to the request context so it can be referenced at all stages later on.
-func Authentication(userStore docshelf.UserStore) func(http.Handler) http.Handler {
+// Authentication is a middleware that verifies a user's session token, or an API token given as
+// a Bearer token, before passing control to the underlying HTTP handler. If the user is verified,
+// their data is pulled and attached to the request context so it can be referenced at all stages
+// later on.
+func Authentication(userStore docshelf.UserStore, tokenStore docshelf.TokenStore, sessions docshelf.SessionManager) func(http.Handler) http.Handler {
 	return func(handler http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			session, err := r.Cookie("session")
+			if header := r.Header.Get("Authorization"); header != "" {
+				user, err := verifyBearer(r.Context(), userStore, tokenStore, header)
+				if err != nil {
+					unauthorized(w, "invalid api token")
+					return
+				}
+
+				ctx := context.WithValue(r.Context(), userKey, user)
+				handler.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			cookie, err := r.Cookie(sessionCookie)
 			if err != nil {
 				unauthorized(w, "no active session")
 				return
 			}
 
-			user, err := userStore.GetUser(r.Context(), session.Value)
+			session, err := sessions.VerifySession(r.Context(), cookie.Value)
+			if err != nil {
+				unauthorized(w, "invalid session")
+				return
+			}
+
+			user, err := userStore.GetUser(r.Context(), session.UserID)
 			if err != nil {
 				unauthorized(w, "invalid user")
 				return
@@ -31,3 +52,53 @@ func Authentication(userStore docshelf.UserStore) func(http.Handler) http.Handle
 		})
 	}
 }
+
+// verifyBearer looks up the User behind an API token given in an Authorization header. The User is limited
+// to what the token's scopes allow.
+func verifyBearer(ctx context.Context, userStore docshelf.UserStore, tokenStore docshelf.TokenStore, header string) (docshelf.User, error) {
+	if !strings.HasPrefix(header, "Bearer ") {
+		return docshelf.User{}, errors.New("unsupported authorization scheme")
+	}
+
+	id, secret, err := docshelf.ParseAPIToken(strings.TrimPrefix(header, "Bearer "))
+	if err != nil {
+		return docshelf.User{}, err
+	}
+
+	token, err := tokenStore.GetToken(ctx, id)
+	if err != nil {
+		return docshelf.User{}, err
+	}
+
+	if !token.Matches(secret) {
+		return docshelf.User{}, errors.New("api token secret does not match")
+	}
+
+	user, err := userStore.GetUser(ctx, token.UserID)
+	if err != nil {
+		return docshelf.User{}, err
+	}
+
+	return token.Apply(user), nil
+}
+
+// RequireRole is a middleware that only passes control to the underlying HTTP handler if the user attached
+// to the request context by Authentication has at least the given Role.
+func RequireRole(role docshelf.Role) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := getContextUser(r.Context())
+			if err != nil {
+				unauthorized(w, "no active session")
+				return
+			}
+
+			if !user.HasRole(role) {
+				forbidden(w, "you don't have permission to do that")
+				return
+			}
+
+			handler.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/docshelf/docshelf"
+	"github.com/docshelf/docshelf/bolt"
+	"github.com/go-chi/chi"
+)
+
+func Test_RequireRole(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := bolt.New(dbName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	viewer := docshelf.User{ID: "viewer", Role: docshelf.RoleViewer}
+	admin := docshelf.User{Email: "admin@docshelf.io", Role: docshelf.RoleAdmin}
+	admin.ID, err = store.PutUser(ctx, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, raw, err := docshelf.NewAPIToken(admin.ID, "scripts", []docshelf.Scope{docshelf.ScopeWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PutToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		noContent(w)
+	}
+
+	router := chi.NewRouter()
+	router.With(RequireRole(docshelf.RoleAdmin)).Get("/admin", ok)
+
+	scoped := chi.NewRouter()
+	scoped.Use(Authentication(store, store, nil))
+	scoped.With(RequireRole(docshelf.RoleAdmin)).Get("/admin", ok)
+	scoped.With(RequireRole(docshelf.RoleEditor)).Get("/editor", ok)
+
+	// RUN
+	viewerRes := serveAs(router, viewer, http.MethodGet, "/admin", "")
+	adminRes := serveAs(router, admin, http.MethodGet, "/admin", "")
+
+	req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	req.Header.Set("Authorization", "Bearer "+raw)
+	scopedAdmin := httptest.NewRecorder()
+	scoped.ServeHTTP(scopedAdmin, req)
+
+	req = httptest.NewRequest(http.MethodGet, "/editor", nil)
+	req.Header.Set("Authorization", "Bearer "+raw)
+	scopedEditor := httptest.NewRecorder()
+	scoped.ServeHTTP(scopedEditor, req)
+
+	// ASSERT
+	if viewerRes.Code != http.StatusForbidden {
+		t.Fatalf("expected viewers to be forbidden from admin routes, got %d", viewerRes.Code)
+	}
+
+	if adminRes.Code != http.StatusNoContent {
+		t.Fatalf("expected admins to pass, got %d", adminRes.Code)
+	}
+
+	if scopedAdmin.Code != http.StatusForbidden {
+		t.Fatalf("expected a write scoped token to lower an admin to an editor, got %d", scopedAdmin.Code)
+	}
+
+	if scopedEditor.Code != http.StatusNoContent {
+		t.Fatalf("expected a write scoped token to pass editor routes, got %d", scopedEditor.Code)
+	}
+}
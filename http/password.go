@@ -0,0 +1,122 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/docshelf/docshelf/auth"
+)
+
+// A PasswordChangeReq is a request to change the current User's password.
+type PasswordChangeReq struct {
+	Current  string `json:"current"`
+	Password string `json:"password"`
+}
+
+// A ResetReq is a request to send a password reset token to the User with the given email.
+type ResetReq struct {
+	Email string `json:"email"`
+}
+
+// A ResetConfirmReq is a request to set a new password using a password reset token.
+type ResetConfirmReq struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (s Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	var req PasswordChangeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.log.Error(err)
+		badRequest(w, "invalid request body, could not change password")
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		s.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return
+	}
+
+	if err := s.Passwords.ChangePassword(r.Context(), user, req.Current, req.Password); err != nil {
+		if errors.Is(err, auth.ErrWrongPassword) {
+			forbidden(w, "current password is incorrect")
+			return
+		}
+
+		if errors.Is(err, auth.ErrEmptyPassword) {
+			badRequest(w, "new password can't be empty")
+			return
+		}
+
+		s.log.Error(err)
+		serverError(w, "something went wrong while changing password")
+		return
+	}
+
+	// changing the password ended every session, including this one
+	if err := s.startSession(w, r, user); err != nil {
+		s.log.Error(err)
+		serverError(w, "failed to start session")
+		return
+	}
+
+	noContent(w)
+}
+
+func (s Server) handleRequestReset(w http.ResponseWriter, r *http.Request) {
+	var req ResetReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		badRequest(w, "an email address is required to reset a password")
+		return
+	}
+
+	// every request counts against the account and the address, whether or not a reset email goes out
+	wait, err := s.ResetThrottle.Attempt(r.Context(), req.Email, s.clientIP(r))
+	if err != nil {
+		s.log.Error(err)
+		serverError(w, "something went wrong while checking password reset requests")
+		return
+	}
+
+	if wait > 0 {
+		tooManyRequests(w, wait, "too many password reset requests, try again later")
+		return
+	}
+
+	// the response is the same whether or not the reset went out so it can't be used to probe for accounts
+	if err := s.Passwords.RequestReset(r.Context(), req.Email); err != nil {
+		s.log.WithError(err).Error("failed to request password reset")
+	}
+
+	noContent(w)
+}
+
+func (s Server) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetConfirmReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.log.Error(err)
+		badRequest(w, "invalid request body, could not reset password")
+		return
+	}
+
+	if err := s.Passwords.ResetPassword(r.Context(), req.Token, req.Password); err != nil {
+		if errors.Is(err, auth.ErrInvalidReset) {
+			badRequest(w, "password reset token is invalid or expired")
+			return
+		}
+
+		if errors.Is(err, auth.ErrEmptyPassword) {
+			badRequest(w, "new password can't be empty")
+			return
+		}
+
+		s.log.Error(err)
+		serverError(w, "something went wrong while resetting password")
+		return
+	}
+
+	noContent(w)
+}
@@ -0,0 +1,97 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docshelf/docshelf"
+	"github.com/docshelf/docshelf/auth"
+	"github.com/docshelf/docshelf/bolt"
+	"github.com/sirupsen/logrus"
+)
+
+// a Notifier that counts the messages sent to each user instead of delivering them
+type countNotifier map[string]int
+
+func (c countNotifier) Notify(ctx context.Context, user docshelf.User, subject, body string) error {
+	c[user.Email]++
+	return nil
+}
+
+func Test_RequestResetThrottle(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := bolt.New(dbName, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	email := "user@docshelf.io"
+	if _, err := store.PutUser(ctx, docshelf.User{Email: email}); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := docshelf.ThrottlePolicy{
+		FreeAttempts: 2,
+		BaseDelay:    time.Minute,
+		MaxDelay:     time.Hour,
+		LockoutAfter: 10,
+		Lockout:      time.Hour,
+		Window:       time.Hour,
+	}
+
+	notifier := make(countNotifier)
+	throttle := auth.NewThrottle(store, store, policy, policy)
+	s := NewServer("", 0, logrus.New())
+	s.Throttle = throttle
+	s.ResetThrottle = throttle.Scoped("reset")
+	s.Passwords = auth.NewPasswords(store, store, store, store, notifier, time.Hour, "http://localhost/reset")
+
+	requestReset := func(email, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/password/reset", strings.NewReader(`{"email": "`+email+`"}`))
+		req.RemoteAddr = addr
+		res := httptest.NewRecorder()
+		s.handleRequestReset(res, req)
+		return res
+	}
+
+	// RUN
+	free := []int{requestReset(email, "10.0.0.1:1234").Code, requestReset(email, "10.0.0.2:1234").Code}
+	account := requestReset(email, "10.0.0.3:1234")
+	missing := []int{requestReset("a@docshelf.io", "10.0.0.4:1234").Code, requestReset("b@docshelf.io", "10.0.0.4:1234").Code}
+	address := requestReset("c@docshelf.io", "10.0.0.4:1234")
+
+	loginWait, err := s.Throttle.Attempt(ctx, email, "10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if free[0] != http.StatusNoContent || free[1] != http.StatusNoContent || notifier[email] != 2 {
+		t.Fatalf("expected the first reset requests to go out, got %v and %d emails", free, notifier[email])
+	}
+
+	if account.Code != http.StatusTooManyRequests || account.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected reset requests to be throttled per account, got %d", account.Code)
+	}
+
+	if missing[0] != http.StatusNoContent || missing[1] != http.StatusNoContent {
+		t.Fatalf("expected requests for unknown accounts to look like any other, got %v", missing)
+	}
+
+	if address.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected reset requests to be throttled per address, got %d", address.Code)
+	}
+
+	if loginWait != 0 {
+		t.Fatalf("reset requests shouldn't count against logins, got a %s wait", loginWait)
+	}
+}
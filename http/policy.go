@@ -0,0 +1,277 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/docshelf/docshelf"
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+// An AttachReq is a request to attach a Policy to a Doc or path prefix.
+type AttachReq struct {
+	Path string `json:"path"`
+}
+
+// A PolicyHandler has methods that can handle HTTP requests for Policies and attaching them to Docs and
+// path prefixes.
+type PolicyHandler struct {
+	policyStore     docshelf.PolicyStore
+	pathPolicyStore docshelf.PathPolicyStore
+	docStore        docshelf.DocStore
+	log             *logrus.Logger
+}
+
+// NewPolicyHandler returns a PolicyHandler struct using the given stores and Logger instance.
+func NewPolicyHandler(policyStore docshelf.PolicyStore, pathPolicyStore docshelf.PathPolicyStore, docStore docshelf.DocStore, logger *logrus.Logger) PolicyHandler {
+	return PolicyHandler{
+		policyStore:     policyStore,
+		pathPolicyStore: pathPolicyStore,
+		docStore:        docStore,
+		log:             logger,
+	}
+}
+
+// GetPolicies handles requests for listing all Policies.
+func (h PolicyHandler) GetPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.policyStore.ListPolicies(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching policy list")
+		return
+	}
+
+	data, err := json.Marshal(policies)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing policy list")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// PostPolicy handles requests for posting new (or updating existing) Policies.
+func (h PolicyHandler) PostPolicy(w http.ResponseWriter, r *http.Request) {
+	var policy docshelf.Policy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		h.log.Error(err)
+		badRequest(w, "invalid request body, could not save policy")
+		return
+	}
+
+	id, err := h.policyStore.PutPolicy(r.Context(), policy)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while saving policy")
+		return
+	}
+
+	data, err := json.Marshal(ID{id})
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "policy was saved, but the id couldn't be returned")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// GetPolicy handles requests for fetching specific Policies.
+func (h PolicyHandler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	policy, err := h.policyStore.GetPolicy(r.Context(), id)
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching policy")
+		return
+	}
+
+	data, err := json.Marshal(policy)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing policy")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// DeletePolicy handles requests for deleting specific Policies. Path prefixes the Policy was attached to
+// stop inheriting it, but Docs keep the copy they were given until it's detached.
+func (h PolicyHandler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.policyStore.RemovePolicy(r.Context(), id); err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while deleting policy")
+		return
+	}
+
+	noContent(w)
+}
+
+// AttachDocPolicy handles requests for attaching a Policy directly to a Doc. The Doc stores a copy of the
+// Policy, so later changes to the Policy need to be attached again to take effect.
+func (h PolicyHandler) AttachDocPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, ok := h.fetchPolicy(w, r)
+	if !ok {
+		return
+	}
+
+	h.setDocPolicy(w, r, &policy)
+}
+
+// DetachDocPolicy handles requests for removing the Policy attached directly to a Doc. The Doc goes back to
+// inheriting from its path prefixes.
+func (h PolicyHandler) DetachDocPolicy(w http.ResponseWriter, r *http.Request) {
+	h.setDocPolicy(w, r, nil)
+}
+
+// GetPathPolicies handles requests for listing every Policy attached to a path prefix.
+func (h PolicyHandler) GetPathPolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.pathPolicyStore.ListPathPolicies(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching path policies")
+		return
+	}
+
+	data, err := json.Marshal(policies)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing path policies")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// AttachPathPolicy handles requests for attaching a Policy to a path prefix. Docs under the prefix without
+// a Policy of their own inherit it.
+func (h PolicyHandler) AttachPathPolicy(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req AttachReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error(err)
+		badRequest(w, "invalid request body, could not attach policy")
+		return
+	}
+
+	if strings.Trim(req.Path, "/") == "" {
+		badRequest(w, "policies can not be attached to the root of the shelf")
+		return
+	}
+
+	if err := h.pathPolicyStore.PutPathPolicy(r.Context(), req.Path, id); err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while attaching policy")
+		return
+	}
+
+	noContent(w)
+}
+
+// DetachPathPolicy handles requests for removing the Policy attached to a path prefix.
+func (h PolicyHandler) DetachPathPolicy(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if strings.Trim(path, "/") == "" {
+		badRequest(w, "a path is required")
+		return
+	}
+
+	if err := h.pathPolicyStore.RemovePathPolicy(r.Context(), path); err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while detaching policy")
+		return
+	}
+
+	noContent(w)
+}
+
+// fetchPolicy loads the Policy named in the URL. If it can't be fetched, an error response is written and
+// false is returned.
+func (h PolicyHandler) fetchPolicy(w http.ResponseWriter, r *http.Request) (docshelf.Policy, bool) {
+	policy, err := h.policyStore.GetPolicy(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return policy, false
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching policy")
+		return policy, false
+	}
+
+	return policy, true
+}
+
+// setDocPolicy replaces the Policy attached to the Doc named in the request.
+func (h PolicyHandler) setDocPolicy(w http.ResponseWriter, r *http.Request, policy *docshelf.Policy) {
+	var path string
+	if policy == nil {
+		path = r.URL.Query().Get("path")
+	} else {
+		var req AttachReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.log.Error(err)
+			badRequest(w, "invalid request body, could not attach policy")
+			return
+		}
+
+		path = req.Path
+	}
+
+	doc, err := h.docStore.GetDoc(r.Context(), strings.Trim(path, "/"))
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching document")
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return
+	}
+
+	doc.Policy = policy
+	doc.Message = "updated access policy"
+	doc.UpdatedBy = user.ID
+	if _, err := h.docStore.PutDoc(r.Context(), doc); err != nil {
+		if docshelf.CheckConflict(err) {
+			conflict(w, "document has been modified, try again")
+			return
+		}
+
+		if docshelf.CheckLocked(err) {
+			locked(w, "document is locked by another user")
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while updating document policy")
+		return
+	}
+
+	noContent(w)
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/docshelf/docshelf"
+	"github.com/docshelf/docshelf/bolt"
+	"github.com/docshelf/docshelf/mock"
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+func Test_PolicyHandler(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := bolt.New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	admin := docshelf.User{ID: "admin", Role: docshelf.RoleAdmin}
+	editor := docshelf.User{ID: "editor", Role: docshelf.RoleEditor}
+	policyID, err := store.PutPolicy(ctx, docshelf.Policy{Users: []string{editor.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PutDoc(ctx, docshelf.Doc{Path: "team/plan.md", Content: "plan", CreatedBy: admin.ID, UpdatedBy: admin.ID}); err != nil {
+		t.Fatal(err)
+	}
+
+	// policies are routed the same way the server routes them
+	handler := NewPolicyHandler(store, store, store, logrus.New())
+	router := chi.NewRouter()
+	router.Route("/policy", func(r chi.Router) {
+		r.Use(RequireRole(docshelf.RoleAdmin))
+		r.Get("/{id}", handler.GetPolicy)
+		r.Post("/{id}/path", handler.AttachPathPolicy)
+		r.Post("/{id}/doc", handler.AttachDocPolicy)
+	})
+
+	// RUN
+	editorGet := serveAs(router, editor, http.MethodGet, "/policy/"+policyID, "")
+	editorAttach := serveAs(router, editor, http.MethodPost, "/policy/"+policyID+"/path", `{"path": "team"}`)
+	adminGet := serveAs(router, admin, http.MethodGet, "/policy/"+policyID, "")
+	root := serveAs(router, admin, http.MethodPost, "/policy/"+policyID+"/path", `{"path": "/"}`)
+	unknownGet := serveAs(router, admin, http.MethodGet, "/policy/unknown", "")
+	unknownPath := serveAs(router, admin, http.MethodPost, "/policy/unknown/path", `{"path": "team"}`)
+	unknownDoc := serveAs(router, admin, http.MethodPost, "/policy/unknown/doc", `{"path": "team/plan.md"}`)
+	attached := serveAs(router, admin, http.MethodPost, "/policy/"+policyID+"/path", `{"path": "team"}`)
+
+	prefixes, err := store.ListPathPolicies(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ASSERT
+	if editorGet.Code != http.StatusForbidden || editorAttach.Code != http.StatusForbidden {
+		t.Fatalf("expected policy routes to be admin only, got %d and %d", editorGet.Code, editorAttach.Code)
+	}
+
+	if adminGet.Code != http.StatusOK {
+		t.Fatalf("expected admins to fetch policies, got %d", adminGet.Code)
+	}
+
+	if root.Code != http.StatusBadRequest {
+		t.Fatalf("expected attaching a policy to the shelf root to be rejected, got %d", root.Code)
+	}
+
+	if unknownGet.Code != http.StatusNotFound || unknownPath.Code != http.StatusNotFound || unknownDoc.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown policies to not be found, got %d, %d and %d", unknownGet.Code, unknownPath.Code, unknownDoc.Code)
+	}
+
+	if attached.Code != http.StatusNoContent || len(prefixes) != 1 {
+		t.Fatalf("expected the policy to be attached to the path, got %d", attached.Code)
+	}
+
+	if _, ok := prefixes[""]; ok {
+		t.Fatal("no policy should be attached to the shelf root")
+	}
+}
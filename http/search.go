@@ -0,0 +1,69 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/docshelf/docshelf"
+)
+
+// SearchResults are the hits of a search, best matches first. Facets counts the tags of every hit when they're
+// asked for.
+type SearchResults struct {
+	Hits   []docshelf.SearchHit `json:"hits"`
+	Facets []docshelf.TagCount  `json:"facets,omitempty"`
+}
+
+// GetSearch handles requests for searching Docs. Results come best matches first, with snippets showing
+// where each Doc matched. Tag facets are included when the facets param is true.
+func (h DocHandler) GetSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("query")
+	hits, err := h.docStore.SearchDocs(r.Context(), query)
+	if err != nil {
+		if docshelf.CheckBadQuery(err) {
+			badRequest(w, err.Error())
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while searching documents")
+		return
+	}
+
+	canRead, err := readChecker(r, h.pathPolicyStore)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while filtering search results")
+		return
+	}
+
+	// snippets would leak content, so unreadable hits have to go entirely
+	readable := make([]docshelf.SearchHit, 0, len(hits))
+	for _, hit := range hits {
+		if canRead(hit.Doc) {
+			readable = append(readable, hit)
+		}
+	}
+
+	results := SearchResults{Hits: readable}
+
+	// facets are counted after filtering, so they don't give away anything about unreadable docs
+	if facets, _ := strconv.ParseBool(r.URL.Query().Get("facets")); facets {
+		docs := make([]docshelf.Doc, len(readable))
+		for i, hit := range readable {
+			docs[i] = hit.Doc
+		}
+
+		results.Facets = docshelf.CountTags(docs)
+	}
+
+	data, err := json.Marshal(results)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing search results")
+		return
+	}
+
+	okJSON(w, data)
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/docshelf/docshelf"
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+// A SnapshotHandler has methods that can handle HTTP requests for Snapshots.
+type SnapshotHandler struct {
+	snapshotStore docshelf.SnapshotStore
+	log           *logrus.Logger
+}
+
+// NewSnapshotHandler returns a SnapshotHandler struct using the given SnapshotStore and Logger instance.
+func NewSnapshotHandler(snapshotStore docshelf.SnapshotStore, logger *logrus.Logger) SnapshotHandler {
+	return SnapshotHandler{
+		snapshotStore: snapshotStore,
+		log:           logger,
+	}
+}
+
+// PostSnapshot handles requests for capturing a new Snapshot of the shelf.
+func (h SnapshotHandler) PostSnapshot(w http.ResponseWriter, r *http.Request) {
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining snapshot author")
+		return
+	}
+
+	id, err := h.snapshotStore.TakeSnapshot(r.Context(), user.ID)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while taking snapshot")
+		return
+	}
+
+	data, err := json.Marshal(ID{id})
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while returning ID")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// GetSnapshots handles requests for listing all Snapshots.
+func (h SnapshotHandler) GetSnapshots(w http.ResponseWriter, r *http.Request) {
+	snaps, err := h.snapshotStore.ListSnapshots(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while listing snapshots")
+		return
+	}
+
+	data, err := json.Marshal(snaps)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing snapshots")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// GetSnapshot handles requests for fetching specific Snapshots.
+func (h SnapshotHandler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	snap, err := h.snapshotStore.GetSnapshot(r.Context(), id)
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching snapshot")
+		return
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing snapshot")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// RestoreSnapshot handles requests for restoring a Snapshot. If a prefix is given, only the Doc at that path
+// or Docs nested under it are restored.
+func (h SnapshotHandler) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	prefix := r.URL.Query().Get("prefix")
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining restore author")
+		return
+	}
+
+	if err := h.snapshotStore.RestoreSnapshot(r.Context(), id, prefix, user.ID); err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		if docshelf.CheckLocked(err) {
+			locked(w, err.Error())
+			return
+		}
+
+		if docshelf.CheckConflict(err) {
+			conflict(w, err.Error())
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while restoring snapshot")
+		return
+	}
+
+	noContent(w)
+}
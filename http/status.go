@@ -2,7 +2,10 @@ package http
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -23,6 +26,14 @@ func okHTML(w http.ResponseWriter, data []byte) {
 	}
 }
 
+func okText(w http.ResponseWriter, data []byte) {
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		log.WithError(err).Error()
+	}
+}
+
 func noContent(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusNoContent)
 	if _, err := w.Write(nil); err != nil {
@@ -47,6 +58,13 @@ func badRequest(w http.ResponseWriter, msg string) {
 	}
 }
 
+func forbidden(w http.ResponseWriter, msg string) {
+	w.WriteHeader(http.StatusForbidden)
+	if _, err := w.Write([]byte(msg)); err != nil {
+		log.WithError(err).Error()
+	}
+}
+
 func notFound(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusNotFound)
 	if _, err := w.Write(nil); err != nil {
@@ -54,6 +72,29 @@ func notFound(w http.ResponseWriter) {
 	}
 }
 
+func conflict(w http.ResponseWriter, msg string) {
+	w.WriteHeader(http.StatusConflict)
+	if _, err := w.Write([]byte(msg)); err != nil {
+		log.WithError(err).Error()
+	}
+}
+
+func locked(w http.ResponseWriter, msg string) {
+	w.WriteHeader(http.StatusLocked)
+	if _, err := w.Write([]byte(msg)); err != nil {
+		log.WithError(err).Error()
+	}
+}
+
+func tooManyRequests(w http.ResponseWriter, retryAfter time.Duration, msg string) {
+	// Retry-After only has second precision, rounding up keeps clients from retrying too early
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	if _, err := w.Write([]byte(msg)); err != nil {
+		log.WithError(err).Error()
+	}
+}
+
 func unauthorized(w http.ResponseWriter, msg string) {
 	w.WriteHeader(http.StatusUnauthorized)
 	if _, err := w.Write([]byte(msg)); err != nil {
@@ -0,0 +1,179 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/docshelf/docshelf"
+	"github.com/sirupsen/logrus"
+)
+
+// An UntagReq is a request to remove tags from a Doc.
+type UntagReq struct {
+	Doc  string   `json:"doc"`
+	Tags []string `json:"tags"`
+}
+
+// A RenameTagReq is a request to rename a tag.
+type RenameTagReq struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// A MergeTagsReq is a request to merge tags into a single one.
+type MergeTagsReq struct {
+	Into string   `json:"into"`
+	From []string `json:"from"`
+}
+
+// A TagHandler has methods that can handle HTTP requests for managing tags across every Doc.
+type TagHandler struct {
+	tagStore        docshelf.TagStore
+	pathPolicyStore docshelf.PathPolicyStore
+	log             *logrus.Logger
+}
+
+// NewTagHandler returns a TagHandler struct using the given TagStore, PathPolicyStore and Logger instance.
+func NewTagHandler(tagStore docshelf.TagStore, pathPolicyStore docshelf.PathPolicyStore, logger *logrus.Logger) TagHandler {
+	return TagHandler{
+		tagStore:        tagStore,
+		pathPolicyStore: pathPolicyStore,
+		log:             logger,
+	}
+}
+
+// GetTags handles requests for listing every tag along with how many Docs have it. Only the Docs the
+// current user can read are counted, the same as search facets.
+func (h TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	canRead, err := readChecker(r, h.pathPolicyStore)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while verifying tag access")
+		return
+	}
+
+	tags, err := h.tagStore.ListTags(r.Context(), canRead)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching tag list")
+		return
+	}
+
+	data, err := json.Marshal(tags)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing tag list")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// RenameTag handles requests for renaming a tag on every Doc that has it.
+func (h TagHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	var req RenameTagReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error(err)
+		badRequest(w, "invalid request body, could not rename tag")
+		return
+	}
+
+	if msg := checkTags(req.From, req.To); msg != "" {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := h.tagStore.RenameTag(r.Context(), req.From, req.To); err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		if docshelf.CheckConflict(err) {
+			conflict(w, "a tag with that name already exists, merge the tags instead")
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while renaming tag")
+		return
+	}
+
+	noContent(w)
+}
+
+// MergeTags handles requests for merging tags into a single tag.
+func (h TagHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	var req MergeTagsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error(err)
+		badRequest(w, "invalid request body, could not merge tags")
+		return
+	}
+
+	if len(req.From) == 0 {
+		badRequest(w, "at least one tag must be merged")
+		return
+	}
+
+	if msg := checkTags(append([]string{req.Into}, req.From...)...); msg != "" {
+		badRequest(w, msg)
+		return
+	}
+
+	if err := h.tagStore.MergeTags(r.Context(), req.Into, req.From...); err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while merging tags")
+		return
+	}
+
+	noContent(w)
+}
+
+// UntagDoc handles requests for removing tags from an existing Doc.
+func (h DocHandler) UntagDoc(w http.ResponseWriter, r *http.Request) {
+	var req UntagReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error(err)
+		badRequest(w, "invalid request body, could not untag document")
+		return
+	}
+
+	if msg := checkTags(req.Tags...); msg != "" {
+		badRequest(w, msg)
+		return
+	}
+
+	if _, ok := h.authorizeDoc(w, r, req.Doc, docshelf.AccessWrite); !ok {
+		return
+	}
+
+	if err := h.docStore.UntagDoc(r.Context(), req.Doc, req.Tags...); err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while untagging document")
+		return
+	}
+
+	noContent(w)
+}
+
+// checkTags returns a message explaining what's wrong with the given tags, or an empty string if they're
+// all regular, named tags. Pins belong to the User that made them and can't be managed as tags.
+func checkTags(tags ...string) string {
+	for _, tag := range tags {
+		if tag == "" {
+			return "tags must have a name"
+		}
+
+		if docshelf.IsPin(tag) {
+			return "pins can not be managed as tags"
+		}
+	}
+
+	return ""
+}
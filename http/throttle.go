@@ -0,0 +1,152 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// how far back the audit log goes when no start is given
+const defaultAuditWindow = 7 * 24 * time.Hour
+
+// An UnlockReq is a request to clear the failed logins of an account, a client address, or both.
+type UnlockReq struct {
+	Email string `json:"email"`
+	IP    string `json:"ip"`
+}
+
+func (s Server) handleUnlock(w http.ResponseWriter, r *http.Request) {
+	var req UnlockReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Email == "" && req.IP == "") {
+		badRequest(w, "an email or ip is required to unlock logins")
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		s.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return
+	}
+
+	if err := s.Throttle.Unlock(r.Context(), req.Email, req.IP, user.ID); err != nil {
+		s.log.Error(err)
+		serverError(w, "something went wrong while unlocking logins")
+		return
+	}
+
+	noContent(w)
+}
+
+func (s Server) handleGetAudit(w http.ResponseWriter, r *http.Request) {
+	since := time.Now().Add(-defaultAuditWindow)
+	if param := r.URL.Query().Get("since"); param != "" {
+		parsed, err := time.Parse(time.RFC3339, param)
+		if err != nil {
+			badRequest(w, "since must be an RFC3339 timestamp")
+			return
+		}
+
+		since = parsed
+	}
+
+	events, err := s.AuditStore.ListAuditEvents(r.Context(), since)
+	if err != nil {
+		s.log.Error(err)
+		serverError(w, "something went wrong while fetching audit log")
+		return
+	}
+
+	data, err := json.Marshal(events)
+	if err != nil {
+		s.log.Error(err)
+		serverError(w, "something went wrong while serializing audit log")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// ParseTrustedProxies parses addresses and CIDR ranges of reverse proxies whose X-Forwarded-For headers can
+// be trusted.
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid proxy address %q", proxy)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy range %q: %w", proxy, err)
+		}
+
+		nets = append(nets, ipNet)
+	}
+
+	return nets, nil
+}
+
+// clientIP returns the address a request came from. Anyone can set forwarding headers, so X-Forwarded-For
+// is only followed for requests coming from a trusted proxy, and only as far back as the last address that
+// isn't one. Otherwise it would be trivial to dodge per address throttling.
+func (s Server) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !s.trustedProxy(host) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(addr) == nil {
+			break
+		}
+
+		host = addr
+		if !s.trustedProxy(addr) {
+			break
+		}
+	}
+
+	return host
+}
+
+func (s Server) trustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, proxy := range s.TrustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
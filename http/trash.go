@@ -0,0 +1,107 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/docshelf/docshelf"
+	"github.com/go-chi/chi"
+)
+
+// GetTrash handles requests for listing every Doc currently in the trash.
+func (h DocHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	docs, err := h.docStore.ListTrash(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while listing trash")
+		return
+	}
+
+	docs, err = h.filterReadable(r, docs)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while filtering trash")
+		return
+	}
+
+	data, err := json.Marshal(docs)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing trash")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// RestoreDoc handles requests for taking a Doc out of the trash and putting it back at its original path.
+func (h DocHandler) RestoreDoc(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	trash, err := h.docStore.ListTrash(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while listing trash")
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return
+	}
+
+	for _, doc := range trash {
+		if doc.ID != id {
+			continue
+		}
+
+		writable, err := h.canAccess(r.Context(), user, doc, docshelf.AccessWrite)
+		if err != nil {
+			h.log.Error(err)
+			serverError(w, "something went wrong while verifying document access")
+			return
+		}
+
+		if !writable {
+			forbidden(w, "you don't have access to this document")
+			return
+		}
+
+		if _, err := h.docStore.GetDoc(r.Context(), doc.Path); err == nil {
+			badRequest(w, "a document already exists at that path")
+			return
+		}
+	}
+
+	if err := h.docStore.RestoreDoc(r.Context(), id); err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while restoring document")
+		return
+	}
+
+	noContent(w)
+}
+
+// PurgeDoc handles requests for permanently removing a Doc from the trash.
+func (h DocHandler) PurgeDoc(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.docStore.PurgeDoc(r.Context(), id); err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while purging document")
+		return
+	}
+
+	noContent(w)
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/docshelf/docshelf"
+)
+
+// A DirReq is a request to create a new folder.
+type DirReq struct {
+	Path string `json:"path"`
+}
+
+// GetTree handles requests for browsing the folders and Docs directly under a path. An empty path lists
+// the root of the shelf.
+func (h DocHandler) GetTree(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	docs, err := h.docStore.ListTree(r.Context(), path)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while listing folder")
+		return
+	}
+
+	docs, err = h.filterReadable(r, docs)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while filtering folder")
+		return
+	}
+
+	data, err := json.Marshal(docs)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing folder")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// PostDir handles requests for creating new folders.
+func (h DocHandler) PostDir(w http.ResponseWriter, r *http.Request) {
+	var req DirReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error(err)
+		badRequest(w, "invalid request body, could not create folder")
+		return
+	}
+
+	path := strings.Trim(req.Path, "/")
+	if path == "" {
+		badRequest(w, "a folder must have a path")
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining author")
+		return
+	}
+
+	writable, err := h.canAccess(r.Context(), user, docshelf.Doc{Path: path}, docshelf.AccessWrite)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while verifying folder access")
+		return
+	}
+
+	if !writable {
+		forbidden(w, "you don't have access to this folder")
+		return
+	}
+
+	dir := docshelf.Doc{
+		Path:      path,
+		Title:     path[strings.LastIndex(path, "/")+1:],
+		IsDir:     true,
+		CreatedBy: user.ID,
+		UpdatedBy: user.ID,
+	}
+
+	id, err := h.docStore.PutDoc(r.Context(), dir)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while creating folder")
+		return
+	}
+
+	data, err := json.Marshal(ID{id})
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while returning ID")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// DeleteDir handles requests for recursively removing a folder and everything in it.
+func (h DocHandler) DeleteDir(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if strings.Trim(path, "/") == "" {
+		badRequest(w, "a folder path is required")
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return
+	}
+
+	prefixes, err := h.pathPolicyStore.ListPathPolicies(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while verifying folder access")
+		return
+	}
+
+	// the folder itself has to be writable, even when it's empty or only nested paths grant access
+	if !docshelf.CanWrite(user, docshelf.WithEffectivePolicy(docshelf.Doc{Path: path}, prefixes)) {
+		forbidden(w, "you don't have access to this folder")
+		return
+	}
+
+	// removing a folder removes everything in it, so every nested doc has to be writable
+	writable, err := h.canWriteTree(r.Context(), user, prefixes, path)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while verifying folder access")
+		return
+	}
+
+	if !writable {
+		forbidden(w, "you don't have access to everything in this folder")
+		return
+	}
+
+	if err := h.docStore.RemoveDir(r.Context(), path, user.ID); err != nil {
+		if docshelf.CheckBadQuery(err) {
+			badRequest(w, "path is not a folder, documents have to be deleted individually")
+			return
+		}
+
+		if docshelf.CheckLocked(err) {
+			locked(w, "a document in this folder is locked by another user")
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while deleting folder")
+		return
+	}
+
+	noContent(w)
+}
+
+// canWriteTree reports whether a user can write to every folder and Doc nested under a path.
+func (h DocHandler) canWriteTree(ctx context.Context, user docshelf.User, prefixes map[string]docshelf.Policy, path string) (bool, error) {
+	children, err := h.docStore.ListTree(ctx, path)
+	if err != nil {
+		return false, err
+	}
+
+	for _, child := range children {
+		if !docshelf.CanWrite(user, docshelf.WithEffectivePolicy(child, prefixes)) {
+			return false, nil
+		}
+
+		if !child.IsDir {
+			continue
+		}
+
+		writable, err := h.canWriteTree(ctx, user, prefixes, child.Path)
+		if err != nil || !writable {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
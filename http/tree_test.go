@@ -0,0 +1,75 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/docshelf/docshelf"
+	"github.com/docshelf/docshelf/bolt"
+	"github.com/docshelf/docshelf/mock"
+	"github.com/go-chi/chi"
+	"github.com/sirupsen/logrus"
+)
+
+func Test_DeleteDirAccess(t *testing.T) {
+	// SETUP
+	ctx := context.Background()
+	defer os.Remove(dbName) // cleanup database after test
+
+	store, err := bolt.New(dbName, mock.NewFileStore(), mock.NewTextIndex(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	user := docshelf.User{ID: "editor", Role: docshelf.RoleEditor}
+	restricted, err := store.PutPolicy(ctx, docshelf.Policy{Users: []string{"someone else"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared, err := store.PutPolicy(ctx, docshelf.Policy{Users: []string{user.ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.PutPathPolicy(ctx, "team", restricted); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.PutPathPolicy(ctx, "team/shared", shared); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PutDoc(ctx, docshelf.Doc{Path: "team/shared/notes.md", Content: "notes"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.PutDoc(ctx, docshelf.Doc{Path: "team/empty", IsDir: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	router.Delete("/tree", NewDocHandler(store, store, logrus.New()).DeleteDir)
+
+	// RUN
+	parent := serveAs(router, user, http.MethodDelete, "/tree?path=team", "")
+	empty := serveAs(router, user, http.MethodDelete, "/tree?path=team/empty", "")
+	nested := serveAs(router, user, http.MethodDelete, "/tree?path=team/shared", "")
+	_, emptyErr := store.GetDoc(ctx, "team/empty")
+
+	// ASSERT
+	if parent.Code != http.StatusForbidden {
+		t.Fatalf("expected removing a folder granted only by a nested path to be forbidden, got %d", parent.Code)
+	}
+
+	if empty.Code != http.StatusForbidden || emptyErr != nil {
+		t.Fatalf("expected removing an unwritable empty folder to be forbidden, got %d", empty.Code)
+	}
+
+	if nested.Code != http.StatusNoContent {
+		t.Fatalf("expected removing a writable folder to succeed, got %d", nested.Code)
+	}
+}
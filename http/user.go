@@ -5,9 +5,9 @@ import (
 	"net/http"
 
 	"github.com/docshelf/docshelf"
+	"github.com/docshelf/docshelf/auth"
 	"github.com/go-chi/chi"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // ID is a struct for marshaling to and from JSON documents containing an ID.
@@ -15,17 +15,31 @@ type ID struct {
 	ID string `json:"id"`
 }
 
+// A TokenReq is a request to issue a new APIToken for the current User.
+type TokenReq struct {
+	Name   string           `json:"name"`
+	Scopes []docshelf.Scope `json:"scopes"`
+}
+
+// A TokenRes is returned when a new APIToken is issued. It's the only time the full token is available.
+type TokenRes struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+}
+
 // A UserHandler has methods that can handle HTTP requests for Users.
 type UserHandler struct {
-	userStore docshelf.UserStore
-	log       *logrus.Logger
+	userStore  docshelf.UserStore
+	tokenStore docshelf.TokenStore
+	log        *logrus.Logger
 }
 
-// NewUserHandler returns a UserHandler struct using the given UserStore and Logger instance.
-func NewUserHandler(userStore docshelf.UserStore, logger *logrus.Logger) UserHandler {
+// NewUserHandler returns a UserHandler struct using the given UserStore, TokenStore and Logger instance.
+func NewUserHandler(userStore docshelf.UserStore, tokenStore docshelf.TokenStore, logger *logrus.Logger) UserHandler {
 	return UserHandler{
-		userStore: userStore,
-		log:       logger,
+		userStore:  userStore,
+		tokenStore: tokenStore,
+		log:        logger,
 	}
 }
 
@@ -76,15 +90,32 @@ func (h UserHandler) PostUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO (erik): Adjust the cost parameter once we can benchmark the time spent hashing the password.
-	hashed, err := bcrypt.GenerateFromPassword([]byte(user.Token), 12)
+	if !user.Role.Valid() {
+		badRequest(w, "unknown role, could not save user")
+		return
+	}
+
+	// an empty token on an existing user means the password isn't being changed, it shouldn't be hashed
+	if user.Token == "" && user.ID != "" {
+		existing, err := h.userStore.GetUser(r.Context(), user.ID)
+		if err != nil && !docshelf.CheckNotFound(err) {
+			h.log.Error(err)
+			serverError(w, "something went wrong while saving user information")
+			return
+		}
 
-	if err != nil {
-		h.log.Error(err)
-		serverError(w, "something went wrong while saving user information")
+		user.Token = existing.Token
+	} else if user.Token != "" {
+		hashed, err := auth.HashPassword(user.Token)
+		if err != nil {
+			h.log.Error(err)
+			serverError(w, "something went wrong while saving user information")
+			return
+		}
+
+		user.Token = hashed
 	}
 
-	user.Token = string(hashed)
 	id, err := h.userStore.PutUser(r.Context(), user)
 	if err != nil {
 		h.log.Error(err)
@@ -132,3 +163,130 @@ func (h UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	noContent(w)
 }
+
+// GetTokens handles requests for listing the current User's APITokens.
+func (h UserHandler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return
+	}
+
+	tokens, err := h.tokenStore.ListTokens(r.Context(), user.ID)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching token list")
+		return
+	}
+
+	// there's no reason to ever hand hashes back out
+	for i := range tokens {
+		tokens[i].Hash = ""
+	}
+
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while serializing token list")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// PostToken handles requests for issuing a new APIToken to the current User. Tokens can't be given scopes
+// beyond what the User is currently allowed to do.
+func (h UserHandler) PostToken(w http.ResponseWriter, r *http.Request) {
+	var req TokenReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.Error(err)
+		badRequest(w, "invalid request body, could not create token")
+		return
+	}
+
+	if req.Name == "" || len(req.Scopes) == 0 {
+		badRequest(w, "a token must have a name and at least one scope")
+		return
+	}
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !scope.Valid() {
+			badRequest(w, "unknown token scope: "+string(scope))
+			return
+		}
+
+		if !user.HasRole(scope.Role()) {
+			forbidden(w, "you can't create a token with the "+string(scope)+" scope")
+			return
+		}
+	}
+
+	token, raw, err := docshelf.NewAPIToken(user.ID, req.Name, req.Scopes)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while generating token")
+		return
+	}
+
+	id, err := h.tokenStore.PutToken(r.Context(), token)
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while saving token")
+		return
+	}
+
+	data, err := json.Marshal(TokenRes{ID: id, Token: raw})
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "token was saved, but couldn't be returned")
+		return
+	}
+
+	okJSON(w, data)
+}
+
+// DeleteToken handles requests for revoking one of the current User's APITokens.
+func (h UserHandler) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	user, err := getContextUser(r.Context())
+	if err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while determining user")
+		return
+	}
+
+	token, err := h.tokenStore.GetToken(r.Context(), id)
+	if err != nil {
+		if docshelf.CheckNotFound(err) {
+			notFound(w)
+			return
+		}
+
+		h.log.Error(err)
+		serverError(w, "something went wrong while fetching token")
+		return
+	}
+
+	// other users' tokens are treated as missing so their IDs can't be probed
+	if token.UserID != user.ID {
+		notFound(w)
+		return
+	}
+
+	if err := h.tokenStore.RemoveToken(r.Context(), id); err != nil {
+		h.log.Error(err)
+		serverError(w, "something went wrong while revoking token")
+		return
+	}
+
+	noContent(w)
+}
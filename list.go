@@ -0,0 +1,313 @@
+package docshelf
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DocSort is an order Docs can be listed in.
+type DocSort string
+
+// Enumeration of possible DocSort values.
+const (
+	SortPath      = DocSort("path")      // alphabetically by path
+	SortUpdated   = DocSort("updatedAt") // most recently updated first
+	SortCreated   = DocSort("createdAt") // most recently created first
+	SortTitle     = DocSort("title")     // alphabetically by title
+	SortRelevance = DocSort("relevance") // best search matches first, only for listings with a query
+)
+
+// Page limits for listing Docs.
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+// ListOptions narrow down and order a listing of Docs. Docs have to match the Query, if there is one, and
+// have every one of the Tags. Cursor is the Next cursor of the previous page, or empty for the first page.
+type ListOptions struct {
+	Query  string
+	Tags   []string
+	Sort   DocSort
+	Limit  int
+	Cursor string
+}
+
+// A DocPage is a single page of listed Docs. Next is the cursor for the following page, and is empty on the
+// last page.
+type DocPage struct {
+	Docs []Doc  `json:"docs"`
+	Next string `json:"next,omitempty"`
+}
+
+// pageKey is the position of a Doc within a sorted listing. Keys are listed in ascending order of their
+// values, then their paths. Cursors are encoded keys of the last Doc on a page.
+type pageKey struct {
+	Sort  DocSort `json:"s"`
+	Value string  `json:"v"`
+	Path  string  `json:"p"`
+}
+
+// A DocPager collects a single page of a Doc listing. Docs can be added in any order and only the ones that
+// belong on the page are kept, so stores can stream every Doc they have through it without holding them all
+// in memory. That still means reading every Doc for every page.
+//
+// Stores that keep an index of Docs in listing order can do better. They add Docs in index order, starting
+// after AfterKey, and stop reading as soon as the pager is Full. That keeps the work for each page bounded by
+// the page size rather than the size of the shelf. Listings sorted by path are always read in order, through
+// Order when they're filtered.
+type DocPager struct {
+	sort    DocSort
+	limit   int
+	after   *pageKey
+	ranks   map[string]int
+	ordered bool
+	keys    []pageKey
+	docs    []Doc
+}
+
+// NewDocPager returns a DocPager for the given options. Listings with a query need the paths of the docs
+// that matched it, best matches first, for sorting by relevance.
+func NewDocPager(opts ListOptions, ranked []string) (*DocPager, error) {
+	p := DocPager{
+		sort:  opts.Sort,
+		limit: opts.Limit,
+		ranks: make(map[string]int, len(ranked)),
+	}
+
+	switch p.sort {
+	case "":
+		p.sort = SortPath
+		if opts.Query != "" {
+			p.sort = SortRelevance
+		}
+	case SortPath, SortUpdated, SortCreated, SortTitle:
+	case SortRelevance:
+		if opts.Query == "" {
+			return nil, NewErrBadQuery("sorting by relevance requires a query")
+		}
+	default:
+		return nil, NewErrBadQuery(fmt.Sprintf("can not sort docs by %s", opts.Sort))
+	}
+
+	if p.limit <= 0 {
+		p.limit = DefaultPageLimit
+	}
+
+	if p.limit > MaxPageLimit {
+		p.limit = MaxPageLimit
+	}
+
+	p.ordered = p.sort == SortPath
+
+	if opts.Cursor != "" {
+		after, err := decodeCursor(opts.Cursor)
+		if err != nil || after.Sort != p.sort {
+			return nil, NewErrBadQuery("invalid cursor")
+		}
+
+		p.after = &after
+	}
+
+	for rank, path := range ranked {
+		p.ranks[path] = rank
+	}
+
+	return &p, nil
+}
+
+// Sort returns the order the listing is in.
+func (p *DocPager) Sort() DocSort {
+	return p.sort
+}
+
+// Limit returns the number of Docs on a full page.
+func (p *DocPager) Limit() int {
+	return p.limit
+}
+
+// After returns the path of the last Doc on the previous page, or an empty string for the first page.
+func (p *DocPager) After() string {
+	if p.after == nil {
+		return ""
+	}
+
+	return p.after.Path
+}
+
+// AfterKey returns the IndexKey of the last Doc on the previous page, or an empty string for the first page.
+func (p *DocPager) AfterKey() string {
+	if p.after == nil {
+		return ""
+	}
+
+	return p.after.indexKey()
+}
+
+// InOrder tells the pager that the rest of the Docs will be added in listing order, starting after AfterKey.
+// Stores call it when reading from an index kept in listing order, so the pager can tell when it's Full.
+func (p *DocPager) InOrder() {
+	p.ordered = true
+}
+
+// Full reports whether adding more Docs can't change the page anymore. That's only ever the case when Docs
+// are added in listing order, since otherwise any other Doc could still belong on the page.
+func (p *DocPager) Full() bool {
+	return p.ordered && len(p.docs) > p.limit
+}
+
+// Order returns the given paths in the order their Docs should be added. Listings sorted by path only need
+// the paths after the cursor, sorted, while any other listing needs all of them.
+func (p *DocPager) Order(paths []string) []string {
+	if p.sort != SortPath {
+		return paths
+	}
+
+	after := p.After()
+	ordered := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path > after {
+			ordered = append(ordered, path)
+		}
+	}
+
+	sort.Strings(ordered)
+	return ordered
+}
+
+// Add considers a Doc for the page.
+func (p *DocPager) Add(doc Doc) {
+	key := p.key(doc)
+
+	// docs added in order starting after the cursor only need collecting
+	if p.ordered {
+		if !p.Full() {
+			p.keys = append(p.keys, key)
+			p.docs = append(p.docs, doc)
+		}
+
+		return
+	}
+	if p.after != nil && !p.less(*p.after, key) {
+		return
+	}
+
+	idx := sort.Search(len(p.keys), func(i int) bool {
+		return p.less(key, p.keys[i])
+	})
+
+	// one extra doc is kept to know whether there's another page
+	if idx > p.limit {
+		return
+	}
+
+	p.keys = append(p.keys, pageKey{})
+	copy(p.keys[idx+1:], p.keys[idx:])
+	p.keys[idx] = key
+
+	p.docs = append(p.docs, Doc{})
+	copy(p.docs[idx+1:], p.docs[idx:])
+	p.docs[idx] = doc
+
+	if len(p.keys) > p.limit+1 {
+		p.keys = p.keys[:p.limit+1]
+		p.docs = p.docs[:p.limit+1]
+	}
+}
+
+// Page returns the page of Docs added so far.
+func (p *DocPager) Page() DocPage {
+	if len(p.docs) <= p.limit {
+		return DocPage{Docs: append([]Doc{}, p.docs...)}
+	}
+
+	return DocPage{
+		Docs: append([]Doc{}, p.docs[:p.limit]...),
+		Next: encodeCursor(p.keys[p.limit-1]),
+	}
+}
+
+func (p *DocPager) key(doc Doc) pageKey {
+	if p.sort != SortRelevance {
+		return sortKey(p.sort, doc)
+	}
+
+	rank, ok := p.ranks[doc.Path]
+	if !ok {
+		rank = len(p.ranks)
+	}
+
+	return pageKey{Sort: p.sort, Value: fmt.Sprintf("%010d", rank), Path: doc.Path}
+}
+
+// less reports whether the left key comes before the right one. Docs with equal sort values are ordered by
+// path so every Doc has a stable position.
+func (p *DocPager) less(left, right pageKey) bool {
+	if left.Value != right.Value {
+		return left.Value < right.Value
+	}
+
+	return left.Path < right.Path
+}
+
+// IndexKey returns the key a Doc is kept under in an index of Docs sorted by the given order. Keys compare
+// bytewise in listing order. Listings by relevance depend on the query, so they can't be indexed.
+func IndexKey(sort DocSort, doc Doc) string {
+	return sortKey(sort, doc).indexKey()
+}
+
+// sortKey returns the position of a Doc in a listing that doesn't depend on a query.
+func sortKey(sort DocSort, doc Doc) pageKey {
+	key := pageKey{Sort: sort, Path: doc.Path}
+	switch sort {
+	case SortUpdated:
+		key.Value = newestFirst(doc.UpdatedAt)
+	case SortCreated:
+		key.Value = newestFirst(doc.CreatedAt)
+	case SortTitle:
+		key.Value = strings.ToLower(doc.Title)
+	}
+
+	return key
+}
+
+// indexKey joins the value and path of a key. The separator sorts before anything else, so a value that's a
+// prefix of another still comes first.
+func (k pageKey) indexKey() string {
+	if k.Sort == SortPath {
+		return k.Path
+	}
+
+	return k.Value + "\x00" + k.Path
+}
+
+// newestFirst formats a time so that later times sort first as strings.
+func newestFirst(t time.Time) string {
+	nanos := t.UnixNano()
+	if t.IsZero() || nanos < 0 {
+		nanos = 0
+	}
+
+	return fmt.Sprintf("%019d", math.MaxInt64-nanos)
+}
+
+func encodeCursor(key pageKey) string {
+	data, _ := json.Marshal(key) // a pageKey is only strings, so marshaling can't fail
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (pageKey, error) {
+	var key pageKey
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return key, err
+	}
+
+	err = json.Unmarshal(data, &key)
+	return key, err
+}
@@ -0,0 +1,109 @@
+package docshelf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_DocPager(t *testing.T) {
+	// SETUP
+	now := time.Now()
+	docs := []Doc{
+		{Path: "b.md", Title: "Bravo", CreatedAt: now.Add(-4 * time.Hour), UpdatedAt: now.Add(-time.Hour)},
+		{Path: "d.md", Title: "delta", CreatedAt: now.Add(-3 * time.Hour), UpdatedAt: now.Add(-time.Hour)},
+		{Path: "a.md", Title: "Alpha", CreatedAt: now.Add(-2 * time.Hour), UpdatedAt: now},
+		{Path: "c.md", Title: "charlie", CreatedAt: now.Add(-time.Hour), UpdatedAt: now.Add(-3 * time.Hour)},
+		{Path: "e.md", Title: "Echo", CreatedAt: now, UpdatedAt: now.Add(-2 * time.Hour)},
+	}
+
+	cases := []struct {
+		name   string
+		opts   ListOptions
+		ranked []string
+		paths  string
+	}{
+		{"path by default", ListOptions{}, nil, "a.md,b.md,c.md,d.md,e.md"},
+		{"updated", ListOptions{Sort: SortUpdated}, nil, "a.md,b.md,d.md,e.md,c.md"},
+		{"created", ListOptions{Sort: SortCreated}, nil, "e.md,c.md,a.md,d.md,b.md"},
+		{"title ignores case", ListOptions{Sort: SortTitle}, nil, "a.md,b.md,c.md,d.md,e.md"},
+		{"relevance by default with a query", ListOptions{Query: "q"}, []string{"c.md", "a.md", "e.md", "b.md", "d.md"}, "c.md,a.md,e.md,b.md,d.md"},
+		{"short pages", ListOptions{Sort: SortUpdated, Limit: 2}, nil, "a.md,b.md|d.md,e.md|c.md"},
+		{"short path pages", ListOptions{Limit: 2}, nil, "a.md,b.md|c.md,d.md|e.md"},
+		{"exact pages", ListOptions{Sort: SortTitle, Limit: 5}, nil, "a.md,b.md,c.md,d.md,e.md"},
+	}
+
+	paths := make([]string, len(docs))
+	byPath := make(map[string]Doc, len(docs))
+	for i, doc := range docs {
+		paths[i] = doc.Path
+		byPath[doc.Path] = doc
+	}
+
+	for _, c := range cases {
+		var pages []string
+		opts := c.opts
+		for {
+			pager, err := NewDocPager(opts, c.ranked)
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+
+			// RUN
+			for _, path := range pager.Order(paths) {
+				if pager.Full() {
+					break
+				}
+
+				pager.Add(byPath[path])
+			}
+
+			page := pager.Page()
+			listed := make([]string, len(page.Docs))
+			for i, doc := range page.Docs {
+				listed[i] = doc.Path
+			}
+
+			pages = append(pages, strings.Join(listed, ","))
+			if page.Next == "" {
+				break
+			}
+
+			opts.Cursor = page.Next
+		}
+
+		// ASSERT
+		if strings.Join(pages, "|") != c.paths {
+			t.Fatalf("%s: expected pages %s, got %s", c.name, c.paths, strings.Join(pages, "|"))
+		}
+	}
+}
+
+func Test_DocPagerBadOptions(t *testing.T) {
+	// SETUP
+	pager, err := NewDocPager(ListOptions{Limit: 1}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pager.Add(Doc{Path: "a.md"})
+	pager.Add(Doc{Path: "b.md"})
+	next := pager.Page().Next
+
+	cases := []ListOptions{
+		{Sort: "size"},
+		{Sort: SortRelevance},
+		{Cursor: "not a cursor"},
+		{Sort: SortTitle, Cursor: next},
+	}
+
+	for _, opts := range cases {
+		// RUN
+		_, err := NewDocPager(opts, nil)
+
+		// ASSERT
+		if !CheckBadQuery(err) {
+			t.Fatalf("%+v: expected a bad query error, got: %v", opts, err)
+		}
+	}
+}
@@ -8,12 +8,15 @@ import (
 
 // TextIndex is a mock implementation of the docshelf.TextIndex interface.
 type TextIndex struct {
-	SearchFn     func(ctx context.Context, term string) ([]string, error)
+	SearchFn     func(ctx context.Context, term string) ([]docshelf.SearchHit, error)
 	SearchCalled int
 
 	IndexFn     func(ctx context.Context, doc docshelf.Doc) error
 	IndexCalled int
 
+	DeleteFn     func(ctx context.Context, path string) error
+	DeleteCalled int
+
 	Err error
 }
 
@@ -25,7 +28,7 @@ func NewTextIndex(err error) *TextIndex {
 }
 
 // Search mocks the docshelf.TextIndex interface.
-func (m *TextIndex) Search(ctx context.Context, term string) ([]string, error) {
+func (m *TextIndex) Search(ctx context.Context, term string) ([]docshelf.SearchHit, error) {
 	m.SearchCalled++
 	if m.Err != nil {
 		return nil, m.Err
@@ -51,3 +54,17 @@ func (m *TextIndex) Index(ctx context.Context, doc docshelf.Doc) error {
 
 	return nil
 }
+
+// Delete mocks the docshelf.TextIndex interface.
+func (m *TextIndex) Delete(ctx context.Context, path string) error {
+	m.DeleteCalled++
+	if m.Err != nil {
+		return m.Err
+	}
+
+	if m.DeleteFn != nil {
+		return m.DeleteFn(ctx, path)
+	}
+
+	return nil
+}
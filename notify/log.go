@@ -0,0 +1,25 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/docshelf/docshelf"
+	"github.com/sirupsen/logrus"
+)
+
+// A Log Notifier writes messages to the log instead of delivering them. It's meant for running docshelf
+// locally without a mail server.
+type Log struct {
+	log *logrus.Logger
+}
+
+// NewLog returns a new Log Notifier using the given Logger instance.
+func NewLog(logger *logrus.Logger) Log {
+	return Log{logger}
+}
+
+// Notify implements the docshelf.Notifier interface.
+func (l Log) Notify(ctx context.Context, user docshelf.User, subject, body string) error {
+	l.log.WithField("to", user.Email).WithField("subject", subject).Info(body)
+	return nil
+}
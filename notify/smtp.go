@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+
+	"github.com/docshelf/docshelf"
+)
+
+// An SMTP Notifier delivers messages to Users by email.
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTP returns a new SMTP Notifier that sends mail through the server at addr (host:port). Messages are
+// sent without authenticating if no username is given.
+func NewSMTP(addr, username, password, from string) (SMTP, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return SMTP{}, fmt.Errorf("invalid smtp address: %w", err)
+	}
+
+	if from == "" {
+		return SMTP{}, errors.New("smtp notifier needs a from address")
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return SMTP{
+		addr: addr,
+		from: from,
+		auth: auth,
+	}, nil
+}
+
+// Notify implements the docshelf.Notifier interface.
+func (s SMTP) Notify(ctx context.Context, user docshelf.User, subject, body string) error {
+	if user.Email == "" {
+		return errors.New("user has no email address to notify")
+	}
+
+	// anything that ends up in a header can't be allowed to start a new one
+	if strings.ContainsAny(user.Email+subject, "\r\n") {
+		return errors.New("invalid characters in mail headers")
+	}
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{user.Email}, s.message(user.Email, subject, body)); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
+
+func (s SMTP) message(to, subject, body string) []byte {
+	headers := []string{
+		"From: " + s.from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+
+	// SMTP requires CRLF line endings throughout the message
+	body = strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n")
+	return []byte(strings.Join(headers, "\r\n") + "\r\n\r\n" + body + "\r\n")
+}
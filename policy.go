@@ -0,0 +1,131 @@
+package docshelf
+
+import "strings"
+
+// An Access is the level of access a User has to a Doc.
+type Access int
+
+// Access enum values
+const (
+	AccessNone = Access(iota)
+	AccessRead
+	AccessWrite
+)
+
+// An EffectivePolicy is the Policy that actually applies to a Doc, along with where it came from. Policies
+// inherited from a path prefix name that prefix in From.
+type EffectivePolicy struct {
+	Policy    *Policy `json:"policy"`
+	Inherited bool    `json:"inherited"`
+	From      string  `json:"from,omitempty"`
+}
+
+// ResolvePolicy finds the Policy that applies to a Doc. A Policy set on the Doc itself always wins. Otherwise
+// the Policy attached to the most specific prefix of the Doc's path is inherited, walking up one folder at
+// a time until the root of the shelf.
+func ResolvePolicy(doc Doc, prefixes map[string]Policy) EffectivePolicy {
+	if doc.Policy != nil {
+		return EffectivePolicy{Policy: doc.Policy}
+	}
+
+	path := strings.Trim(doc.Path, "/")
+	for {
+		if policy, ok := prefixes[path]; ok {
+			return EffectivePolicy{Policy: &policy, Inherited: true, From: path}
+		}
+
+		if path == "" {
+			return EffectivePolicy{}
+		}
+
+		idx := strings.LastIndex(path, "/")
+		if idx < 0 {
+			path = ""
+			continue
+		}
+
+		path = path[:idx]
+	}
+}
+
+// WithEffectivePolicy returns a copy of the Doc carrying whichever Policy applies to it, so it can be passed
+// to access checks.
+func WithEffectivePolicy(doc Doc, prefixes map[string]Policy) Doc {
+	doc.Policy = ResolvePolicy(doc, prefixes).Policy
+	return doc
+}
+
+// Access evaluates the Policy for the given User. A nil Policy, or one that doesn't name any users or
+// groups, applies to everyone. Users matching the Policy can read, and can also write unless the Policy is
+// ReadOnly.
+func (p *Policy) Access(user User) Access {
+	if p == nil {
+		return AccessWrite
+	}
+
+	if len(p.Users) > 0 || len(p.Groups) > 0 {
+		if !contains(p.Users, user.ID) && !overlaps(p.Groups, user.Groups) {
+			return AccessNone
+		}
+	}
+
+	if p.ReadOnly {
+		return AccessRead
+	}
+
+	return AccessWrite
+}
+
+// DocAccess returns the level of access a User has to a Doc. Admins always have full access, and so does
+// the creator of a Doc so a Policy can't lock them out of their own work. Viewers never get more than read
+// access.
+func DocAccess(user User, doc Doc) Access {
+	if user.ID == "" {
+		return doc.Policy.Access(user)
+	}
+
+	if user.Role == RoleAdmin {
+		return AccessWrite
+	}
+
+	access := doc.Policy.Access(user)
+	if user.ID == doc.CreatedBy {
+		access = AccessWrite
+	}
+
+	if !user.HasRole(RoleEditor) && access > AccessRead {
+		return AccessRead
+	}
+
+	return access
+}
+
+// CanRead reports whether a User is allowed to read a Doc.
+func CanRead(user User, doc Doc) bool {
+	return DocAccess(user, doc) >= AccessRead
+}
+
+// CanWrite reports whether a User is allowed to modify a Doc.
+func CanWrite(user User, doc Doc) bool {
+	return DocAccess(user, doc) >= AccessWrite
+}
+
+func contains(slice []string, el string) bool {
+	for _, s := range slice {
+		if s == el {
+			return true
+		}
+	}
+
+	return false
+}
+
+func overlaps(left, right []string) bool {
+	for _, el := range left {
+		if contains(right, el) {
+			return true
+		}
+	}
+
+	return false
+}
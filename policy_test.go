@@ -0,0 +1,88 @@
+package docshelf
+
+import "testing"
+
+func Test_DocAccess(t *testing.T) {
+	// SETUP
+	member := User{ID: "member"}
+	grouped := User{ID: "grouped", Groups: []string{"writers"}}
+	outsider := User{ID: "outsider", Groups: []string{"readers"}}
+	creator := User{ID: "creator"}
+	admin := User{ID: "admin", Role: RoleAdmin}
+	viewer := User{ID: "viewer", Role: RoleViewer}
+	viewingCreator := User{ID: "creator", Role: RoleViewer}
+
+	restricted := &Policy{Users: []string{member.ID}, Groups: []string{"writers"}}
+	readOnly := &Policy{ReadOnly: true}
+	restrictedReadOnly := &Policy{Users: []string{member.ID}, ReadOnly: true}
+
+	cases := []struct {
+		name   string
+		user   User
+		policy *Policy
+		access Access
+	}{
+		{"no policy", outsider, nil, AccessWrite},
+		{"listed user", member, restricted, AccessWrite},
+		{"listed group", grouped, restricted, AccessWrite},
+		{"unlisted user", outsider, restricted, AccessNone},
+		{"anonymous user", User{}, restricted, AccessNone},
+		{"read only for everyone", outsider, readOnly, AccessRead},
+		{"read only for members", member, restrictedReadOnly, AccessRead},
+		{"read only for outsiders", outsider, restrictedReadOnly, AccessNone},
+		{"creator", creator, restrictedReadOnly, AccessWrite},
+		{"admin", admin, restrictedReadOnly, AccessWrite},
+		{"viewer", viewer, nil, AccessRead},
+		{"viewer outside policy", viewer, restricted, AccessNone},
+		{"viewer creator", viewingCreator, restricted, AccessRead},
+	}
+
+	for _, c := range cases {
+		// RUN
+		access := DocAccess(c.user, Doc{CreatedBy: creator.ID, Policy: c.policy})
+
+		// ASSERT
+		if access != c.access {
+			t.Fatalf("%s: expected access %d, got %d", c.name, c.access, access)
+		}
+	}
+}
+
+func Test_ResolvePolicy(t *testing.T) {
+	// SETUP
+	own := &Policy{ID: "own"}
+	prefixes := map[string]Policy{
+		"security":      {ID: "security"},
+		"security/keys": {ID: "keys"},
+		"hr":            {ID: "hr"},
+	}
+
+	cases := []struct {
+		name string
+		doc  Doc
+		id   string
+		from string
+	}{
+		{"own policy wins", Doc{Path: "security/keys/root.md", Policy: own}, "own", ""},
+		{"most specific prefix", Doc{Path: "security/keys/root.md"}, "keys", "security/keys"},
+		{"parent prefix", Doc{Path: "security/audit.md"}, "security", "security"},
+		{"folder itself", Doc{Path: "hr", IsDir: true}, "hr", "hr"},
+		{"similar name", Doc{Path: "hrm/notes.md"}, "", ""},
+		{"no policy", Doc{Path: "notes.md"}, "", ""},
+	}
+
+	for _, c := range cases {
+		// RUN
+		effective := ResolvePolicy(c.doc, prefixes)
+
+		// ASSERT
+		var id string
+		if effective.Policy != nil {
+			id = effective.Policy.ID
+		}
+
+		if id != c.id || effective.From != c.from || effective.Inherited != (c.from != "") {
+			t.Fatalf("%s: unexpected effective policy %+v", c.name, effective)
+		}
+	}
+}
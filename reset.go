@@ -0,0 +1,62 @@
+package docshelf
+
+import (
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// A PasswordReset lets a User who forgot their password choose a new one. It can only be used once and
+// expires on its own. Like APITokens, only a hash of its secret is stored.
+type PasswordReset struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"userId"`
+	Hash      string     `json:"hash"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+}
+
+// NewPasswordReset creates a new PasswordReset for a User that's valid for the given duration, along with
+// the full token that has to be delivered to them.
+func NewPasswordReset(userID string, ttl time.Duration) (PasswordReset, string, error) {
+	encoded, err := newSecret()
+	if err != nil {
+		return PasswordReset{}, "", err
+	}
+
+	now := time.Now()
+	reset := PasswordReset{
+		ID:        xid.New().String(),
+		UserID:    userID,
+		Hash:      hashSecret(encoded),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	return reset, reset.ID + "." + encoded, nil
+}
+
+// ParsePasswordReset splits a full reset token into the ID of the PasswordReset it belongs to and its
+// secret.
+func ParsePasswordReset(raw string) (string, string, error) {
+	parts := strings.SplitN(raw, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.New("malformed password reset token")
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// Matches reports whether the secret belongs to the PasswordReset.
+func (p PasswordReset) Matches(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(p.Hash), []byte(hashSecret(secret))) == 1
+}
+
+// Active reports whether the PasswordReset can still be used.
+func (p PasswordReset) Active() bool {
+	return p.UsedAt == nil && time.Now().Before(p.ExpiresAt)
+}
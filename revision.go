@@ -0,0 +1,17 @@
+package docshelf
+
+import "github.com/rs/xid"
+
+// NewRevision builds the Revision that should be recorded for the given version of a Doc.
+func NewRevision(doc Doc) Revision {
+	return Revision{
+		ID:        xid.New().String(),
+		DocID:     doc.ID,
+		Path:      doc.Path,
+		Title:     doc.Title,
+		Content:   doc.Content,
+		Message:   doc.Message,
+		CreatedBy: doc.UpdatedBy,
+		CreatedAt: doc.UpdatedAt,
+	}
+}
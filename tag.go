@@ -0,0 +1,32 @@
+package docshelf
+
+import "sort"
+
+// CountTags counts how many of the given Docs have each of their tags, most used first. Pins aren't counted.
+func CountTags(docs []Doc) []TagCount {
+	counts := make(map[string]int)
+	for _, doc := range docs {
+		for _, tag := range WithoutPins(doc.Tags) {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
+	}
+
+	SortTagCounts(tags)
+	return tags
+}
+
+// SortTagCounts sorts tags so the most used come first. Tags used equally often are sorted by name.
+func SortTagCounts(tags []TagCount) {
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+
+		return tags[i].Tag < tags[j].Tag
+	})
+}
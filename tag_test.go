@@ -0,0 +1,24 @@
+package docshelf
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_CountTags(t *testing.T) {
+	// SETUP
+	docs := []Doc{
+		{Path: "a.md", Tags: []string{"ops", "how to", "user/alice"}},
+		{Path: "b.md", Tags: []string{"ops", "people"}},
+		{Path: "c.md", Tags: []string{"people", "user/alice"}},
+		{Path: "d.md"},
+	}
+
+	// RUN
+	counts := CountTags(docs)
+
+	// ASSERT
+	if fmt.Sprint(counts) != "[{ops 2} {people 2} {how to 1}]" {
+		t.Fatalf("unexpected tag counts: %v", counts)
+	}
+}
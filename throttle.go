@@ -0,0 +1,76 @@
+package docshelf
+
+import "time"
+
+// LoginAttempts tracks the failed logins for a single account or client address. Once there have been too
+// many, further attempts have to wait for an exponentially growing delay.
+type LoginAttempts struct {
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"lastFailure"`
+}
+
+// A ThrottlePolicy decides how long logins have to wait after failing.
+type ThrottlePolicy struct {
+	// FreeAttempts is how many failures are allowed before any delay is enforced.
+	FreeAttempts int
+
+	// BaseDelay is the delay after the first failure past the free ones, and doubles for each one after that
+	// up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// After LockoutAfter failures logins are locked out for the Lockout duration instead.
+	LockoutAfter int
+	Lockout      time.Duration
+
+	// Window is how long failures are remembered. A failure after a quiet Window starts counting from zero.
+	Window time.Duration
+}
+
+// Delay returns how long logins have to wait after the given number of failures.
+func (p ThrottlePolicy) Delay(failures int) time.Duration {
+	if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+		return p.Lockout
+	}
+
+	if failures < p.FreeAttempts || failures <= 0 {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeAttempts; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+
+	return delay
+}
+
+// Locked reports whether the failures amount to a lockout rather than just a delay.
+func (p ThrottlePolicy) Locked(failures int) bool {
+	return p.LockoutAfter > 0 && failures >= p.LockoutAfter
+}
+
+// Current returns the LoginAttempts as they stand at the given time, forgetting failures that are older than
+// the policy's Window.
+func (a LoginAttempts) Current(p ThrottlePolicy, now time.Time) LoginAttempts {
+	if p.Window > 0 && now.Sub(a.LastFailure) > p.Window {
+		a.Failures = 0
+	}
+
+	return a
+}
+
+// Wait returns how long a login has to wait at the given time before it's allowed.
+func (a LoginAttempts) Wait(p ThrottlePolicy, now time.Time) time.Duration {
+	a = a.Current(p, now)
+	if wait := a.LastFailure.Add(p.Delay(a.Failures)).Sub(now); wait > 0 {
+		return wait
+	}
+
+	return 0
+}
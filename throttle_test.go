@@ -0,0 +1,67 @@
+package docshelf
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ThrottlePolicy(t *testing.T) {
+	// SETUP
+	policy := ThrottlePolicy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     10 * time.Second,
+		LockoutAfter: 10,
+		Lockout:      time.Hour,
+		Window:       time.Hour,
+	}
+
+	cases := []struct {
+		failures int
+		delay    time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{7, 10 * time.Second},
+		{9, 10 * time.Second},
+		{10, time.Hour},
+	}
+
+	for _, c := range cases {
+		// RUN
+		delay := policy.Delay(c.failures)
+
+		// ASSERT
+		if delay != c.delay {
+			t.Fatalf("%d failures: expected delay %s, got %s", c.failures, c.delay, delay)
+		}
+	}
+}
+
+func Test_LoginAttemptsWait(t *testing.T) {
+	// SETUP
+	policy := ThrottlePolicy{FreeAttempts: 1, BaseDelay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	now := time.Now()
+	attempts := LoginAttempts{Failures: 2, LastFailure: now.Add(-30 * time.Second)}
+
+	// RUN
+	wait := attempts.Wait(policy, now)
+	waitLater := attempts.Wait(policy, now.Add(2*time.Minute))
+	forgotten := attempts.Current(policy, now.Add(2*time.Hour))
+
+	// ASSERT
+	if wait != 90*time.Second {
+		t.Fatalf("expected to wait out the rest of the delay, got %s", wait)
+	}
+
+	if waitLater != 0 {
+		t.Fatalf("expected no wait once the delay passed, got %s", waitLater)
+	}
+
+	if forgotten.Failures != 0 {
+		t.Fatal("expected failures outside the window to be forgotten")
+	}
+}
@@ -0,0 +1,124 @@
+package docshelf
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const tokenPrefix = "ds_"
+
+// An APIToken lets scripts authenticate as a User without going through the login flow. Only a hash of the
+// token's secret is stored, so the full token is only ever available right after it's issued.
+type APIToken struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	Name      string    `json:"name"`
+	Scopes    []Scope   `json:"scopes"`
+	Hash      string    `json:"hash,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// A Scope limits what an APIToken can be used for. A token can never do more than the User it belongs to.
+type Scope string
+
+// Scope enum values
+const (
+	ScopeRead  = Scope("read")
+	ScopeWrite = Scope("write")
+	ScopeAdmin = Scope("admin")
+)
+
+// Role returns the Role a Scope grants.
+func (s Scope) Role() Role {
+	switch s {
+	case ScopeRead:
+		return RoleViewer
+	case ScopeWrite:
+		return RoleEditor
+	case ScopeAdmin:
+		return RoleAdmin
+	default:
+		return Role("")
+	}
+}
+
+// Valid reports whether the Scope is one docshelf knows about.
+func (s Scope) Valid() bool {
+	return s.Role() != ""
+}
+
+// NewAPIToken creates a new APIToken for a User along with the full token that has to be handed to them.
+func NewAPIToken(userID, name string, scopes []Scope) (APIToken, string, error) {
+	encoded, err := newSecret()
+	if err != nil {
+		return APIToken{}, "", err
+	}
+
+	token := APIToken{
+		ID:        xid.New().String(),
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		Hash:      hashSecret(encoded),
+		CreatedAt: time.Now(),
+	}
+
+	return token, tokenPrefix + token.ID + "." + encoded, nil
+}
+
+// ParseAPIToken splits a full token into the ID of the APIToken it belongs to and its secret.
+func ParseAPIToken(raw string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(raw, tokenPrefix), ".", 2)
+	if !strings.HasPrefix(raw, tokenPrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errors.New("malformed api token")
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// Matches reports whether the secret belongs to the APIToken.
+func (t APIToken) Matches(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hashSecret(secret))) == 1
+}
+
+// Apply limits a User to what the APIToken's Scopes allow. The User's Role is lowered to the highest Role
+// granted by the Scopes, but never raised. Tokens without any known Scopes are read only.
+func (t APIToken) Apply(user User) User {
+	granted := RoleViewer
+	for _, scope := range t.Scopes {
+		if scope.Valid() && scope.Role().rank() > granted.rank() {
+			granted = scope.Role()
+		}
+	}
+
+	if !user.HasRole(granted) {
+		return user
+	}
+
+	user.Role = granted
+	return user
+}
+
+func newSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// secrets are long and random, so a plain hash is enough to keep them safe at rest and lets them be
+// compared without a slow KDF on every request
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,67 @@
+package docshelf
+
+import "testing"
+
+func Test_APIToken(t *testing.T) {
+	// SETUP
+	token, raw, err := NewAPIToken("user", "ci", []Scope{ScopeWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RUN
+	id, secret, err := ParseAPIToken(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, malformedErr := ParseAPIToken("ds_" + id)
+
+	// ASSERT
+	if id != token.ID {
+		t.Fatalf("expected token id %s, got %s", token.ID, id)
+	}
+
+	if !token.Matches(secret) {
+		t.Fatal("expected secret to match token")
+	}
+
+	if token.Matches(secret + "x") {
+		t.Fatal("expected altered secret not to match token")
+	}
+
+	if token.Hash == secret {
+		t.Fatal("expected secret to be hashed")
+	}
+
+	if malformedErr == nil {
+		t.Fatal("expected malformed token to be rejected")
+	}
+}
+
+func Test_APITokenApply(t *testing.T) {
+	// SETUP
+	cases := []struct {
+		name   string
+		role   Role
+		scopes []Scope
+		result Role
+	}{
+		{"read lowers admin", RoleAdmin, []Scope{ScopeRead}, RoleViewer},
+		{"write lowers admin", RoleAdmin, []Scope{ScopeRead, ScopeWrite}, RoleEditor},
+		{"admin keeps admin", RoleAdmin, []Scope{ScopeAdmin}, RoleAdmin},
+		{"admin doesn't raise editor", RoleEditor, []Scope{ScopeAdmin}, RoleEditor},
+		{"write doesn't raise viewer", RoleViewer, []Scope{ScopeWrite}, RoleViewer},
+		{"no scopes are read only", RoleEditor, nil, RoleViewer},
+	}
+
+	for _, c := range cases {
+		// RUN
+		user := APIToken{Scopes: c.scopes}.Apply(User{ID: "user", Role: c.role})
+
+		// ASSERT
+		if user.Role != c.result {
+			t.Fatalf("%s: expected role %s, got %s", c.name, c.result, user.Role)
+		}
+	}
+}
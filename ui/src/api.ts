@@ -7,6 +7,23 @@ export interface Doc {
   createdAt?: string
 }
 
+export interface SearchHit {
+	doc: Doc;
+	score: number;
+	fields: string[];
+	fragments?: { [field: string]: string[] };
+}
+
+export interface TagCount {
+	tag: string;
+	count: number;
+}
+
+export interface SearchResults {
+	hits: SearchHit[];
+	facets?: TagCount[];
+}
+
 export interface User {
 	id: string;
 	email: string;
@@ -69,6 +86,19 @@ export async function listDocs(): Promise<Doc[]> {
 	}
 }
 
+export async function searchDocs(query: string, facets = false): Promise<SearchResults> {
+	try {
+		const res = await fetch(`${basePath}/api/search?query=${encodeURIComponent(query)}&facets=${facets}`, {
+			credentials: "include",
+		});
+		const results = await res.json();
+		return results;
+	} catch (err) {
+		console.log(err);
+		return err;
+	}
+}
+
 export async function getDoc(id: string): Promise<Doc> {
 	try {
 		const res = await fetch(`${basePath}/api/doc/${id}`, {